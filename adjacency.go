@@ -0,0 +1,85 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// adjacent is a consequent an item implies, and the confidence it does so
+// with.
+type adjacent struct {
+	item       Item
+	confidence float64
+}
+
+// writeRulesAdjacency writes, for each item which is the antecedent of a
+// rule with a single item on each side, the consequents of those rules by
+// decreasing confidence, at most topConsequents of them if it is
+// positive, as in:
+//
+//	milk -> [(bread, 0.750000), (eggs, 0.500000)]
+//
+// Items are written in lexical order, and consequents with the same
+// confidence are too. Rules with more items on either side are left out.
+func writeRulesAdjacency(w io.Writer, rules [][]Rule, itemizer *Itemizer, topConsequents int) error {
+	adjacency := make(map[Item][]adjacent)
+	for _, chunk := range rules {
+		for _, rule := range chunk {
+			if len(rule.Antecedent) != 1 || len(rule.Consequent) != 1 {
+				continue
+			}
+			item := rule.Antecedent[0]
+			adjacency[item] = append(adjacency[item], adjacent{rule.Consequent[0], rule.Confidence})
+		}
+	}
+	items := make([]Item, 0, len(adjacency))
+	for item := range adjacency {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return itemizer.cmp(items[i], items[j])
+	})
+	for _, item := range items {
+		consequents := adjacency[item]
+		sort.Slice(consequents, func(i, j int) bool {
+			if consequents[i].confidence != consequents[j].confidence {
+				return consequents[i].confidence > consequents[j].confidence
+			}
+			return itemizer.cmp(consequents[i].item, consequents[j].item)
+		})
+		if topConsequents > 0 && len(consequents) > topConsequents {
+			consequents = consequents[:topConsequents]
+		}
+		if _, err := fmt.Fprintf(w, "%s -> [", itemizer.toStr(item)); err != nil {
+			return err
+		}
+		for i, c := range consequents {
+			sep := ", "
+			if i == 0 {
+				sep = ""
+			}
+			if _, err := fmt.Fprintf(w, "%s(%s, %f)", sep, itemizer.toStr(c.item), c.confidence); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w, "]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
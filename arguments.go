@@ -17,41 +17,843 @@
 
 package arm
 
-import "errors"
+import (
+	"errors"
+	"regexp"
+	"time"
+)
 
 var (
-	ErrMinSupportOutOfRange    = errors.New("MinSupport value is out of range [0,1.0].")
-	ErrMinConfidenceOutOfRange = errors.New("MinConfidence value is out of range [0,1.0].")
-	ErrMinLiftOutOfRange       = errors.New("MinLift is out of range [1.0,∞].")
+	ErrMinSupportOutOfRange              = errors.New("MinSupport value is out of range [0,1.0].")
+	ErrMinConfidenceOutOfRange           = errors.New("MinConfidence value is out of range [0,1.0].")
+	ErrMinLiftOutOfRange                 = errors.New("MinLift is out of range [1.0,∞].")
+	ErrUnknownOutputFormat               = errors.New("OutputFormat is not a known format.")
+	ErrInvalidSQLTable                   = errors.New("SQLTable is not a valid table name.")
+	ErrInvalidCypherLabel                = errors.New("CypherLabel is not a valid node label.")
+	ErrMinExcessCountOutOfRange          = errors.New("MinExcessCount is out of range [0,∞].")
+	ErrMinJaccardOutOfRange              = errors.New("MinJaccard is out of range [0,1].")
+	ErrMinValidationConfidenceOutOfRange = errors.New("MinValidationConfidence is out of range [0,1.0].")
+	ErrVocabularyWarnRatioOutOfRange     = errors.New("VocabularyWarnRatio is out of range [0,∞].")
+	ErrUnknownNumericItemPolicy          = errors.New("NumericItemPolicy is not a known policy.")
+	ErrOutputShardsOutOfRange            = errors.New("OutputShards is out of range [0,∞].")
+	ErrOutputShardsUnsupported           = errors.New("OutputShards is not supported by OutputFormat.")
+	ErrFieldSplitFuncUnsupported         = errors.New("FieldSplitFunc is not supported by InputFormat or PivotKey.")
+	ErrUnknownSortBy                     = errors.New("SortBy is not a known measure.")
+	ErrTopKOutOfRange                    = errors.New("TopK is out of range [0,∞].")
+	ErrTopKConflict                      = errors.New("TopK may not be combined with StreamItemsets.")
+	ErrUnknownInputFormat                = errors.New("InputFormat is not a known format.")
+	ErrMaxNegativeLiftOutOfRange         = errors.New("MaxNegativeLift is out of range [0,1.0].")
+	ErrAppendOutputUnsupported           = errors.New("AppendOutput is not supported by OutputFormat.")
+	ErrOutputCompressionUnsupported      = errors.New("OutputCompression is not supported by OutputFormat.")
+	ErrSweepSupportConflict              = errors.New("MineSweep takes its supports in place of MinSupportPPM and MinSupportCount, which may not be set.")
+	ErrMaxItemsPerTransactionOutOfRange  = errors.New("MaxItemsPerTransaction is out of range [0,∞].")
+	ErrUnknownOutputCompression          = errors.New("OutputCompression is not a known compression.")
+	ErrMaxRecursionDepthOutOfRange       = errors.New("MaxRecursionDepth is out of range [0,∞].")
+	ErrWorkersOutOfRange                 = errors.New("Workers is out of range [0,∞].")
+	ErrLaplaceSmoothingOutOfRange        = errors.New("LaplaceSmoothing is out of range [0,∞].")
+	ErrIDColumnOutOfRange                = errors.New("IDColumn is out of range [-1,∞].")
+	ErrInputMatchesNoFiles               = errors.New("Input matches no files.")
+	ErrMinSupportPPMOutOfRange           = errors.New("MinSupportPPM is out of range [0,1000000].")
+	ErrMinSupportConflict                = errors.New("MinSupport, MinSupportPPM and MinSupportCount are mutually exclusive.")
+	ErrMinSupportCountOutOfRange         = errors.New("MinSupportCount is out of range [0,∞].")
+	ErrUnknownTieBreak                   = errors.New("TieBreak is not a known order.")
+	ErrUnknownMetricOverflow             = errors.New("MetricOverflow is not a known policy.")
+	ErrUnknownMetricOverflowJSON         = errors.New("MetricOverflowJSON is not a known encoding.")
+	ErrMetricOverflowJSONConflict        = errors.New("MetricOverflowJSON may only be combined with MetricOverflowEmit.")
+	ErrMineColumnsOutOfRange             = errors.New("MineColumns is out of range [1,∞].")
+	ErrMinRuleSupportCountOutOfRange     = errors.New("MinRuleSupportCount is out of range [0,∞].")
+	ErrMaxOutputBytesOutOfRange          = errors.New("MaxOutputBytes is out of range [0,∞].")
+	ErrMaxOutputBytesUnsupported         = errors.New("MaxOutputBytes is not supported by OutputFormat.")
+	ErrMinConfidenceLiftOutOfRange       = errors.New("MinConfidenceLift is out of range [0,1].")
+	ErrPivotIncomplete                   = errors.New("PivotKey and PivotGroup must be set together.")
+	ErrPivotConflict                     = errors.New("PivotKey may not be combined with InputFormat, IDColumn or MineColumns.")
+	ErrMaxItemsetsOutOfRange             = errors.New("MaxItemsets is out of range [0,∞].")
+	ErrMaxLineBytesOutOfRange            = errors.New("MaxLineBytes is out of range [0,∞].")
+	ErrMinTransactionsOutOfRange         = errors.New("MinTransactions is out of range [0,∞].")
+	ErrPhaseTimeoutsOutOfRange           = errors.New("PhaseTimeouts is out of range [0,∞].")
+	ErrUnknownPhase                      = errors.New("PhaseTimeouts has a key which is not a phase.")
+	ErrStreamItemsetsWithoutOutput       = errors.New("StreamItemsets requires an itemsets output.")
+	ErrStreamItemsetsUnsupported         = errors.New("StreamItemsets is not supported by OutputFormat.")
+	ErrStreamItemsetsConflict            = errors.New("StreamItemsets may not be combined with FocusItem, MaxItemsets or VerifySupports.")
+	ErrCheckpointIntervalOutOfRange      = errors.New("CheckpointInterval is out of range [0,∞].")
+	ErrCheckpointConflict                = errors.New("CheckpointPath may not be combined with FocusItem or StreamItemsets.")
+	ErrSupportBinsOutOfRange             = errors.New("SupportBins must be increasing and in range (0,1).")
+	ErrLabelColumnOutOfRange             = errors.New("LabelColumn is out of range [0,∞], or is the IDColumn.")
+	ErrClassRulesOnlyConflict            = errors.New("ClassRulesOnly may not be combined with BothDirections, PivotKey or InputFormat onehot.")
+	ErrSingleItemOnlyConflict            = errors.New("SingleItemOnly may not be combined with StreamItemsets.")
+	ErrTopConsequentsPerItemOutOfRange   = errors.New("TopConsequentsPerItem is out of range [0,∞].")
+	ErrTopFrequentItemsOutOfRange        = errors.New("TopFrequentItems is out of range [0,∞].")
+	ErrOneHotGlobUnsupported             = errors.New("InputFormat onehot may not be combined with a glob pattern in Input or ValidationInput.")
+	ErrClosedMaximalConflict             = errors.New("Closed and Maximal are mutually exclusive.")
+	ErrItemsetFilterConflict             = errors.New("Closed and Maximal may not be combined with MaxItemsets, SingleItemOnly or StreamItemsets.")
+)
+
+// Formats in which rules can be written.
+const (
+	// Comma separated values, one rule per line. This is the default.
+	OutputFormatCSV = "csv"
+	// SQL INSERT statements, one rule per statement.
+	OutputFormatSQL = "sql"
+	// Apache Parquet, with a fixed schema: the antecedent, consequent,
+	// confidence, lift and support of each rule, whatever the options,
+	// such as EmitRuleIDs, which add columns to the CSV output. Itemsets
+	// are written as Parquet too, as the itemset and its support. Cannot
+	// be appended to, nor compressed with gzip, as Parquet readers expect
+	// the file itself rather than an archive of it.
+	OutputFormatParquet = "parquet"
+	// Itemsets as nested JSON, each itemset under the itemset it extends
+	// by one item, with items in lexical order. Rules are written as CSV.
+	// Cannot be appended to.
+	OutputFormatTreeJSON = "tree-json"
+	// Cypher statements merging the antecedent and consequent of each
+	// rule as nodes, and an IMPLIES relationship holding its measures
+	// between them, one rule per statement. Itemsets are written as CSV.
+	OutputFormatCypher = "cypher"
+	// Two CSV tables in long format: the rules, one per row with its ID
+	// and measures, and their items, one per row with the ID of the rule
+	// and its side, which go to RuleItemsPath. Itemsets are written as CSV.
+	OutputFormatLong = "long"
+	// For each item, the items it implies by rules with a single item on
+	// each side, by decreasing confidence, one item per line, as in
+	// "milk -> [(bread, 0.750000), (eggs, 0.500000)]", to serve as a
+	// recommendation table. Rules with more items are left out. Itemsets
+	// are written as CSV.
+	OutputFormatAdjacency = "adjacency"
+	// JSON Lines, one object per rule, with the names of the items of its
+	// antecedent and consequent and its measures, as in
+	// {"antecedent":["milk"],"consequent":["bread"],"support":0.6,...}.
+	// Infinite and NaN measures are encoded as MetricOverflowJSON says.
+	// Itemsets are written as CSV.
+	OutputFormatJSON = "json"
+)
+
+// Measures by which rules can be ranked, best first.
+const (
+	SortByConfidence = "confidence"
+	SortByLift       = "lift"
+	SortBySupport    = "support"
+	SortByConviction = "conviction"
+	SortByLeverage   = "leverage"
+	SortByKulczynski = "kulczynski"
+	// Support of the antecedent, then support of the rule, then the
+	// names of the items. Every rule has its own place in this order,
+	// which is the same in every run, so that it suits pagination.
+	SortByAntecedentSupport = "antecedent-support"
+)
+
+// Policies for rules with an infinite or NaN measure, such as the
+// conviction of rules which always hold.
+const (
+	// Keep such rules as they are. This is the default.
+	MetricOverflowEmit = "emit"
+	// Drop such rules.
+	MetricOverflowSkip = "skip"
+	// Replace infinite measures by plus or minus maxMetric, and NaN by 0.
+	MetricOverflowClamp = "clamp"
 )
 
+// Encodings of infinite and NaN measures in JSON output, which JSON numbers
+// cannot hold, with MetricOverflowEmit. The other policies leave no such
+// measure to encode.
+const (
+	// The strings "Infinity", "-Infinity" and "NaN", which keep the value.
+	// This is the default.
+	MetricOverflowJSONString = "string"
+	// null, which strict parsers take as a missing value.
+	MetricOverflowJSONNull = "null"
+)
+
+// maxMetric is the value MetricOverflowClamp clamps infinite measures to.
+const maxMetric = 1e9
+
+// Orders of items of equal frequency in the FP-tree.
+const (
+	// By item string. This is the default.
+	TieBreakLexical = "lexical"
+	// In the order items are first seen in the Input.
+	TieBreakInsertion = "insertion"
+	// By item string, in reverse.
+	TieBreakReverseLexical = "reverse-lexical"
+)
+
+// Phases of mining reported to OnProgress.
+const (
+	// Growing the frequent itemsets, measured by the frequent items whose
+	// itemsets have been grown. Not reported with FocusItem, which grows
+	// a single item.
+	ProgressFPGrowth = "fpgrowth"
+)
+
+// Policies for items which are decimal numbers.
+const (
+	// Keep such items as they are, so that "1" and "1.0" are different
+	// items. This is the default.
+	NumericItemLiteral = "literal"
+	// Write such items in their shortest form, so that "1", "1.0", "01"
+	// and "+1" are the same item, "1". Numbers with an exponent, such as
+	// "1e3", are kept as they are.
+	NumericItemCanonical = "canonical"
+)
+
+// Phases of mining which PhaseTimeouts can give time budgets to.
+const (
+	// Reading the dataset to count the items.
+	PhaseCount = "count"
+	// Reading the dataset again into an FP-tree, and growing the frequent
+	// itemsets from it.
+	PhaseFPGrowth = ProgressFPGrowth
+	// Generating the rules from the frequent itemsets.
+	PhaseRules = "rules"
+)
+
+// defaultSQLTable is the table the SQL output format inserts into.
+const defaultSQLTable = "rules"
+
+// sqlTableName matches unquoted, optionally schema qualified, table names.
+var sqlTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// cypherLabel matches node labels which need no quoting in Cypher.
+var cypherLabel = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 type Arguments struct {
-	// Input dataset in CSV format.
+	// Input dataset in CSV format. May be a glob pattern such as
+	// data/2023-*.csv, in which case the matching files are read in
+	// lexical order as one dataset, except with InputFormatOneHot.
 	Input string
 	// File path in which to store Output rules. Format:
 	// antecedent -> consequent, confidence, lift, support.
 	Output string
 	// Minimum itemset support threshold, in range [0,1].
 	MinSupport float64
-	// Minimum rule confidence threshold, in range [0,1].
+	// Minimum rule confidence threshold, in range [0,1]. At 1, exactly the
+	// rules which hold in every transaction holding their antecedent are
+	// kept, even with LaplaceSmoothing.
 	MinConfidence float64
 	// Minimum rule lift confidence threshold, in range
-	// [1,∞] (optional).
+	// [1,∞] (optional). A rule must reach it, or exceed it if
+	// LiftExclusive is set.
 	MinLift float64
 	// File path in which to store generated itemsets
 	// (optional).
 	ItemsetsPath string
+	// Only count items, and log and record in Stats.Estimate an estimate
+	// of the cost of mining, without generating itemsets or rules
+	// (optional).
+	DryRun bool
+	// Order in which the items of each transaction are inserted into the
+	// FP-tree. Defaults to decreasing frequency, ties broken
+	// lexicographically. Only the size of the tree and the speed of mining
+	// depend on it, not the results (optional).
+	ItemOrder ItemOrder
+	// Format in which to write the rules and itemsets, OutputFormatCSV,
+	// OutputFormatSQL, OutputFormatParquet, OutputFormatTreeJSON,
+	// OutputFormatCypher, OutputFormatLong, OutputFormatAdjacency or
+	// OutputFormatJSON.
+	// Defaults to OutputFormatCSV (optional).
+	OutputFormat string
+	// Name of the table the SQL output format inserts into. Defaults to
+	// "rules" (optional).
+	SQLTable string
+	// Append to the output files rather than overwriting them. Headers
+	// are only written to files which are empty (optional).
+	AppendOutput bool
+	// Measure by which rules are ranked for TopK and SortOutput, one of
+	// the SortBy constants. Defaults to SortByConfidence (optional).
+	SortBy string
+	// Retain only the TopK best ranked rules, or all rules if zero. May
+	// not be combined with StreamItemsets, which generates no rules. With
+	// MaxItemsets, the rules of the itemsets retained are ranked
+	// (optional).
+	TopK int
+	// Write the rules in ranked order, best first. Rules ranked equal are
+	// ordered by the names of their items (optional).
+	SortOutput bool
+	// Write only the rules themselves, as "antecedent => consequent"
+	// lines without a header or metric columns, in the CSV output format
+	// (optional).
+	RulesOnlyText bool
+	// Filled in with statistics about the run (optional).
+	Stats *Stats
+	// Format of the Input dataset, InputFormatCSV, InputFormatJSONL or
+	// InputFormatOneHot. Defaults to InputFormatCSV (optional).
+	InputFormat string
+	// Match items regardless of case and accents, so that "Café" and
+	// "cafe" are the same item. Items are written as first seen
+	// (optional).
+	NormalizeUnicode bool
+	// Path to write pairs of items which appear together far less often
+	// than if they were independent, such as substitutes. Both items of a
+	// pair must reach MinSupport, the pair itself need not (optional).
+	NegativeCorrelationPath string
+	// Pairs with a lift of at most MaxNegativeLift are written to
+	// NegativeCorrelationPath. Defaults to 0.5 (optional).
+	MaxNegativeLift float64
+	// Transactions with more items than this, frequent or not, typically
+	// malformed lines, are truncated to the items which appear in the most
+	// transactions that are not, or skipped if SkipLongTransactions is
+	// set. Both passes truncate them alike, so only the items they keep
+	// are counted, which takes reading them twice in the first pass. 0
+	// means no limit (optional).
+	MaxItemsPerTransaction int
+	SkipLongTransactions   bool
+	// Compression of the output files, OutputCompressionGzip or
+	// OutputCompressionNone. If unset, files whose path ends in .gz are
+	// compressed with gzip (optional).
+	OutputCompression string
+	// Limits how deep fpGrowth recurses, which bounds stack usage on
+	// pathological inputs. As fpGrowth recurses once per item, no
+	// itemsets longer than this are generated. 0 means no limit
+	// (optional).
+	MaxRecursionDepth int
+	// Number of goroutines generating frequent itemsets. The output is the
+	// same for any number of workers. 0 or 1 generates them on the calling
+	// goroutine (optional).
+	Workers int
+	// Maps alternative names of items, such as "coke", to the canonical
+	// name, such as "coca-cola", which they are counted and written as
+	// (optional).
+	Aliases map[string]string
+	// Pseudo-count k of the Laplace corrected confidence of a rule,
+	// (count(A,C) + k) / (count(A) + 2k), which discounts rules with
+	// little support. MinConfidence applies to the corrected confidence,
+	// which is written as an extra column. 0 means no correction
+	// (optional).
+	LaplaceSmoothing float64
+	// Write items as their integer IDs rather than their strings, which is
+	// more compact. The strings are written to ItemizerPath (optional).
+	OutputItemIDs bool
+	// Path to write the Itemizer to, as a JSON array of item strings where
+	// the string of item i is at index i-1. Defaults to Output with
+	// ".itemizer.json" appended if OutputItemIDs is set (optional).
+	ItemizerPath string
+	// Skip and log malformed lines of the Input, rather than failing with
+	// a *ParseError (optional).
+	SkipMalformed bool
+	// 1-based column of the Input which holds a transaction ID, rather
+	// than an item, and is ignored. -1, or 0 so that the zero value mines
+	// every column, means there is none (optional).
+	IDColumn int
+	// Write rules with the same antecedent together, sorted by antecedent.
+	// In CSV output, groups are separated by a blank line (optional).
+	GroupByAntecedent bool
+	// Separator between the antecedent and the consequent of rules in CSV
+	// output. Defaults to " => " (optional).
+	RuleArrow string
+	// Separator between the items of rules and itemsets in CSV output.
+	// Defaults to a space (optional).
+	ItemSeparator string
+	// Start CSV output files with a line, commented out by #, recording
+	// the thresholds, the number of transactions and the time of the run
+	// (optional).
+	EmitMetadataHeader bool
+	// Treat items starting with ! as the negation of the rest of the item,
+	// so that rules can involve the absence of an item. "!milk" and
+	// "! milk" are the same item, NormalizeUnicode and Aliases apply to the
+	// negated item, so "!Whole Milk" may become "!milk", and "!!milk" is
+	// "milk". A transaction may hold both an item and its negation
+	// (optional).
+	RespectNegation bool
+	// Alternative to MinSupport for tiny thresholds, in parts per million
+	// of the transactions, so that 500 is a MinSupport of 0.0005. Only one
+	// of MinSupport, MinSupportPPM and MinSupportCount may be set
+	// (optional).
+	MinSupportPPM int
+	// List the frequent itemsets which yield no rule in
+	// Stats.ItemsetsWithoutRules, rather than only counting them
+	// (optional).
+	RecordItemsetsWithoutRules bool
+	// Order of items of equal frequency in the FP-tree, TieBreakLexical,
+	// TieBreakInsertion or TieBreakReverseLexical. Like ItemOrder, only
+	// the size of the tree and the speed of mining depend on it. Defaults
+	// to TieBreakLexical (optional).
+	TieBreak string
+	// What to do with rules which have an infinite or NaN measure,
+	// MetricOverflowEmit, MetricOverflowSkip or MetricOverflowClamp.
+	// Defaults to MetricOverflowEmit (optional).
+	MetricOverflow string
+	// 1-based columns of the Input which hold items. Other columns are
+	// ignored, as is the IDColumn even if listed. A line with fewer
+	// columns is malformed. Defaults to all columns (optional).
+	MineColumns []int
+	// Write the ID of each rule as a column of CSV output, so that rules
+	// can be matched across runs (optional).
+	EmitRuleIDs bool
+	// Minimum number of transactions in which a rule holds, that is which
+	// hold both its antecedent and its consequent. Unlike MinSupport, this
+	// only drops rules, not the itemsets written. 0 means no minimum
+	// (optional).
+	MinRuleSupportCount int
+	// Write the antecedent and the consequent of rules in CSV output as
+	// separate columns, rather than as one joined by RuleArrow (optional).
+	SeparateRuleColumns bool
+	// Stop writing rules once the rules output holds this many bytes,
+	// before compression, so that a run cannot fill the disk. Only whole
+	// rules are written, and Stats.TruncatedOutput is set. With SortOutput
+	// or TopK, the best rules are kept. Not supported by
+	// OutputFormatParquet. 0 means no limit (optional).
+	MaxOutputBytes int64
+	// Replace each run of whitespace within an item with a single space,
+	// so that "red   shirt" and "red shirt" are the same item. Items are
+	// written in this collapsed form. Applies to Aliases as well
+	// (optional).
+	CollapseWhitespace bool
+	// Minimum by which the confidence of a rule must exceed the support of
+	// its consequent, confidence(A => C) - support(C), which drops rules
+	// that merely reflect a common consequent. The difference is written
+	// as an extra ConfidenceLift column. 0 means no minimum (optional).
+	MinConfidenceLift float64
+	// Read Input as lines of space separated key=value pairs, such as
+	// "user=42 action=buy item=milk", and mine the values of PivotKey,
+	// "item", grouped into a transaction per value of PivotGroup, "user".
+	// Lines are read as a stream, so the lines of a group must be
+	// consecutive, as when sorted by PivotGroup; a group which reappears
+	// later starts another transaction. Lines without PivotGroup are
+	// ignored. Values may not contain spaces. Requires PivotGroup, and
+	// may not be combined with InputFormat, IDColumn or MineColumns
+	// (optional).
+	PivotKey   string
+	PivotGroup string
+	// After mining, read Input again and count the transactions holding
+	// each frequent itemset by brute force, failing with
+	// ErrSupportMismatch if any differs from the count FP-growth found.
+	// This checks the miner on a new dataset, at the cost of a third pass
+	// which tests every itemset against every transaction, so it is far
+	// slower than mining itself (optional).
+	VerifySupports bool
+	// Mine only the rules which involve this item, in their antecedent or
+	// consequent. FP-growth then only reads the transactions containing
+	// the item, which is far faster on large datasets. Other itemsets are
+	// only counted when rules need their support, in a third pass over
+	// Input. ItemsetsPath gets the itemsets containing the item, and those
+	// subsets. The item is normalized like any other (optional).
+	FocusItem string
+	// Retain only the MaxItemsets frequent itemsets of highest support,
+	// for rule generation and for ItemsetsPath. Mining is exact, and the
+	// itemsets are only cut afterwards, which is logged. Smaller itemsets
+	// come first among those of equal support, so that the itemsets
+	// retained include all of their subsets. 0 means no limit (optional).
+	MaxItemsets int
+	// Write the frequent itemsets to ItemsetsPath as FP-growth finds them,
+	// rather than holding them all in memory, for datasets with more
+	// itemsets than fit in it. No rules are generated, so the rules
+	// output is empty. Requires ItemsetsPath, is not supported by
+	// OutputFormatParquet or OutputFormatTreeJSON, which need every
+	// itemset at once, and may not be combined with FocusItem, MaxItemsets
+	// or VerifySupports. Workers is ignored (optional).
+	StreamItemsets bool
+	// Path to write the count, support and support rank of each item to,
+	// as CSV with the columns Item,Count,Support,Rank, most frequent item
+	// first. Rank 1 is the most frequent item, and items with the same
+	// count share a rank. Only the first pass over Input is needed, so
+	// this is written even with DryRun, to help choose the items to mine
+	// (optional).
+	ItemStatsPath string
+	// Maximum length in bytes of a line of Input, including its newline.
+	// Longer lines fail with a *ParseError. Raise it for wide rows, such
+	// as those of InputFormatOneHot. With PivotKey, it also bounds the
+	// items of each group. Defaults to 64KiB (optional).
+	MaxLineBytes int
+	// Separator between attribute and value in items such as "color=red".
+	// If set, rules whose antecedent and consequent hold items of the same
+	// attribute, such as color=red => color=blue, are not generated.
+	// Items without the separator have no attribute (optional).
+	AttributeSeparator string
+	// Refuse to mine datasets with fewer transactions than this, failing
+	// with ErrTooFewTransactions after the first pass, so that rules are
+	// not drawn from tiny samples by accident. Transactions skipped by
+	// SkipLongTransactions do not count. 0 means no minimum (optional).
+	MinTransactions int
+	// For each pair of items yielding a rule A => B, also generate
+	// B => A, and the other way around, even if that falls below
+	// MinConfidence, MinConfidenceLift or MinLift, for studies of
+	// substitutes and complements. Longer itemsets are unaffected. Each
+	// rule is still output once, and MetricOverflowSkip, TopK and
+	// MaxOutputBytes may still drop one of the two (optional).
+	BothDirections bool
+	// Called as mining proceeds with the phase, such as ProgressFPGrowth,
+	// and the fraction of it done so far, from 0 to 1. With Workers, it
+	// may be called from several goroutines, but never concurrently
+	// (optional).
+	OnProgress func(phase string, fraction float64)
+	// Write supports in the CSV output formats as percentages, such as
+	// 12.3000% rather than 0.123000, for reading by people. The columns
+	// keep their names. The SQL and Parquet output formats are unaffected
+	// (optional).
+	SupportAsPercent bool
+	// Time budget of each phase, keyed by PhaseCount, PhaseFPGrowth and
+	// PhaseRules, within the deadline of the context mining is given, if
+	// any. A phase which runs out of time fails with an error wrapping
+	// ErrPhaseTimeout that names it, and leaves the later phases unrun.
+	// Phases without a positive timeout are not limited (optional).
+	PhaseTimeouts map[string]time.Duration
+	// Label of the nodes the Cypher output format merges. Defaults to
+	// "Item" (optional).
+	CypherLabel string
+	// Minimum number of transactions in which a rule holds beyond those
+	// expected if its antecedent and consequent were independent, which is
+	// its leverage times the number of transactions. Unlike a minimum
+	// leverage, it asks for the same evidence on datasets of any size. The
+	// excess is written as an extra ExcessCount column. 0 means no minimum
+	// (optional).
+	MinExcessCount int
+	// Minimum Jaccard similarity of the antecedent and consequent of a
+	// rule, in range [0,1]: the support of the rule over the support of
+	// either side. As it is symmetric, it suits grouping similar items.
+	// It is written as an extra Jaccard column. 0 means no minimum
+	// (optional).
+	MinJaccard float64
+	// Splits each line of Input into fields, in place of splitting it at
+	// commas, for formats with mixed or unusual delimiters. IDColumn and
+	// MineColumns then count these fields, and InputFormatOneHot takes its
+	// header and columns from them. Every pass over Input calls it, so it
+	// must return the same fields for the same line every time. Not
+	// supported by InputFormatJSONL or PivotKey (optional).
+	FieldSplitFunc func(line string) []string
+	// Also generate the baseline rule {} => B of each frequent item B,
+	// with an empty antecedent, whose confidence is the support of B and
+	// whose lift is 1, as a reference for the other rules with consequent
+	// B. Baseline rules are generated whatever MinConfidence, MinLift and
+	// the other rule thresholds, and are written with an empty antecedent
+	// (optional).
+	IncludeBaselineRules bool
+	// Path to write the items of the rules to with OutputFormatLong, as CSV
+	// with the columns RuleID,Side,Item, where Side is "antecedent" or
+	// "consequent". Defaults to Output with ".items.csv" appended
+	// (optional).
+	RuleItemsPath string
+	// How items which are decimal numbers are matched,
+	// NumericItemLiteral or NumericItemCanonical. Defaults to
+	// NumericItemLiteral (optional).
+	NumericItemPolicy string
+	// Write the rules to this many files rather than to Output alone, each
+	// rule to the file of a hash of its antecedent, so that they can be
+	// loaded in parallel. The hash is of the item strings, so every run
+	// splits the same rules the same way, and rules with the same
+	// antecedent share a file. Each file is Output with the number of its
+	// shard, from 0, inserted before its extensions, as in rules.0.csv.gz,
+	// and is written even if empty. MaxOutputBytes applies to each file.
+	// Not supported by OutputFormatLong or OutputFormatAdjacency. 0 or 1
+	// means a single file (optional).
+	OutputShards int
+	// Log a summary once the output is written: the total time, and how
+	// many transactions were counted, itemsets grown and rules generated
+	// per second, which helps compare runs. The time of each phase is also
+	// in Stats (optional).
+	PrintSummary bool
+	// Drop rules whose lift equals MinLift. By default the comparison is
+	// inclusive, so MinLift 1 keeps the rules of items which occur
+	// independently of each other, and LiftExclusive drops them (optional).
+	LiftExclusive bool
+	// File path or glob pattern of a validation dataset, in the format of
+	// Input, on which the support, confidence and lift of each rule are
+	// computed again once rules are generated, to detect overfitting. The
+	// CSV and long output formats write them as extra columns (optional).
+	ValidationInput string
+	// Minimum confidence of a rule on the ValidationInput dataset, in range
+	// [0,1]. Rules below it are dropped. Needs ValidationInput (optional).
+	MinValidationConfidence float64
+	// Log a warning after counting items if there are more distinct items
+	// than this many times the number of transactions, which usually means
+	// a column of unique values, such as an ID, is read as items. The
+	// warning lists the most frequent items and some which occur once. 0
+	// means no warning (optional).
+	VocabularyWarnRatio float64
+	// File in which fpGrowth records the frequent itemsets it has grown, so
+	// that a run which stops, by crashing or by a timeout, can resume where
+	// it was. If the file exists, the itemsets it holds are restored and
+	// the rest are grown; it must be from a run on the same dataset with
+	// the same options, or mining fails with ErrCheckpointMismatch. Each
+	// frequent item of the FP-tree header is recorded once all the itemsets
+	// grown from it are found, so a resumed run yields every itemset
+	// exactly once, as an uninterrupted run would. The work of items which
+	// were being grown, or were not yet written, when the run stopped is
+	// done again: it is at least once. The file is removed once fpGrowth
+	// completes. Not supported with FocusItem or StreamItemsets (optional).
+	CheckpointPath string
+	// How often the checkpoint is written to disk, at most: what a crash
+	// may lose. Defaults to a minute (optional).
+	CheckpointInterval time.Duration
+	// Write the number of items of the antecedent and of the consequent of
+	// each rule as AntecedentSize and ConsequentSize columns of CSV output,
+	// to filter rules by how complex they are (optional).
+	EmitSizes bool
+	// Increasing bounds, in range (0,1), of bins of support. If set, the
+	// CSV output formats write the bin holding each support, labelled by
+	// its bounds as percentages, such as 1-5%, rather than the support
+	// itself, for coarse reports which hide precise counts. The first bin
+	// starts at 0 and the last ends at 100%; each holds its lower bound.
+	// It takes precedence over SupportAsPercent. The SQL and Parquet output
+	// formats are unaffected (optional).
+	SupportBins []float64
+	// 1-based column of the Input which holds the class label of each
+	// transaction, for ClassRulesOnly. Defaults to the first column. The
+	// label is an item like the others, and counts towards supports, even
+	// if MineColumns leaves its column out (optional).
+	LabelColumn int
+	// Only generate rules which predict the class label from other items:
+	// rules whose consequent holds only items read from LabelColumn, and
+	// whose antecedent holds none, as for training a rule-based
+	// classifier. May not be combined with BothDirections, PivotKey or
+	// InputFormatOneHot (optional).
+	ClassRulesOnly bool
+	// Stop after counting items: the items reaching MinSupport are written
+	// to ItemsetsPath as 1-itemsets, and neither longer itemsets nor rules
+	// are generated. The cheapest run which reports anything, to profile a
+	// dataset. May not be combined with StreamItemsets (optional).
+	SingleItemOnly bool
+	// Maximum number of consequents written for each item with
+	// OutputFormatAdjacency, those with the highest confidence. 0 means
+	// all of them (optional).
+	TopConsequentsPerItem int
+	// Keep only this many items, the most frequent, and drop the others
+	// from every transaction before the FP-tree is built, to bound its
+	// width on dense data. Ties are broken as with TieBreak. Itemsets with
+	// a dropped item are never found, and the share of item occurrences
+	// dropped is logged. 0 keeps every item (optional).
+	TopFrequentItems int
+	// Called with the fields of each line of Input, as split before
+	// IDColumn, LabelColumn and MineColumns select the items, and skips
+	// the line if it returns false, as for keeping only the transactions
+	// of weekdays by a date column. Every pass over Input calls it, and
+	// they must all skip the same lines, so it must be pure: its result
+	// may only depend on the fields, which it may not modify (optional).
+	TransactionFilter func(fields []string) bool
+	// Alternative to MinSupport as the number of transactions an itemset
+	// must appear in, which becomes a MinSupport once the first pass has
+	// counted the transactions. Only one of MinSupport, MinSupportPPM and
+	// MinSupportCount may be set (optional).
+	MinSupportCount int
+	// How OutputFormatJSON encodes infinite and NaN measures,
+	// MetricOverflowJSONString or MetricOverflowJSONNull. Only
+	// MetricOverflowEmit keeps such measures, so it may not be combined
+	// with another MetricOverflow. Defaults to MetricOverflowJSONString
+	// (optional).
+	MetricOverflowJSON string
+	// Write only the closed frequent itemsets to ItemsetsPath, those with
+	// no superset of the same support. Rules are still generated from
+	// every frequent itemset (optional).
+	Closed bool
+	// Write only the maximal frequent itemsets to ItemsetsPath, those with
+	// no frequent superset. Rules are still generated from every frequent
+	// itemset. Closed and Maximal are mutually exclusive, and neither may
+	// be combined with MaxItemsets, SingleItemOnly or StreamItemsets,
+	// whose itemsets lack the supersets needed to tell (optional).
+	Maximal bool
 }
 
 func (args Arguments) Validate() error {
 	if args.MinSupport < 0.0 || args.MinSupport > 1.0 {
 		return ErrMinSupportOutOfRange
 	}
+	if args.MinSupportPPM < 0 || args.MinSupportPPM > 1000000 {
+		return ErrMinSupportPPMOutOfRange
+	}
+	if args.MinSupportCount < 0 {
+		return ErrMinSupportCountOutOfRange
+	}
+	numThresholds := 0
+	for _, set := range []bool{args.MinSupport > 0, args.MinSupportPPM > 0, args.MinSupportCount > 0} {
+		if set {
+			numThresholds++
+		}
+	}
+	if numThresholds > 1 {
+		return ErrMinSupportConflict
+	}
 	if args.MinConfidence < 0.0 || args.MinConfidence > 1.0 {
 		return ErrMinConfidenceOutOfRange
 	}
 	if args.MinLift != 0.0 && args.MinLift < 1.0 {
 		return ErrMinLiftOutOfRange
 	}
+	switch args.InputFormat {
+	case "", InputFormatCSV, InputFormatJSONL, InputFormatOneHot:
+	default:
+		return ErrUnknownInputFormat
+	}
+	if args.InputFormat == InputFormatOneHot && (isGlob(args.Input) || isGlob(args.ValidationInput)) {
+		// The files would be read as one, with the header of each but the
+		// first taken for a row.
+		return ErrOneHotGlobUnsupported
+	}
+	switch args.OutputFormat {
+	case "", OutputFormatCSV, OutputFormatSQL, OutputFormatCypher, OutputFormatLong, OutputFormatAdjacency, OutputFormatJSON:
+	case OutputFormatParquet, OutputFormatTreeJSON:
+		if args.AppendOutput {
+			return ErrAppendOutputUnsupported
+		}
+		if args.OutputFormat == OutputFormatParquet &&
+			(compressionFor(args.OutputCompression, args.Output) == OutputCompressionGzip ||
+				compressionFor(args.OutputCompression, args.ItemsetsPath) == OutputCompressionGzip) {
+			return ErrOutputCompressionUnsupported
+		}
+	default:
+		return ErrUnknownOutputFormat
+	}
+	if args.SQLTable != "" && !sqlTableName.MatchString(args.SQLTable) {
+		return ErrInvalidSQLTable
+	}
+	if args.CypherLabel != "" && !cypherLabel.MatchString(args.CypherLabel) {
+		return ErrInvalidCypherLabel
+	}
+	if ruleMeasure(args.SortBy) == nil {
+		return ErrUnknownSortBy
+	}
+	if args.TopK < 0 {
+		return ErrTopKOutOfRange
+	}
+	if args.TopK > 0 && args.StreamItemsets {
+		return ErrTopKConflict
+	}
+	if args.MaxNegativeLift < 0.0 || args.MaxNegativeLift > 1.0 {
+		return ErrMaxNegativeLiftOutOfRange
+	}
+	if args.MaxItemsPerTransaction < 0 {
+		return ErrMaxItemsPerTransactionOutOfRange
+	}
+	if args.MaxRecursionDepth < 0 {
+		return ErrMaxRecursionDepthOutOfRange
+	}
+	if args.Workers < 0 {
+		return ErrWorkersOutOfRange
+	}
+	if args.LaplaceSmoothing < 0 {
+		return ErrLaplaceSmoothingOutOfRange
+	}
+	if args.IDColumn < -1 {
+		return ErrIDColumnOutOfRange
+	}
+	if args.StreamItemsets && (args.OutputFormat == OutputFormatParquet || args.OutputFormat == OutputFormatTreeJSON) {
+		return ErrStreamItemsetsUnsupported
+	}
+	if args.StreamItemsets && (args.FocusItem != "" || args.MaxItemsets > 0 || args.VerifySupports) {
+		return ErrStreamItemsetsConflict
+	}
+	if args.Closed && args.Maximal {
+		return ErrClosedMaximalConflict
+	}
+	if (args.Closed || args.Maximal) && (args.MaxItemsets > 0 || args.SingleItemOnly || args.StreamItemsets) {
+		return ErrItemsetFilterConflict
+	}
+	for i, bound := range args.SupportBins {
+		if bound <= 0 || bound >= 1 || (i > 0 && bound <= args.SupportBins[i-1]) {
+			return ErrSupportBinsOutOfRange
+		}
+	}
+	if args.LabelColumn < 0 {
+		return ErrLabelColumnOutOfRange
+	}
+	if args.ClassRulesOnly && max(args.LabelColumn, 1) == args.IDColumn {
+		return ErrLabelColumnOutOfRange
+	}
+	if args.ClassRulesOnly && (args.BothDirections || args.PivotKey != "" || args.InputFormat == InputFormatOneHot) {
+		return ErrClassRulesOnlyConflict
+	}
+	if args.TopFrequentItems < 0 {
+		return ErrTopFrequentItemsOutOfRange
+	}
+	if args.TopConsequentsPerItem < 0 {
+		return ErrTopConsequentsPerItemOutOfRange
+	}
+	if args.SingleItemOnly && args.StreamItemsets {
+		return ErrSingleItemOnlyConflict
+	}
+	if args.CheckpointInterval < 0 {
+		return ErrCheckpointIntervalOutOfRange
+	}
+	if args.CheckpointPath != "" && (args.FocusItem != "" || args.StreamItemsets) {
+		return ErrCheckpointConflict
+	}
+	for phase, timeout := range args.PhaseTimeouts {
+		switch phase {
+		case PhaseCount, PhaseFPGrowth, PhaseRules:
+		default:
+			return ErrUnknownPhase
+		}
+		if timeout < 0 {
+			return ErrPhaseTimeoutsOutOfRange
+		}
+	}
+	if args.MinTransactions < 0 {
+		return ErrMinTransactionsOutOfRange
+	}
+	if args.MaxLineBytes < 0 {
+		return ErrMaxLineBytesOutOfRange
+	}
+	if args.MaxItemsets < 0 {
+		return ErrMaxItemsetsOutOfRange
+	}
+	if (args.PivotKey == "") != (args.PivotGroup == "") {
+		return ErrPivotIncomplete
+	}
+	if args.PivotKey != "" && (args.InputFormat != "" || args.IDColumn > 0 || args.MineColumns != nil) {
+		return ErrPivotConflict
+	}
+	if args.FieldSplitFunc != nil && (args.InputFormat == InputFormatJSONL || args.PivotKey != "") {
+		return ErrFieldSplitFuncUnsupported
+	}
+	if args.MinConfidenceLift < 0 || args.MinConfidenceLift > 1 {
+		return ErrMinConfidenceLiftOutOfRange
+	}
+	if args.MinExcessCount < 0 {
+		return ErrMinExcessCountOutOfRange
+	}
+	if args.MinJaccard < 0 || args.MinJaccard > 1 {
+		return ErrMinJaccardOutOfRange
+	}
+	if args.OutputShards < 0 {
+		return ErrOutputShardsOutOfRange
+	}
+	if args.OutputShards > 1 && (args.OutputFormat == OutputFormatLong || args.OutputFormat == OutputFormatAdjacency) {
+		return ErrOutputShardsUnsupported
+	}
+	if args.MaxOutputBytes < 0 {
+		return ErrMaxOutputBytesOutOfRange
+	}
+	if args.MaxOutputBytes > 0 && (args.OutputFormat == OutputFormatParquet || args.OutputFormat == OutputFormatLong) {
+		return ErrMaxOutputBytesUnsupported
+	}
+	if args.MinRuleSupportCount < 0 {
+		return ErrMinRuleSupportCountOutOfRange
+	}
+	for _, column := range args.MineColumns {
+		if column < 1 {
+			return ErrMineColumnsOutOfRange
+		}
+	}
+	switch args.TieBreak {
+	case "", TieBreakLexical, TieBreakInsertion, TieBreakReverseLexical:
+	default:
+		return ErrUnknownTieBreak
+	}
+	switch args.MetricOverflow {
+	case "", MetricOverflowEmit, MetricOverflowSkip, MetricOverflowClamp:
+	default:
+		return ErrUnknownMetricOverflow
+	}
+	switch args.MetricOverflowJSON {
+	case "", MetricOverflowJSONString, MetricOverflowJSONNull:
+	default:
+		return ErrUnknownMetricOverflowJSON
+	}
+	if args.MetricOverflowJSON != "" && args.MetricOverflow != "" && args.MetricOverflow != MetricOverflowEmit {
+		return ErrMetricOverflowJSONConflict
+	}
+	if args.MinValidationConfidence < 0.0 || args.MinValidationConfidence > 1.0 {
+		return ErrMinValidationConfidenceOutOfRange
+	}
+	if args.VocabularyWarnRatio < 0 {
+		return ErrVocabularyWarnRatioOutOfRange
+	}
+	switch args.NumericItemPolicy {
+	case "", NumericItemLiteral, NumericItemCanonical:
+	default:
+		return ErrUnknownNumericItemPolicy
+	}
+	switch args.OutputCompression {
+	case "", OutputCompressionNone, OutputCompressionGzip:
+	default:
+		return ErrUnknownOutputCompression
+	}
 	return nil
 }
@@ -16,6 +16,7 @@ package arm_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/nokia/arm-go"
 )
@@ -40,6 +41,56 @@ func TestArgumentsValidate(t *testing.T) {
 		{"minconfidence<1", arm.Arguments{MinLift: 0.9}, arm.ErrMinLiftOutOfRange},
 		{"minconfidence=1", arm.Arguments{MinLift: 1.0}, nil},
 		{"minconfidence>1", arm.Arguments{MinLift: 1.1}, nil},
+		{"inputformat=jsonl", arm.Arguments{InputFormat: arm.InputFormatJSONL}, nil},
+		{"inputformat=unknown", arm.Arguments{InputFormat: "xml"}, arm.ErrUnknownInputFormat},
+		{"outputformat=csv", arm.Arguments{OutputFormat: arm.OutputFormatCSV}, nil},
+		{"outputformat=sql", arm.Arguments{OutputFormat: arm.OutputFormatSQL}, nil},
+		{"outputformat=unknown", arm.Arguments{OutputFormat: "xml"}, arm.ErrUnknownOutputFormat},
+		{"sqltable=schema.table", arm.Arguments{SQLTable: "shop.rules"}, nil},
+		{"sortby=kulczynski", arm.Arguments{SortBy: arm.SortByKulczynski}, nil},
+		{"sortby=unknown", arm.Arguments{SortBy: "interest"}, arm.ErrUnknownSortBy},
+		{"topk<0", arm.Arguments{TopK: -1}, arm.ErrTopKOutOfRange},
+		{"topk>0", arm.Arguments{TopK: 10}, nil},
+		{"sqltable=injection", arm.Arguments{SQLTable: "rules; DROP TABLE x"}, arm.ErrInvalidSQLTable},
+		{"outputformat=cypher", arm.Arguments{OutputFormat: arm.OutputFormatCypher, CypherLabel: "Product"}, nil},
+		{"cypherlabel=injection", arm.Arguments{CypherLabel: "Item}) DETACH DELETE (n"}, arm.ErrInvalidCypherLabel},
+		{"minjaccard>1", arm.Arguments{MinJaccard: 1.5}, arm.ErrMinJaccardOutOfRange},
+		{"minjaccard=0.5", arm.Arguments{MinJaccard: 0.5}, nil},
+		{"supportbins-decreasing", arm.Arguments{SupportBins: []float64{0.05, 0.01}}, arm.ErrSupportBinsOutOfRange},
+		{"supportbins=1", arm.Arguments{SupportBins: []float64{0.5, 1}}, arm.ErrSupportBinsOutOfRange},
+		{"supportbins", arm.Arguments{SupportBins: []float64{0.01, 0.05}}, nil},
+		{"checkpointinterval<0", arm.Arguments{CheckpointInterval: -time.Second}, arm.ErrCheckpointIntervalOutOfRange},
+		{"checkpoint-with-focus", arm.Arguments{CheckpointPath: "checkpoint.jsonl", FocusItem: "milk"}, arm.ErrCheckpointConflict},
+		{"topk-with-maxitemsets", arm.Arguments{TopK: 10, MaxItemsets: 100}, nil},
+		{"topk-with-streamitemsets", arm.Arguments{TopK: 10, StreamItemsets: true, ItemsetsPath: "itemsets.csv"}, arm.ErrTopKConflict},
+		{"maxitemsets-without-topk", arm.Arguments{MaxItemsets: 100}, nil},
+		{"labelcolumn<0", arm.Arguments{LabelColumn: -1}, arm.ErrLabelColumnOutOfRange},
+		{"metricoverflowjson=null", arm.Arguments{OutputFormat: arm.OutputFormatJSON, MetricOverflowJSON: arm.MetricOverflowJSONNull}, nil},
+		{"metricoverflowjson-unknown", arm.Arguments{MetricOverflowJSON: "zero"}, arm.ErrUnknownMetricOverflowJSON},
+		{"metricoverflowjson-with-emit", arm.Arguments{MetricOverflow: arm.MetricOverflowEmit, MetricOverflowJSON: arm.MetricOverflowJSONString}, nil},
+		{"metricoverflowjson-with-skip", arm.Arguments{MetricOverflow: arm.MetricOverflowSkip, MetricOverflowJSON: arm.MetricOverflowJSONNull}, arm.ErrMetricOverflowJSONConflict},
+		{"labelcolumn=idcolumn", arm.Arguments{ClassRulesOnly: true, IDColumn: 1}, arm.ErrLabelColumnOutOfRange},
+		{"idcolumn=-1", arm.Arguments{IDColumn: -1}, nil},
+		{"idcolumn<-1", arm.Arguments{IDColumn: -2}, arm.ErrIDColumnOutOfRange},
+		{"pivot-with-idcolumn=-1", arm.Arguments{PivotKey: "item", PivotGroup: "user", IDColumn: -1}, nil},
+		{"classrules-with-bothdirections", arm.Arguments{ClassRulesOnly: true, BothDirections: true}, arm.ErrClassRulesOnlyConflict},
+		{"parquet-with-gzip", arm.Arguments{OutputFormat: arm.OutputFormatParquet, OutputCompression: arm.OutputCompressionGzip}, arm.ErrOutputCompressionUnsupported},
+		{"parquet-with-gz-path", arm.Arguments{OutputFormat: arm.OutputFormatParquet, Output: "rules.parquet.gz"}, arm.ErrOutputCompressionUnsupported},
+		{"parquet-with-gz-itemsets", arm.Arguments{OutputFormat: arm.OutputFormatParquet, ItemsetsPath: "itemsets.parquet.gz"}, arm.ErrOutputCompressionUnsupported},
+		{"parquet-with-none", arm.Arguments{OutputFormat: arm.OutputFormatParquet, Output: "rules.parquet.gz", OutputCompression: arm.OutputCompressionNone}, nil},
+		{"onehot-with-glob", arm.Arguments{InputFormat: arm.InputFormatOneHot, Input: "data/*.csv"}, arm.ErrOneHotGlobUnsupported},
+		{"onehot-with-validation-glob", arm.Arguments{InputFormat: arm.InputFormatOneHot, Input: "data.csv", ValidationInput: "holdout-*.csv"}, arm.ErrOneHotGlobUnsupported},
+		{"onehot-without-glob", arm.Arguments{InputFormat: arm.InputFormatOneHot, Input: "data.csv"}, nil},
+		{"closed-and-maximal", arm.Arguments{Closed: true, Maximal: true}, arm.ErrClosedMaximalConflict},
+		{"closed-with-maxitemsets", arm.Arguments{Closed: true, MaxItemsets: 100}, arm.ErrItemsetFilterConflict},
+		{"maximal-with-singleitemonly", arm.Arguments{Maximal: true, SingleItemOnly: true}, arm.ErrItemsetFilterConflict},
+		{"maximal", arm.Arguments{Maximal: true}, nil},
+		{"topfrequentitems<0", arm.Arguments{TopFrequentItems: -1}, arm.ErrTopFrequentItemsOutOfRange},
+		{"topconsequentsperitem<0", arm.Arguments{TopConsequentsPerItem: -1}, arm.ErrTopConsequentsPerItemOutOfRange},
+		{"adjacency-with-shards", arm.Arguments{OutputFormat: arm.OutputFormatAdjacency, OutputShards: 2}, arm.ErrOutputShardsUnsupported},
+		{"singleitem-with-stream", arm.Arguments{SingleItemOnly: true, StreamItemsets: true, ItemsetsPath: "itemsets.csv"}, arm.ErrSingleItemOnlyConflict},
+		{"vocabularywarnratio<0", arm.Arguments{VocabularyWarnRatio: -1}, arm.ErrVocabularyWarnRatioOutOfRange},
+		{"minvalidationconfidence<0", arm.Arguments{MinValidationConfidence: -0.1}, arm.ErrMinValidationConfidenceOutOfRange},
 	}
 	for _, tt := range tests {
 		tt := tt
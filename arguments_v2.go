@@ -17,19 +17,35 @@ package arm
 import (
 	"errors"
 	"io"
+	"math"
+	"time"
 )
 
 var (
-	ErrItemsReaderIsNil = errors.New("ItemsReader may not be nil")
-	ErrRulesWriterIsNil = errors.New("RulesWriter may not be nil")
+	ErrItemsReaderIsNil      = errors.New("ItemsReader may not be nil")
+	ErrRulesWriterIsNil      = errors.New("RulesWriter may not be nil")
+	ErrRuleItemsWriterIsNil  = errors.New("RuleItemsWriter may not be nil with OutputFormat long")
+	ErrRuleShardWriterIsNil  = errors.New("RuleShardWriter may not be nil with OutputShards")
+	ErrValidationReaderIsNil = errors.New("ValidationReader may not be nil with MinValidationConfidence")
 )
 
 type (
-	ItemsReader    func() (io.ReadCloser, error)
-	RulesWriter    func() (io.WriteCloser, error)
-	ItemsetsWriter func() (io.WriteCloser, error)
+	ItemsReader               func() (io.ReadCloser, error)
+	RulesWriter               func() (io.WriteCloser, error)
+	ItemsetsWriter            func() (io.WriteCloser, error)
+	NegativeCorrelationWriter func() (io.WriteCloser, error)
+	ItemizerWriter            func() (io.WriteCloser, error)
+	ItemStatsWriter           func() (io.WriteCloser, error)
+	RuleItemsWriter           func() (io.WriteCloser, error)
+	RuleShardWriter           func(shard int) (io.WriteCloser, error)
 )
 
+// ItemOrder reports whether item a, which appears in freqA transactions,
+// is inserted into the FP-tree before item b, which appears in freqB. It
+// must be a strict weak ordering; items it considers equal are ordered
+// lexicographically.
+type ItemOrder func(a, b Item, freqA, freqB int) bool
+
 type ArgumentsV2 struct {
 	ItemsReader    ItemsReader
 	RulesWriter    RulesWriter
@@ -37,6 +53,104 @@ type ArgumentsV2 struct {
 	MinSupport     float64
 	MinConfidence  float64
 	MinLift        float64
+	DryRun         bool
+	ItemOrder      ItemOrder
+	OutputFormat   string
+	SQLTable       string
+	// The writers append to existing content. Headers are skipped if the
+	// writer is a file, or anything with a Stat method, which is not empty.
+	AppendOutput     bool
+	SortBy           string
+	TopK             int
+	SortOutput       bool
+	RulesOnlyText    bool
+	Stats            *Stats
+	InputFormat      string
+	NormalizeUnicode bool
+	// If set, negatively correlated pairs of frequent items are written.
+	NegativeCorrelationWriter NegativeCorrelationWriter
+	MaxNegativeLift           float64
+	MaxItemsPerTransaction    int
+	SkipLongTransactions      bool
+	// Compression of everything written, defaults to none.
+	OutputCompression          string
+	MaxRecursionDepth          int
+	Workers                    int
+	Aliases                    map[string]string
+	LaplaceSmoothing           float64
+	OutputItemIDs              bool
+	SkipMalformed              bool
+	IDColumn                   int
+	GroupByAntecedent          bool
+	RuleArrow                  string
+	ItemSeparator              string
+	EmitMetadataHeader         bool
+	RespectNegation            bool
+	MinSupportPPM              int
+	RecordItemsetsWithoutRules bool
+	TieBreak                   string
+	MetricOverflow             string
+	MineColumns                []int
+	EmitRuleIDs                bool
+	MinRuleSupportCount        int
+	SeparateRuleColumns        bool
+	MaxOutputBytes             int64
+	CollapseWhitespace         bool
+	MinConfidenceLift          float64
+	PivotKey                   string
+	PivotGroup                 string
+	VerifySupports             bool
+	FocusItem                  string
+	MaxItemsets                int
+	StreamItemsets             bool
+	MaxLineBytes               int
+	AttributeSeparator         string
+	MinTransactions            int
+	BothDirections             bool
+	OnProgress                 func(phase string, fraction float64)
+	SupportAsPercent           bool
+	PhaseTimeouts              map[string]time.Duration
+	CypherLabel                string
+	MinExcessCount             int
+	MinJaccard                 float64
+	FieldSplitFunc             func(line string) []string
+	IncludeBaselineRules       bool
+	NumericItemPolicy          string
+	OutputShards               int
+	PrintSummary               bool
+	LiftExclusive              bool
+	MinValidationConfidence    float64
+	VocabularyWarnRatio        float64
+	CheckpointPath             string
+	CheckpointInterval         time.Duration
+	EmitSizes                  bool
+	SupportBins                []float64
+	LabelColumn                int
+	ClassRulesOnly             bool
+	SingleItemOnly             bool
+	TopConsequentsPerItem      int
+	TopFrequentItems           int
+	TransactionFilter          func(fields []string) bool
+	MinSupportCount            int
+	MetricOverflowJSON         string
+	Closed                     bool
+	Maximal                    bool
+	// If set, the Itemizer is written as JSON.
+	ItemizerWriter ItemizerWriter
+	// If set, the count, support and rank of each item are written as CSV,
+	// even with DryRun.
+	ItemStatsWriter ItemStatsWriter
+	// The items of the rules are written to this with OutputFormatLong,
+	// which needs it.
+	RuleItemsWriter RuleItemsWriter
+	// The rules of each shard, from 0 to OutputShards-1, are written to
+	// this with OutputShards, which needs it. RulesWriter is then unused.
+	RuleShardWriter RuleShardWriter
+	// If set, each rule is scored on the dataset read from this too, as
+	// ValidationInput describes. MinValidationConfidence needs it.
+	ValidationReader ItemsReader
+	// The files the writers write to, if they come from Arguments.
+	paths outputPaths
 }
 
 func (args ArgumentsV2) Validate() error {
@@ -46,9 +160,113 @@ func (args ArgumentsV2) Validate() error {
 	if args.RulesWriter == nil {
 		return ErrRulesWriterIsNil
 	}
+	return args.validateOptions()
+}
+
+// validateOptions validates the options of args, but not the reader and
+// writers, except for those the options need.
+func (args ArgumentsV2) validateOptions() error {
+	if args.StreamItemsets && args.ItemsetsWriter == nil {
+		return ErrStreamItemsetsWithoutOutput
+	}
+	if args.OutputFormat == OutputFormatLong && args.RuleItemsWriter == nil {
+		return ErrRuleItemsWriterIsNil
+	}
+	if args.OutputShards > 1 && args.RuleShardWriter == nil {
+		return ErrRuleShardWriterIsNil
+	}
+	if args.MinValidationConfidence > 0 && args.ValidationReader == nil {
+		return ErrValidationReaderIsNil
+	}
 	return Arguments{
-		MinSupport:    args.MinSupport,
-		MinConfidence: args.MinConfidence,
-		MinLift:       args.MinLift,
+		MinSupport:              args.MinSupport,
+		MinConfidence:           args.MinConfidence,
+		MinLift:                 args.MinLift,
+		OutputFormat:            args.OutputFormat,
+		SQLTable:                args.SQLTable,
+		SortBy:                  args.SortBy,
+		TopK:                    args.TopK,
+		InputFormat:             args.InputFormat,
+		MaxNegativeLift:         args.MaxNegativeLift,
+		AppendOutput:            args.AppendOutput,
+		MaxItemsPerTransaction:  args.MaxItemsPerTransaction,
+		OutputCompression:       args.OutputCompression,
+		MaxRecursionDepth:       args.MaxRecursionDepth,
+		Workers:                 args.Workers,
+		LaplaceSmoothing:        args.LaplaceSmoothing,
+		IDColumn:                args.IDColumn,
+		MinSupportPPM:           args.MinSupportPPM,
+		MinSupportCount:         args.MinSupportCount,
+		TieBreak:                args.TieBreak,
+		MetricOverflow:          args.MetricOverflow,
+		MetricOverflowJSON:      args.MetricOverflowJSON,
+		MineColumns:             args.MineColumns,
+		MinRuleSupportCount:     args.MinRuleSupportCount,
+		MaxOutputBytes:          args.MaxOutputBytes,
+		MinConfidenceLift:       args.MinConfidenceLift,
+		PivotKey:                args.PivotKey,
+		PivotGroup:              args.PivotGroup,
+		MaxItemsets:             args.MaxItemsets,
+		VerifySupports:          args.VerifySupports,
+		FocusItem:               args.FocusItem,
+		StreamItemsets:          args.StreamItemsets,
+		MaxLineBytes:            args.MaxLineBytes,
+		MinTransactions:         args.MinTransactions,
+		PhaseTimeouts:           args.PhaseTimeouts,
+		CypherLabel:             args.CypherLabel,
+		MinExcessCount:          args.MinExcessCount,
+		MinJaccard:              args.MinJaccard,
+		FieldSplitFunc:          args.FieldSplitFunc,
+		NumericItemPolicy:       args.NumericItemPolicy,
+		OutputShards:            args.OutputShards,
+		MinValidationConfidence: args.MinValidationConfidence,
+		VocabularyWarnRatio:     args.VocabularyWarnRatio,
+		CheckpointPath:          args.CheckpointPath,
+		CheckpointInterval:      args.CheckpointInterval,
+		SupportBins:             args.SupportBins,
+		LabelColumn:             args.LabelColumn,
+		ClassRulesOnly:          args.ClassRulesOnly,
+		BothDirections:          args.BothDirections,
+		SingleItemOnly:          args.SingleItemOnly,
+		TopConsequentsPerItem:   args.TopConsequentsPerItem,
+		TopFrequentItems:        args.TopFrequentItems,
+		Closed:                  args.Closed,
+		Maximal:                 args.Maximal,
 	}.Validate()
 }
+
+// labelColumn returns the 1-based column of the class label, or 0 if
+// there is none, as only ClassRulesOnly reads one.
+func (args ArgumentsV2) labelColumn() int {
+	if !args.ClassRulesOnly {
+		return 0
+	}
+	if args.LabelColumn == 0 {
+		return 1
+	}
+	return args.LabelColumn
+}
+
+// convertMinSupportPPM sets MinSupport from MinSupportPPM, if that is set,
+// so that the rest of mining only deals with MinSupport.
+func (args *ArgumentsV2) convertMinSupportPPM() {
+	if args.MinSupportPPM > 0 {
+		args.MinSupport = float64(args.MinSupportPPM) / 1e6
+		args.MinSupportPPM = 0
+	}
+}
+
+// convertMinSupportCount sets MinSupport from MinSupportCount, if that is
+// set, once the first pass has counted numTransactions, to the highest
+// support whose minimum count is MinSupportCount.
+func (args *ArgumentsV2) convertMinSupportCount(numTransactions int) {
+	if args.MinSupportCount == 0 || numTransactions == 0 {
+		return
+	}
+	minSupport := float64(args.MinSupportCount) / float64(numTransactions)
+	for minSupport > 0 && minCountFor(minSupport, numTransactions) > args.MinSupportCount {
+		minSupport = math.Nextafter(minSupport, 0)
+	}
+	args.MinSupport = minSupport
+	args.MinSupportCount = 0
+}
@@ -45,6 +45,19 @@ func TestArgumentsV2Validate(t *testing.T) {
 		{"minconfidence<1", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinLift: 0.9}, arm.ErrMinLiftOutOfRange},
 		{"minconfidence=1", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinLift: 1.0}, nil},
 		{"minconfidence>1", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinLift: 1.1}, nil},
+		{"minsupportppm<0", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinSupportPPM: -1}, arm.ErrMinSupportPPMOutOfRange},
+		{"minsupportppm=500", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinSupportPPM: 500}, nil},
+		{"minsupportppm>1000000", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinSupportPPM: 1000001}, arm.ErrMinSupportPPMOutOfRange},
+		{"minsupportppm-and-minsupport", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinSupport: 0.1, MinSupportPPM: 500}, arm.ErrMinSupportConflict},
+		{"minsupportcount<0", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinSupportCount: -1}, arm.ErrMinSupportCountOutOfRange},
+		{"minsupportcount=2", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinSupportCount: 2}, nil},
+		{"minsupportcount-and-minsupport", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinSupport: 0.1, MinSupportCount: 2}, arm.ErrMinSupportConflict},
+		{"minsupportcount-and-minsupportppm", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinSupportPPM: 500, MinSupportCount: 2}, arm.ErrMinSupportConflict},
+		{"workers<0", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, Workers: -1}, arm.ErrWorkersOutOfRange},
+		{"long-without-items", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, OutputFormat: arm.OutputFormatLong}, arm.ErrRuleItemsWriterIsNil},
+		{"minvalidationconfidence-without-reader", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, MinValidationConfidence: 0.5}, arm.ErrValidationReaderIsNil},
+		{"minvalidationconfidence>1", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, ValidationReader: r, MinValidationConfidence: 1.5}, arm.ErrMinValidationConfidenceOutOfRange},
+		{"minvalidationconfidence=0.5", arm.ArgumentsV2{ItemsReader: r, RulesWriter: w, ValidationReader: r, MinValidationConfidence: 0.5}, nil},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -20,11 +20,16 @@ package arm
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -37,53 +42,414 @@ type Logger interface {
 	Printf(string, ...interface{})
 }
 
-func writeItemsets(itemsets []itemsetWithCount, itemsetsWriter ItemsetsWriter, itemizer *Itemizer, numTransactions int) error {
-	output, err := itemsetsWriter()
+// closeOutput closes output, reporting its error unless an earlier one
+// is already being returned through err.
+func closeOutput(output io.Closer, err *error) {
+	if cerr := output.Close(); *err == nil {
+		*err = cerr
+	}
+}
+
+// outputPaths are the files which the writers argumentsV2 makes write to,
+// so that errors can name them.
+type outputPaths struct {
+	rules, itemsets, itemizer, itemStats, negativeCorrelations string
+}
+
+// writeError wraps err, from writing what, with the path it was written
+// to, if that is known.
+func writeError(what string, path string, err error) error {
+	if path == "" {
+		return fmt.Errorf("writing %s: %w", what, err)
+	}
+	return fmt.Errorf("writing %s to %q: %w", what, path, err)
+}
+
+// openOutput opens the file at path for writing, either truncating it or
+// appending to it, and compressing what is written if compression, or
+// else the extension of path, asks for it.
+func openOutput(path string, appendOutput bool, compression string) (io.WriteCloser, error) {
+	var f *os.File
+	var err error
+	if appendOutput {
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	} else {
+		f, err = os.Create(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return compress(f, compressionFor(compression, path)), nil
+}
+
+// writeHeader reports whether a header should be written to output. When
+// appending, the header is only written if output is not a file which
+// already has content.
+func writeHeader(output io.Writer, appendOutput bool) bool {
+	if !appendOutput {
+		return true
+	}
+	f, ok := output.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return true
+	}
+	fi, err := f.Stat()
+	return err != nil || fi.Size() == 0
+}
+
+// errOutputLimit is returned by a limitedWriter once its limit is reached.
+var errOutputLimit = errors.New("output limit reached")
+
+// limitedWriter writes up to remaining bytes to w, in whole lines, and then
+// fails with errOutputLimit.
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= l.remaining {
+		n, err := l.w.Write(p)
+		l.remaining -= int64(n)
+		return n, err
+	}
+	n := bytes.LastIndexByte(p[:l.remaining], '\n') + 1
+	l.remaining = 0
+	if _, err := l.w.Write(p[:n]); err != nil {
+		return 0, err
+	}
+	return n, errOutputLimit
+}
+
+// writeMetadata writes the line EmitMetadataHeader asks for, if it does.
+// With AppendOutput, each run adds its own line.
+func writeMetadata(w io.Writer, args ArgumentsV2, numTransactions int) error {
+	if !args.EmitMetadataHeader {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "# MinSupport=%f MinConfidence=%f MinLift=%f NumTransactions=%d Time=%s\n",
+		args.MinSupport, args.MinConfidence, args.MinLift, numTransactions, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func writeItemsets(itemsets []itemsetWithCount, args ArgumentsV2, itemizer *Itemizer, numTransactions int) (err error) {
+	output, err := openWriter(args.ItemsetsWriter, args.OutputCompression)
 	if err != nil {
 		return err
 	}
-	defer output.Close()
+	defer closeOutput(output, &err)
+	if args.Closed || args.Maximal {
+		itemsets = condenseItemsets(itemsets, args.Maximal)
+	}
 	w := bufio.NewWriter(output)
-	if _, err := fmt.Fprintln(w, "Itemset,Support"); err != nil {
+	switch args.OutputFormat {
+	case OutputFormatParquet:
+		if err := writeItemsetsParquet(w, itemsets, itemizer, numTransactions); err != nil {
+			return err
+		}
+		return w.Flush()
+	case OutputFormatTreeJSON:
+		if err := writeItemsetsTreeJSON(w, itemsets, itemizer, numTransactions); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+	if err := writeItemsetsHeader(w, output, args, numTransactions); err != nil {
 		return err
 	}
-	n := float64(numTransactions)
+	sep := orDefault(args.ItemSeparator, " ")
 	for _, iwc := range itemsets {
-		first := true
-		for _, item := range iwc.itemset {
-			if !first {
-				if _, err := fmt.Fprintf(w, " "); err != nil {
-					return err
-				}
-			}
-			first = false
-			if _, err := fmt.Fprint(w, itemizer.toStr(item)); err != nil {
-				return err
-			}
-		}
-		if _, err := fmt.Fprintf(w, " %f\n", float64(iwc.count)/n); err != nil {
+		if err := writeItemset(w, iwc, itemizer, sep, numTransactions, args.supportFormat()); err != nil {
 			return err
 		}
 	}
 	return w.Flush()
 }
 
-func writeRules(rules [][]Rule, rulesWriter RulesWriter, itemizer *Itemizer) error {
-	output, err := rulesWriter()
+// writeItemsetsHeader writes the lines which precede the itemsets in CSV
+// to w, which buffers output.
+func writeItemsetsHeader(w io.Writer, output io.Writer, args ArgumentsV2, numTransactions int) error {
+	if err := writeMetadata(w, args, numTransactions); err != nil {
+		return err
+	}
+	if writeHeader(output, args.AppendOutput) {
+		if _, err := fmt.Fprintln(w, "Itemset,Support"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeItemset writes the line of iwc in CSV, with its items separated by
+// sep, and its support formatted by format.
+func writeItemset(w io.Writer, iwc itemsetWithCount, itemizer *Itemizer, sep string, numTransactions int, format supportFormat) error {
+	support := format.format(float64(iwc.count) / float64(numTransactions))
+	_, err := fmt.Fprintf(w, "%s %s\n", itemizer.join(iwc.itemset, sep), support)
+	return err
+}
+
+// supportFormat selects how supports are written in CSV.
+type supportFormat struct {
+	// Write supports as percentages.
+	percent bool
+	// If set, write the bin of each support instead, as SupportBins.
+	bins []float64
+}
+
+func (args ArgumentsV2) supportFormat() supportFormat {
+	return supportFormat{percent: args.SupportAsPercent, bins: args.SupportBins}
+}
+
+// format formats support with six decimals, or as a percentage with four
+// decimals, which is as precise, or as the label of its bin.
+func (f supportFormat) format(support float64) string {
+	if len(f.bins) > 0 {
+		return supportBin(support, f.bins)
+	}
+	if f.percent {
+		return strconv.FormatFloat(support*100, 'f', 4, 64) + "%"
+	}
+	return strconv.FormatFloat(support, 'f', 6, 64)
+}
+
+// supportBin returns the label of the bin of bins, the increasing bounds
+// between 0 and 1, which holds support, such as "1-5%". Each bin holds its
+// lower bound, and the last one also holds 1.
+func supportBin(support float64, bins []float64) string {
+	i := sort.SearchFloat64s(bins, support)
+	if i < len(bins) && bins[i] == support {
+		i++
+	}
+	lower, upper := 0.0, 1.0
+	if i > 0 {
+		lower = bins[i-1]
+	}
+	if i < len(bins) {
+		upper = bins[i]
+	}
+	return percentLabel(lower) + "-" + percentLabel(upper) + "%"
+}
+
+// percentLabel formats fraction as a percentage, without the sign or
+// trailing zeros.
+func percentLabel(fraction float64) string {
+	s := strconv.FormatFloat(fraction*100, 'f', 4, 64)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
+
+// writeItemizer writes the strings of the items, so that output written
+// with OutputItemIDs can be translated back.
+func writeItemizer(itemizer *Itemizer, args ArgumentsV2) (err error) {
+	output, err := openWriter(args.ItemizerWriter, args.OutputCompression)
 	if err != nil {
 		return err
 	}
-	defer output.Close()
+	defer closeOutput(output, &err)
+	return json.NewEncoder(output).Encode(itemizer)
+}
+
+// writeItemStats writes the count, support and rank of each item, by
+// decreasing count. Items with the same count share the rank of the first
+// of them, and are ordered by name.
+func writeItemStats(frequency *itemCount, args ArgumentsV2, itemizer *Itemizer, numTransactions int) (err error) {
+	output, err := openWriter(args.ItemStatsWriter, args.OutputCompression)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(output, &err)
+	items := make([]Item, 0)
+	for idx, count := range frequency.counts {
+		if count > 0 {
+			items = append(items, Item(idx))
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if ci, cj := frequency.get(items[i]), frequency.get(items[j]); ci != cj {
+			return ci > cj
+		}
+		return itemizer.cmp(items[i], items[j])
+	})
 	w := bufio.NewWriter(output)
-	if _, err := fmt.Fprintln(w, "Antecedent => Consequent,Confidence,Lift,Support"); err != nil {
+	if _, err := fmt.Fprintln(w, "Item,Count,Support,Rank"); err != nil {
 		return err
 	}
+	rank := 0
+	for i, item := range items {
+		count := frequency.get(item)
+		if i == 0 || count != frequency.get(items[i-1]) {
+			rank = i + 1
+		}
+		support := args.supportFormat().format(float64(count) / float64(numTransactions))
+		if _, err := fmt.Fprintf(w, "%s,%d,%s,%d\n", itemizer.toStr(item), count, support, rank); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func writeRules(rules [][]Rule, args ArgumentsV2, itemizer *Itemizer, numTransactions int) (err error) {
+	if args.OutputShards > 1 {
+		return writeRuleShards(rules, args, itemizer, numTransactions)
+	}
+	output, err := openWriter(args.RulesWriter, args.OutputCompression)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(output, &err)
+	var target io.Writer = output
+	if args.MaxOutputBytes > 0 {
+		target = &limitedWriter{w: output, remaining: args.MaxOutputBytes}
+	}
+	w := bufio.NewWriter(target)
+	if args.GroupByAntecedent {
+		rules = groupByAntecedent(rules, itemizer)
+	}
+	switch args.OutputFormat {
+	case OutputFormatSQL:
+		err = writeRulesSQL(w, rules, itemizer, args.SQLTable)
+	case OutputFormatCypher:
+		err = writeRulesCypher(w, rules, itemizer, args.CypherLabel)
+	case OutputFormatAdjacency:
+		err = writeRulesAdjacency(w, rules, itemizer, args.TopConsequentsPerItem)
+	case OutputFormatJSON:
+		err = writeRulesJSON(w, rules, itemizer, args.MetricOverflowJSON)
+	case OutputFormatLong:
+		err = writeMetadata(w, args, numTransactions)
+		if err == nil {
+			err = writeRulesLong(w, writeHeader(output, args.AppendOutput), rules, args, itemizer)
+		}
+	case OutputFormatParquet:
+		err = writeRulesParquet(w, rules, itemizer)
+	default:
+		err = writeMetadata(w, args, numTransactions)
+		if err == nil {
+			err = writeRulesCSV(w, rules, itemizer, csvFormat{
+				header:   !args.RulesOnlyText && writeHeader(output, args.AppendOutput),
+				metrics:  !args.RulesOnlyText,
+				columns:  ruleColumns(args),
+				group:    args.GroupByAntecedent,
+				arrow:    args.RuleArrow,
+				itemSep:  args.ItemSeparator,
+				separate: args.SeparateRuleColumns,
+				support:  args.supportFormat(),
+			})
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == errOutputLimit {
+		if args.Stats != nil {
+			args.Stats.TruncatedOutput = true
+		}
+		return nil
+	}
+	return err
+}
+
+// ruleColumn is a column written after the default ones, if the option
+// it goes with is set.
+type ruleColumn struct {
+	name  string
+	value func(*Rule) string
+}
+
+// measureColumn returns a column of a measure, formatted like the default
+// ones.
+func measureColumn(name string, measure func(*Rule) float64) ruleColumn {
+	return ruleColumn{name, func(r *Rule) string {
+		return strconv.FormatFloat(measure(r), 'f', 6, 64)
+	}}
+}
+
+func ruleColumns(args ArgumentsV2) []ruleColumn {
+	columns := make([]ruleColumn, 0)
+	if args.LaplaceSmoothing > 0 {
+		columns = append(columns, measureColumn("CorrectedConfidence", func(r *Rule) float64 { return r.CorrectedConfidence }))
+	}
+	if args.MinConfidenceLift > 0 {
+		columns = append(columns, measureColumn("ConfidenceLift", func(r *Rule) float64 { return r.ConfidenceLift }))
+	}
+	if args.MinExcessCount > 0 {
+		columns = append(columns, measureColumn("ExcessCount", func(r *Rule) float64 { return r.ExcessCount }))
+	}
+	if args.MinJaccard > 0 {
+		columns = append(columns, measureColumn("Jaccard", func(r *Rule) float64 { return r.Jaccard }))
+	}
+	if args.ValidationReader != nil {
+		columns = append(columns,
+			measureColumn("ValidationSupport", func(r *Rule) float64 { return r.ValidationSupport }),
+			measureColumn("ValidationConfidence", func(r *Rule) float64 { return r.ValidationConfidence }),
+			measureColumn("ValidationLift", func(r *Rule) float64 { return r.ValidationLift }))
+	}
+	if args.EmitSizes {
+		columns = append(columns,
+			ruleColumn{"AntecedentSize", func(r *Rule) string { return strconv.Itoa(len(r.Antecedent)) }},
+			ruleColumn{"ConsequentSize", func(r *Rule) string { return strconv.Itoa(len(r.Consequent)) }})
+	}
+	if args.EmitRuleIDs {
+		columns = append(columns, ruleColumn{"ID", func(r *Rule) string { return fmt.Sprintf("%016x", r.ID) }})
+	}
+	return columns
+}
+
+// csvFormat selects what writeRulesCSV writes.
+type csvFormat struct {
+	header  bool
+	metrics bool
+	columns []ruleColumn
+	// Separate groups of rules with the same antecedent by a blank line.
+	group bool
+	// Separators between antecedent and consequent, and between items.
+	// Empty means " => " and a space.
+	arrow   string
+	itemSep string
+	// Write antecedent and consequent as separate columns, ignoring arrow.
+	separate bool
+	// How to write the support.
+	support supportFormat
+}
+
+// orDefault returns s, or def if s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func writeRulesCSV(w io.Writer, rules [][]Rule, itemizer *Itemizer, format csvFormat) error {
+	arrow := orDefault(format.arrow, " => ")
+	if format.separate {
+		arrow = ","
+	}
+	sep := orDefault(format.itemSep, " ")
+	if format.header {
+		if _, err := fmt.Fprintf(w, "Antecedent%sConsequent,Confidence,Lift,Support", arrow); err != nil {
+			return err
+		}
+		for _, column := range format.columns {
+			if _, err := fmt.Fprintf(w, ",%s", column.name); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	var previous []Item
 	for _, chunk := range rules {
 		for _, rule := range chunk {
+			if format.group && previous != nil && !itemSliceEquals(previous, rule.Antecedent) {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+			previous = rule.Antecedent
 			first := true
 			for _, item := range rule.Antecedent {
 				if !first {
-					if _, err := fmt.Fprintf(w, " "); err != nil {
+					if _, err := fmt.Fprint(w, sep); err != nil {
 						return err
 					}
 				}
@@ -92,13 +458,13 @@ func writeRules(rules [][]Rule, rulesWriter RulesWriter, itemizer *Itemizer) err
 					return err
 				}
 			}
-			if _, err := fmt.Fprint(w, " => "); err != nil {
+			if _, err := fmt.Fprint(w, arrow); err != nil {
 				return err
 			}
 			first = true
 			for _, item := range rule.Consequent {
 				if !first {
-					if _, err := fmt.Fprintf(w, " "); err != nil {
+					if _, err := fmt.Fprint(w, sep); err != nil {
 						return err
 					}
 				}
@@ -107,12 +473,27 @@ func writeRules(rules [][]Rule, rulesWriter RulesWriter, itemizer *Itemizer) err
 					return err
 				}
 			}
-			if _, err := fmt.Fprintf(w, ",%f,%f,%f\n", rule.Confidence, rule.Lift, rule.Support); err != nil {
+			if !format.metrics {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+				continue
+			}
+			support := format.support.format(rule.Support)
+			if _, err := fmt.Fprintf(w, ",%f,%f,%s", rule.Confidence, rule.Lift, support); err != nil {
+				return err
+			}
+			for _, column := range format.columns {
+				if _, err := fmt.Fprintf(w, ",%s", column.value(&rule)); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintln(w); err != nil {
 				return err
 			}
 		}
 	}
-	return w.Flush()
+	return nil
 }
 
 func countRules(rules [][]Rule) int {
@@ -123,138 +504,544 @@ func countRules(rules [][]Rule) int {
 	return n
 }
 
-func countItems(itemsReader ItemsReader) (*Itemizer, *itemCount, int, error) {
-	file, err := itemsReader()
-	if err != nil {
-		return nil, nil, 0, err
-	}
-	defer file.Close()
+// countItems counts the transactions each item appears in. Transactions
+// longer than MaxItemsPerTransaction are counted in stats, and not counted
+// at all if SkipLongTransactions is set. Otherwise only the items they are
+// truncated to are counted, as limitTransaction truncates them, which
+// takes reading them again once the others are counted. Malformed lines
+// are skipped, and logged.
+func countItems(args ArgumentsV2, stats *Stats, log Logger) (*Itemizer, *itemCount, int, error) {
+	return countItemsContext(context.Background(), args, stats, log)
+}
 
+// countItemsContext is countItems, but stops reading when ctx is done, and
+// returns ctx.Err().
+func countItemsContext(ctx context.Context, args ArgumentsV2, stats *Stats, log Logger) (*Itemizer, *itemCount, int, error) {
 	frequency := makeCounts()
-	itemizer := newItemizer()
-
-	scanner := bufio.NewScanner(file)
+	itemizer := itemizerFor(args)
 	numTransactions := 0
-	for scanner.Scan() {
+	skipped, err := scanTransactions(args, log, func(fields []string) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		items := itemizer.itemizeLabelled(fields, args)
+		if longTransaction(args, len(items)) {
+			stats.NumLongTransactions++
+			if !args.SkipLongTransactions {
+				numTransactions++
+			}
+			return true
+		}
 		numTransactions++
-		itemizer.forEachItem(
-			strings.Split(scanner.Text(), ","),
-			func(item Item) {
-				frequency.increment(item, 1)
-			})
+		for _, item := range items {
+			frequency.increment(item, 1)
+		}
+		return true
+	})
+	if err == nil && stats.NumLongTransactions > 0 && !args.SkipLongTransactions {
+		short := frequency.clone()
+		frequency.short = &short
+		_, err = scanTransactions(args, nil, func(fields []string) bool {
+			if ctx.Err() != nil {
+				return false
+			}
+			items := itemizer.Itemize(fields)
+			if longTransaction(args, len(items)) {
+				for _, item := range truncateTransaction(args, items, &itemizer, &frequency) {
+					frequency.increment(item, 1)
+				}
+			}
+			return true
+		})
+	}
+	if err == nil {
+		err = ctx.Err()
 	}
-	if err := scanner.Err(); err != nil {
+	if err != nil {
 		return nil, nil, 0, err
 	}
+	stats.NumMalformedLines = skipped
 	return &itemizer, &frequency, numTransactions, nil
 }
 
-func generateFrequentItemsets(itemsReader ItemsReader, minSupport float64, itemizer *Itemizer, frequency *itemCount, numTransactions int) ([]itemsetWithCount, error) {
-	file, err := itemsReader()
+// longTransaction reports whether a transaction of n items exceeds
+// MaxItemsPerTransaction.
+func longTransaction(args ArgumentsV2, n int) bool {
+	return args.MaxItemsPerTransaction > 0 && n > args.MaxItemsPerTransaction
+}
+
+// limitTransaction returns the items of transaction which appear in at
+// least minCount transactions, once a long transaction is truncated by
+// truncateTransaction. It returns false if the transaction is to be
+// skipped instead. A transaction is long if it has more than
+// MaxItemsPerTransaction items, frequent or not, as the first pass counts
+// it.
+func limitTransaction(args ArgumentsV2, transaction []Item, itemizer *Itemizer, frequency *itemCount, minCount int) ([]Item, bool) {
+	if longTransaction(args, len(transaction)) {
+		if args.SkipLongTransactions {
+			return nil, false
+		}
+		transaction = truncateTransaction(args, transaction, itemizer, frequency)
+	}
+	frequent := transaction[:0]
+	for _, item := range transaction {
+		if frequency.get(item) >= minCount {
+			frequent = append(frequent, item)
+		}
+	}
+	return frequent, true
+}
+
+// truncateTransaction returns the MaxItemsPerTransaction items of a long
+// transaction which appear in the most transactions which are not long,
+// ties broken lexically. Those counts are known before the items of long
+// transactions are counted, so the first pass counts the same items as
+// the second one keeps.
+func truncateTransaction(args ArgumentsV2, transaction []Item, itemizer *Itemizer, frequency *itemCount) []Item {
+	short := frequency
+	if frequency.short != nil {
+		short = frequency.short
+	}
+	byFrequency := itemLess(nil, "", itemizer, short)
+	sort.SliceStable(transaction, func(i, j int) bool {
+		return byFrequency(transaction[i], transaction[j])
+	})
+	return transaction[:args.MaxItemsPerTransaction]
+}
+
+// itemLess returns the order in which items are inserted into the FP-tree.
+// By default that is decreasing frequency, ties broken as tieBreak asks. A
+// custom order falls back to the tie break for items it considers equal,
+// so that every transaction is inserted in the same order.
+func itemLess(order ItemOrder, tieBreak string, itemizer *Itemizer, frequency *itemCount) func(a, b Item) bool {
+	tie := itemizer.cmp
+	switch tieBreak {
+	case TieBreakInsertion:
+		tie = func(a, b Item) bool { return a < b }
+	case TieBreakReverseLexical:
+		tie = func(a, b Item) bool { return itemizer.cmp(b, a) }
+	}
+	if order == nil {
+		return func(a, b Item) bool {
+			if frequency.get(a) == frequency.get(b) {
+				return tie(a, b)
+			}
+			return frequency.get(a) > frequency.get(b)
+		}
+	}
+	return func(a, b Item) bool {
+		freqA := frequency.get(a)
+		freqB := frequency.get(b)
+		if order(a, b, freqA, freqB) {
+			return true
+		}
+		if order(b, a, freqB, freqA) {
+			return false
+		}
+		return tie(a, b)
+	}
+}
+
+// minCountFor converts a support threshold into the minimum number of
+// transactions an itemset must appear in.
+func minCountFor(minSupport float64, numTransactions int) int {
+	return max(1, int(math.Ceil(minSupport*float64(numTransactions))))
+}
+
+func generateFrequentItemsets(args ArgumentsV2, itemizer *Itemizer, frequency *itemCount, numTransactions int, stats *Stats) ([]itemsetWithCount, error) {
+	return generateFrequentItemsetsContext(context.Background(), args, itemizer, frequency, numTransactions, stats)
+}
+
+// generateFrequentItemsetsContext is generateFrequentItemsets, but stops
+// when ctx is done, and returns ctx.Err().
+func generateFrequentItemsetsContext(ctx context.Context, args ArgumentsV2, itemizer *Itemizer, frequency *itemCount, numTransactions int, stats *Stats) ([]itemsetWithCount, error) {
+	minCount := minCountFor(args.MinSupport, numTransactions)
+	tree, err := buildTree(ctx, args, itemizer, frequency, minCount)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	if focus, focused := focusItem(args, itemizer); focused {
+		return focusItemsets(args, tree, focus, itemizer, frequency, minCount, stats)
+	}
+	return growTree(ctx, args, tree, itemizer, minCount, stats)
+}
+
+// streamFrequentItemsets is generateFrequentItemsets, but writes the
+// itemsets to ItemsetsWriter as fpGrowth finds them, rather than returning
+// them, and returns how many there are. It stops when ctx is done, like
+// generateFrequentItemsetsContext.
+func streamFrequentItemsets(ctx context.Context, args ArgumentsV2, itemizer *Itemizer, frequency *itemCount, numTransactions int, stats *Stats) (n int, err error) {
+	minCount := minCountFor(args.MinSupport, numTransactions)
+	tree, err := buildTree(ctx, args, itemizer, frequency, minCount)
+	if err != nil {
+		return 0, err
+	}
+	output, err := openWriter(args.ItemsetsWriter, args.OutputCompression)
+	if err != nil {
+		return 0, writeError("itemsets", args.paths.itemsets, err)
+	}
+	defer func() {
+		closeOutput(output, &err)
+		if err != nil && !errors.Is(err, ctx.Err()) {
+			err = writeError("itemsets", args.paths.itemsets, err)
+		}
+	}()
+	w := bufio.NewWriter(output)
+	if err := writeItemsetsHeader(w, output, args, numTransactions); err != nil {
+		return 0, err
+	}
+	if args.OutputItemIDs {
+		itemizer = itemizer.withIDs()
+	}
+	sep := orDefault(args.ItemSeparator, " ")
+	stats.ReachedMaxRecursionDepth = fpGrowthRoot(ctx, tree, minCount, args.MaxRecursionDepth, func(iwc itemsetWithCount) {
+		n++
+		if err == nil {
+			err = writeItemset(w, iwc, itemizer, sep, numTransactions, args.supportFormat())
+		}
+	}, args.fpGrowthProgress())
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, w.Flush()
+}
 
-	minCount := max(1, int(math.Ceil(minSupport*float64(numTransactions))))
+// buildTree reads the dataset again, and inserts the items of each
+// transaction which appear in at least minCount transactions into an
+// FP-tree. With FocusItem, only the transactions containing it are
+// inserted, with it last. It stops reading when ctx is done, and returns
+// ctx.Err().
+func buildTree(ctx context.Context, args ArgumentsV2, itemizer *Itemizer, frequency *itemCount, minCount int) (*fpTree, error) {
+	less := itemLess(args.ItemOrder, args.TieBreak, itemizer, frequency)
+	focus, focused := focusItem(args, itemizer)
+	if focused {
+		less = focusLast(less, focus)
+	}
 
-	scanner := bufio.NewScanner(file)
 	tree := newTree()
-	for scanner.Scan() {
-		transaction := itemizer.filter(
-			strings.Split(scanner.Text(), ","),
-			func(i Item) bool {
-				return frequency.get(i) >= minCount
-			})
-
-		if len(transaction) == 0 {
-			continue
+	_, err := scanTransactions(args, nil, func(fields []string) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		transaction, ok := limitTransaction(args, itemizer.Itemize(fields), itemizer, frequency, minCount)
+		if !ok || len(transaction) == 0 {
+			return true
+		}
+		if focused && !containsItems(transaction, []Item{focus}) {
+			return true
 		}
-		// Sort by decreasing frequency, tie break lexicographically.
 		sort.SliceStable(transaction, func(i, j int) bool {
-			a := transaction[i]
-			b := transaction[j]
-			if frequency.get(a) == frequency.get(b) {
-				return itemizer.cmp(a, b)
-			}
-			return frequency.get(a) > frequency.get(b)
+			return less(transaction[i], transaction[j])
 		})
 		tree.Insert(transaction, 1)
+		return true
+	})
+	if err == nil {
+		err = ctx.Err()
 	}
-	if err := scanner.Err(); err != nil {
+	if err != nil {
 		return nil, err
 	}
+	return tree, nil
+}
 
-	return fpGrowth(tree, make([]Item, 0), minCount), nil
+// growTree generates the frequent itemsets of tree, whose items itemizer
+// converts to strings. It stops when ctx is done, and returns ctx.Err().
+// With CheckpointPath, it resumes from the checkpoint there, if any.
+func growTree(ctx context.Context, args ArgumentsV2, tree *fpTree, itemizer *Itemizer, minCount int, stats *Stats) ([]itemsetWithCount, error) {
+	var cp *checkpoint
+	if args.CheckpointPath != "" {
+		var err error
+		if cp, err = openCheckpoint(args, tree, itemizer, minCount); err != nil {
+			return nil, err
+		}
+		stats.NumRestoredHeaderItems = len(cp.restored)
+	}
+	var itemsets []itemsetWithCount
+	var truncated bool
+	if args.Workers > 1 || cp != nil {
+		workers := max(1, args.Workers)
+		itemsets, truncated = parallelFPGrowth(ctx, tree, minCount, args.MaxRecursionDepth, workers, args.fpGrowthProgress(), cp)
+	} else {
+		itemsets = make([]itemsetWithCount, 0)
+		truncated = fpGrowthRoot(ctx, tree, minCount, args.MaxRecursionDepth, func(iwc itemsetWithCount) {
+			itemsets = append(itemsets, iwc)
+		}, args.fpGrowthProgress())
+	}
+	stats.ReachedMaxRecursionDepth = truncated
+	if cp != nil {
+		if err := cp.close(ctx.Err() == nil); err != nil && ctx.Err() == nil {
+			return nil, writeError("checkpoint", args.CheckpointPath, err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return itemsets, nil
+}
+
+// fpGrowthProgress returns the progress function for fpGrowthRoot which
+// reports to OnProgress, or nil if that is not set.
+func (args ArgumentsV2) fpGrowthProgress() func(done, total int) {
+	if args.OnProgress == nil {
+		return nil
+	}
+	return func(done, total int) {
+		args.OnProgress(ProgressFPGrowth, float64(done)/float64(total))
+	}
 }
 
 func MineAssociationRules(args Arguments, log Logger) error {
 	if err := args.Validate(); err != nil {
 		return err
 	}
+	args_v2, err := argumentsV2(args, log)
+	if err != nil {
+		return err
+	}
+	return MineAssociationRulesV2(args_v2, log)
+}
 
+// argumentsV2 converts args to ArgumentsV2, whose reader and writers use
+// the files of args.
+func argumentsV2(args Arguments, log Logger) (ArgumentsV2, error) {
+	inputs, err := inputFiles(args.Input)
+	if err != nil {
+		return ArgumentsV2{}, err
+	}
 	args_v2 := ArgumentsV2{
 		ItemsReader: func() (io.ReadCloser, error) {
-			return os.Open(args.Input)
+			return openInputs(inputs)
 		},
 		RulesWriter: func() (io.WriteCloser, error) {
 			log.Printf("Writing rules to '%s'...", args.Output)
-			return os.Create(args.Output)
+			return openOutput(args.Output, args.AppendOutput, args.OutputCompression)
 		},
-		MinSupport:    args.MinSupport,
-		MinConfidence: args.MinConfidence,
-		MinLift:       args.MinLift,
+		paths: outputPaths{
+			rules:                args.Output,
+			itemsets:             args.ItemsetsPath,
+			itemStats:            args.ItemStatsPath,
+			negativeCorrelations: args.NegativeCorrelationPath,
+		},
+		MinSupport:                 args.MinSupport,
+		MinConfidence:              args.MinConfidence,
+		MinLift:                    args.MinLift,
+		DryRun:                     args.DryRun,
+		ItemOrder:                  args.ItemOrder,
+		OutputFormat:               args.OutputFormat,
+		SQLTable:                   args.SQLTable,
+		AppendOutput:               args.AppendOutput,
+		SortBy:                     args.SortBy,
+		TopK:                       args.TopK,
+		SortOutput:                 args.SortOutput,
+		RulesOnlyText:              args.RulesOnlyText,
+		Stats:                      args.Stats,
+		InputFormat:                args.InputFormat,
+		NormalizeUnicode:           args.NormalizeUnicode,
+		MaxNegativeLift:            args.MaxNegativeLift,
+		MaxItemsPerTransaction:     args.MaxItemsPerTransaction,
+		SkipLongTransactions:       args.SkipLongTransactions,
+		MaxRecursionDepth:          args.MaxRecursionDepth,
+		Workers:                    args.Workers,
+		Aliases:                    args.Aliases,
+		LaplaceSmoothing:           args.LaplaceSmoothing,
+		OutputItemIDs:              args.OutputItemIDs,
+		SkipMalformed:              args.SkipMalformed,
+		IDColumn:                   args.IDColumn,
+		GroupByAntecedent:          args.GroupByAntecedent,
+		RuleArrow:                  args.RuleArrow,
+		ItemSeparator:              args.ItemSeparator,
+		EmitMetadataHeader:         args.EmitMetadataHeader,
+		RespectNegation:            args.RespectNegation,
+		MinSupportPPM:              args.MinSupportPPM,
+		RecordItemsetsWithoutRules: args.RecordItemsetsWithoutRules,
+		TieBreak:                   args.TieBreak,
+		MetricOverflow:             args.MetricOverflow,
+		MineColumns:                args.MineColumns,
+		EmitRuleIDs:                args.EmitRuleIDs,
+		MinRuleSupportCount:        args.MinRuleSupportCount,
+		SeparateRuleColumns:        args.SeparateRuleColumns,
+		MaxOutputBytes:             args.MaxOutputBytes,
+		CollapseWhitespace:         args.CollapseWhitespace,
+		MinConfidenceLift:          args.MinConfidenceLift,
+		PivotKey:                   args.PivotKey,
+		PivotGroup:                 args.PivotGroup,
+		VerifySupports:             args.VerifySupports,
+		FocusItem:                  args.FocusItem,
+		MaxItemsets:                args.MaxItemsets,
+		StreamItemsets:             args.StreamItemsets,
+		MaxLineBytes:               args.MaxLineBytes,
+		AttributeSeparator:         args.AttributeSeparator,
+		MinTransactions:            args.MinTransactions,
+		BothDirections:             args.BothDirections,
+		OnProgress:                 args.OnProgress,
+		SupportAsPercent:           args.SupportAsPercent,
+		PhaseTimeouts:              args.PhaseTimeouts,
+		CypherLabel:                args.CypherLabel,
+		MinExcessCount:             args.MinExcessCount,
+		MinJaccard:                 args.MinJaccard,
+		FieldSplitFunc:             args.FieldSplitFunc,
+		IncludeBaselineRules:       args.IncludeBaselineRules,
+		NumericItemPolicy:          args.NumericItemPolicy,
+		OutputShards:               args.OutputShards,
+		PrintSummary:               args.PrintSummary,
+		LiftExclusive:              args.LiftExclusive,
+		MinValidationConfidence:    args.MinValidationConfidence,
+		VocabularyWarnRatio:        args.VocabularyWarnRatio,
+		CheckpointPath:             args.CheckpointPath,
+		CheckpointInterval:         args.CheckpointInterval,
+		EmitSizes:                  args.EmitSizes,
+		SupportBins:                args.SupportBins,
+		LabelColumn:                args.LabelColumn,
+		ClassRulesOnly:             args.ClassRulesOnly,
+		SingleItemOnly:             args.SingleItemOnly,
+		TopConsequentsPerItem:      args.TopConsequentsPerItem,
+		TopFrequentItems:           args.TopFrequentItems,
+		TransactionFilter:          args.TransactionFilter,
+		MinSupportCount:            args.MinSupportCount,
+		MetricOverflowJSON:         args.MetricOverflowJSON,
+		Closed:                     args.Closed,
+		Maximal:                    args.Maximal,
 	}
 	if args.ItemsetsPath != "" {
 		args_v2.ItemsetsWriter = func() (io.WriteCloser, error) {
 			log.Printf("Writing itemsets to '%s'\n", args.ItemsetsPath)
-			return os.Create(args.ItemsetsPath)
+			return openOutput(args.ItemsetsPath, args.AppendOutput, args.OutputCompression)
 		}
 	}
-
-	return MineAssociationRulesV2(args_v2, log)
+	if args.OutputItemIDs || args.ItemizerPath != "" {
+		path := args.ItemizerPath
+		if path == "" {
+			path = args.Output + ".itemizer.json"
+		}
+		args_v2.paths.itemizer = path
+		args_v2.ItemizerWriter = func() (io.WriteCloser, error) {
+			log.Printf("Writing itemizer to '%s'\n", path)
+			return openOutput(path, false, args.OutputCompression)
+		}
+	}
+	if args.ItemStatsPath != "" {
+		args_v2.ItemStatsWriter = func() (io.WriteCloser, error) {
+			log.Printf("Writing item stats to '%s'\n", args.ItemStatsPath)
+			return openOutput(args.ItemStatsPath, false, args.OutputCompression)
+		}
+	}
+	if args.OutputShards > 1 {
+		args_v2.RuleShardWriter = func(shard int) (io.WriteCloser, error) {
+			path := shardPath(args.Output, shard)
+			log.Printf("Writing rules to '%s'...", path)
+			return openOutput(path, args.AppendOutput, args.OutputCompression)
+		}
+	}
+	if args.OutputFormat == OutputFormatLong {
+		path := args.RuleItemsPath
+		if path == "" {
+			path = args.Output + ".items.csv"
+		}
+		args_v2.RuleItemsWriter = func() (io.WriteCloser, error) {
+			log.Printf("Writing rule items to '%s'\n", path)
+			return openOutput(path, args.AppendOutput, args.OutputCompression)
+		}
+	}
+	if args.ValidationInput != "" {
+		validation, err := inputFiles(args.ValidationInput)
+		if err != nil {
+			return ArgumentsV2{}, err
+		}
+		args_v2.ValidationReader = func() (io.ReadCloser, error) {
+			return openInputs(validation)
+		}
+	}
+	if args.NegativeCorrelationPath != "" {
+		args_v2.NegativeCorrelationWriter = func() (io.WriteCloser, error) {
+			log.Printf("Writing negative correlations to '%s'\n", args.NegativeCorrelationPath)
+			return openOutput(args.NegativeCorrelationPath, args.AppendOutput, args.OutputCompression)
+		}
+	}
+	return args_v2, nil
 }
 
 func MineAssociationRulesV2(args ArgumentsV2, log Logger) error {
 	log.Println("Association Rule Mining - in Go via FPGrowth")
+	begin := time.Now()
 
 	if err := args.Validate(); err != nil {
 		return err
 	}
-
-	log.Println("First pass, counting Item frequencies...")
-	start := time.Now()
-	itemizer, frequency, numTransactions, err := countItems(args.ItemsReader)
+	args.convertMinSupportPPM()
+	args.pivot()
+	args.Stats = statsFor(args.Stats)
+	result, err := Mine(args, log)
 	if err != nil {
 		return err
 	}
-	log.Printf("First pass finished in %s", time.Since(start))
+	args.convertMinSupportCount(result.NumTransactions)
+	itemizer := result.outputItemizer(args)
 
-	log.Println("Generating frequent itemsets via fpGrowth")
-	start = time.Now()
+	if args.ItemStatsWriter != nil {
+		if err := writeItemStats(result.frequency, args, itemizer, result.NumTransactions); err != nil {
+			return writeError("item stats", args.paths.itemStats, err)
+		}
+	}
+	if args.DryRun {
+		if args.PrintSummary {
+			logSummary(args.Stats, time.Since(begin), log)
+		}
+		return nil
+	}
 
-	itemsWithCount, err := generateFrequentItemsets(args.ItemsReader, args.MinSupport, itemizer, frequency, numTransactions)
-	if err != nil {
-		return err
+	if args.ItemizerWriter != nil {
+		if err := writeItemizer(result.Itemizer, args); err != nil {
+			return writeError("itemizer", args.paths.itemizer, err)
+		}
 	}
-	log.Printf("fpGrowth generated %d frequent patterns in %s",
-		len(itemsWithCount), time.Since(start))
 
-	if args.ItemsetsWriter != nil {
+	if args.ItemsetsWriter != nil && !args.StreamItemsets {
 		start := time.Now()
-		writeItemsets(itemsWithCount, args.ItemsetsWriter, itemizer, numTransactions)
-		log.Printf("Wrote %d itemsets in %s", len(itemsWithCount), time.Since(start))
+		if err := writeItemsets(result.itemsets, args, itemizer, result.NumTransactions); err != nil {
+			return writeError("itemsets", args.paths.itemsets, err)
+		}
+		log.Printf("Wrote %d itemsets in %s", len(result.itemsets), time.Since(start))
+	}
+	if args.SingleItemOnly {
+		if args.PrintSummary {
+			logSummary(args.Stats, time.Since(begin), log)
+		}
+		return nil
 	}
 
-	log.Println("Generating association rules...")
-	start = time.Now()
-	rules := generateRules(itemsWithCount, numTransactions, args.MinConfidence, args.MinLift, log)
-	numRules := countRules(rules)
-	log.Printf("Generated %d association rules in %s", numRules, time.Since(start))
+	if args.NegativeCorrelationWriter != nil {
+		start := time.Now()
+		minCount := minCountFor(args.MinSupport, result.NumTransactions)
+		pairs, err := countPairs(args, result.Itemizer, result.frequency, minCount)
+		if err != nil {
+			return err
+		}
+		maxLift := args.MaxNegativeLift
+		if maxLift == 0 {
+			maxLift = defaultMaxNegativeLift
+		}
+		negative := findNegativePairs(result.frequency, pairs, result.NumTransactions, minCount, maxLift)
+		if err := writeNegativePairs(negative, args, itemizer, result.NumTransactions); err != nil {
+			return writeError("negative correlations", args.paths.negativeCorrelations, err)
+		}
+		log.Printf("Wrote %d negatively correlated pairs in %s", len(negative), time.Since(start))
+	}
 
-	start = time.Now()
-	writeRules(rules, args.RulesWriter, itemizer)
-	log.Printf("Wrote %d rules in %s", numRules, time.Since(start))
+	start := time.Now()
+	if err := writeRules([][]Rule{result.Rules}, args, itemizer, result.NumTransactions); err != nil {
+		return writeError("rules", args.paths.rules, err)
+	}
+	if args.Stats.TruncatedOutput {
+		log.Printf("Stopped writing rules at MaxOutputBytes %d", args.MaxOutputBytes)
+	}
+	log.Printf("Wrote %d rules in %s", len(result.Rules), time.Since(start))
 
+	if args.PrintSummary {
+		logSummary(args.Stats, time.Since(begin), log)
+	}
 	return nil
 }
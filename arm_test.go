@@ -0,0 +1,1568 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm_test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nokia/arm-go"
+)
+
+const groceries = "milk,bread\nmilk,bread,eggs\nbread,eggs\nmilk,eggs\nmilk,bread\n"
+
+func writeFile(t *testing.T, dir, name, data string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMineAssociationRulesUnopenableOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := writeFile(t, dir, "groceries.csv", groceries)
+	// Nothing can be created under a regular file, even by root.
+	unopenable := filepath.Join(input, "output")
+	tests := []struct {
+		name string
+		set  func(*arm.Arguments)
+		what string
+	}{
+		{"rules", func(args *arm.Arguments) { args.Output = unopenable }, "rules"},
+		{"itemsets", func(args *arm.Arguments) { args.ItemsetsPath = unopenable }, "itemsets"},
+		{"stream-itemsets", func(args *arm.Arguments) {
+			args.ItemsetsPath = unopenable
+			args.StreamItemsets = true
+		}, "itemsets"},
+		{"itemizer", func(args *arm.Arguments) { args.ItemizerPath = unopenable }, "itemizer"},
+		{"item-stats", func(args *arm.Arguments) { args.ItemStatsPath = unopenable }, "item stats"},
+		{"negative-correlations", func(args *arm.Arguments) { args.NegativeCorrelationPath = unopenable }, "negative correlations"},
+	}
+	for _, tt := range tests {
+		args := arm.Arguments{
+			Input:         input,
+			Output:        filepath.Join(dir, "rules.csv"),
+			MinSupport:    0.2,
+			MinConfidence: 0.2,
+		}
+		tt.set(&args)
+		err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0))
+		var perr *os.PathError
+		if !errors.As(err, &perr) {
+			t.Errorf("%s: expected a *os.PathError, got %v", tt.name, err)
+			continue
+		}
+		prefix := fmt.Sprintf("writing %s to %q: ", tt.what, unopenable)
+		if !strings.HasPrefix(err.Error(), prefix) {
+			t.Errorf("%s: expected an error starting with %s, got %v", tt.name, prefix, err)
+		}
+	}
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write(p []byte) (int, error) { return 0, w.err }
+func (w failingWriter) Close() error                { return nil }
+
+func discardWriter() (io.WriteCloser, error) {
+	return nopWriteCloser{io.Discard}, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestMineAssociationRulesV2WriteError(t *testing.T) {
+	errDiskFull := errors.New("disk full")
+	args := arm.ArgumentsV2{
+		ItemsReader: readerOf(groceries),
+		RulesWriter: func() (io.WriteCloser, error) {
+			return failingWriter{errDiskFull}, nil
+		},
+		MinSupport:    0.2,
+		MinConfidence: 0.2,
+	}
+	err := arm.MineAssociationRulesV2(args, log.New(io.Discard, "", 0))
+	if !errors.Is(err, errDiskFull) {
+		t.Errorf("expected write error, got %v", err)
+	}
+}
+
+func TestMineAssociationRulesAppendOutput(t *testing.T) {
+	dir := t.TempDir()
+	args := arm.Arguments{
+		Input:         writeFile(t, dir, "groceries.csv", groceries),
+		Output:        filepath.Join(dir, "rules"),
+		MinSupport:    0.2,
+		MinConfidence: 0.2,
+		AppendOutput:  true,
+	}
+	var sizes []int
+	for run := 0; run < 2; run++ {
+		if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(args.Output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(data), "Antecedent"); n != 1 {
+			t.Errorf("expected a single header, got %d", n)
+		}
+		sizes = append(sizes, strings.Count(string(data), "\n"))
+	}
+	if sizes[1] != 2*sizes[0]-1 {
+		t.Errorf("expected second run to append its rules, got %d then %d lines", sizes[0], sizes[1])
+	}
+}
+
+func TestMineItemStats(t *testing.T) {
+	var stats arm.Stats
+	args := arm.ArgumentsV2{
+		ItemsReader: readerOf(groceries + "jam\n"),
+		MinSupport:  0.5,
+		Stats:       &stats,
+	}
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	// jam appears once, and eggs in 3 of 6 transactions.
+	if stats.NumDistinctItems != 4 || stats.NumFrequentItems != 3 {
+		t.Errorf("expected 4 distinct and 3 frequent items, got %d and %d", stats.NumDistinctItems, stats.NumFrequentItems)
+	}
+}
+
+func TestMineAssociationRulesV2MinSupportTooHigh(t *testing.T) {
+	var logged strings.Builder
+	var stats arm.Stats
+	args := arm.ArgumentsV2{
+		ItemsReader: readerOf(groceries),
+		RulesWriter: discardWriter,
+		MinSupport:  0.9,
+		Stats:       &stats,
+	}
+	if err := arm.MineAssociationRulesV2(args, log.New(&logged, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if stats.NumItemsets != 0 || stats.NumTransactions != 5 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.MaxSingleItemSupport != 0.8 {
+		t.Errorf("expected MaxSingleItemSupport 0.8, got %f", stats.MaxSingleItemSupport)
+	}
+	if !strings.Contains(logged.String(), "Warning: no itemsets") {
+		t.Error("expected a warning about MinSupport")
+	}
+}
+
+func TestMineAssociationRulesInputGlob(t *testing.T) {
+	dir := t.TempDir()
+	whole := arm.Arguments{
+		Input:         writeFile(t, dir, "groceries.csv", groceries),
+		Output:        filepath.Join(dir, "whole"),
+		MinSupport:    0.2,
+		MinConfidence: 0.2,
+	}
+	// The first shard has no trailing newline.
+	writeFile(t, dir, "shard-1.csv", "milk,bread\nmilk,bread,eggs")
+	writeFile(t, dir, "shard-2.csv", "bread,eggs\nmilk,eggs\nmilk,bread\n")
+	sharded := whole
+	sharded.Input = filepath.Join(dir, "shard-*.csv")
+	sharded.Output = filepath.Join(dir, "sharded")
+	for _, args := range []arm.Arguments{whole, sharded} {
+		if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(whole.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := os.ReadFile(sharded.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != string(expected) {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	sharded.Input = filepath.Join(dir, "missing-*.csv")
+	err = arm.MineAssociationRules(sharded, log.New(io.Discard, "", 0))
+	if !errors.Is(err, arm.ErrInputMatchesNoFiles) {
+		t.Errorf("expected ErrInputMatchesNoFiles, got %v", err)
+	}
+}
+
+func TestMineAssociationRulesEmitMetadataHeader(t *testing.T) {
+	dir := t.TempDir()
+	args := arm.Arguments{
+		Input:              writeFile(t, dir, "groceries.csv", groceries),
+		Output:             filepath.Join(dir, "rules"),
+		ItemsetsPath:       filepath.Join(dir, "itemsets"),
+		MinSupport:         0.2,
+		MinConfidence:      0.2,
+		EmitMetadataHeader: true,
+	}
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{args.Output, args.ItemsetsPath} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(string(data), "\n")
+		prefix := "# MinSupport=0.200000 MinConfidence=0.200000 MinLift=0.000000 NumTransactions=5 Time="
+		if !strings.HasPrefix(lines[0], prefix) {
+			t.Errorf("%s: expected metadata line, got %q", path, lines[0])
+		}
+		if strings.HasPrefix(lines[1], "#") {
+			t.Errorf("%s: expected a single metadata line", path)
+		}
+	}
+}
+
+func TestMineMinSupportPPM(t *testing.T) {
+	var rules [2]int
+	for i, args := range []arm.ArgumentsV2{
+		{ItemsReader: readerOf(groceries), MinSupport: 0.4},
+		{ItemsReader: readerOf(groceries), MinSupportPPM: 400000},
+	} {
+		args.MinConfidence = 0.2
+		result, err := arm.Mine(args, log.New(io.Discard, "", 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules[i] = len(result.Rules)
+	}
+	if rules[0] == 0 || rules[0] != rules[1] {
+		t.Errorf("expected the same rules with MinSupportPPM, got %d and %d", rules[0], rules[1])
+	}
+}
+
+func TestMineMinSupportCount(t *testing.T) {
+	var rules [2]int
+	for i, args := range []arm.ArgumentsV2{
+		{ItemsReader: readerOf(groceries), MinSupport: 0.4},
+		{ItemsReader: readerOf(groceries), MinSupportCount: 2},
+	} {
+		args.MinConfidence = 0.2
+		result, err := arm.Mine(args, log.New(io.Discard, "", 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules[i] = len(result.Rules)
+	}
+	if rules[0] == 0 || rules[0] != rules[1] {
+		t.Errorf("expected the same rules with MinSupportCount, got %d and %d", rules[0], rules[1])
+	}
+
+	// Stats of the first pass use the converted threshold too.
+	var stats [2]arm.Stats
+	for i, args := range []arm.ArgumentsV2{
+		{ItemsReader: readerOf("a,b\na,b\na,c\nd\n"), MinSupport: 0.5},
+		{ItemsReader: readerOf("a,b\na,b\na,c\nd\n"), MinSupportCount: 2},
+	} {
+		args.DryRun = true
+		args.Stats = &stats[i]
+		if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats[0].NumFrequentItems != 2 || stats[1].NumFrequentItems != 2 {
+		t.Errorf("expected 2 frequent items with MinSupport and MinSupportCount, got %d and %d",
+			stats[0].NumFrequentItems, stats[1].NumFrequentItems)
+	}
+}
+
+func TestMineItemsetsWithoutRules(t *testing.T) {
+	var stats arm.Stats
+	args := arm.ArgumentsV2{
+		ItemsReader:                readerOf(groceries),
+		MinSupport:                 0.2,
+		MinConfidence:              0.7,
+		Stats:                      &stats,
+		RecordItemsetsWithoutRules: true,
+	}
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	// Only {milk, bread} has a rule with confidence 0.75.
+	if stats.NumItemsetsWithoutRules != 3 {
+		t.Errorf("expected 3 itemsets without rules, got %d", stats.NumItemsetsWithoutRules)
+	}
+	if len(stats.ItemsetsWithoutRules) != 3 {
+		t.Fatalf("expected 3 itemsets listed, got %v", stats.ItemsetsWithoutRules)
+	}
+	for _, itemset := range stats.ItemsetsWithoutRules {
+		if len(itemset) == 2 && itemset[0] != "eggs" && itemset[1] != "eggs" {
+			t.Errorf("expected every pair without rules to hold eggs, got %v", itemset)
+		}
+	}
+}
+
+func TestMineRulesChan(t *testing.T) {
+	args := arm.ArgumentsV2{
+		ItemsReader:   readerOf(groceries),
+		MinSupport:    0.2,
+		MinConfidence: 0.2,
+	}
+	result, err := arm.Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, errs := arm.MineRulesChan(args)
+	n := 0
+	for range rules {
+		n++
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if n != len(result.Rules) {
+		t.Errorf("expected %d rules, got %d", len(result.Rules), n)
+	}
+
+	args.MinSupport = 2
+	rules, errs = arm.MineRulesChan(args)
+	for range rules {
+		t.Error("expected no rules with invalid arguments")
+	}
+	if err := <-errs; err != arm.ErrMinSupportOutOfRange {
+		t.Errorf("expected ErrMinSupportOutOfRange, got %v", err)
+	}
+}
+
+func TestMineSweep(t *testing.T) {
+	args := arm.Arguments{
+		Input:         writeFile(t, t.TempDir(), "groceries.csv", groceries),
+		MinConfidence: 0.2,
+		SortOutput:    true,
+	}
+	supports := []float64{0.6, 0.2, 0.4}
+	results, err := arm.MineSweep(args, supports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, support := range supports {
+		expected, err := arm.Mine(arm.ArgumentsV2{
+			ItemsReader:   readerOf(groceries),
+			MinSupport:    support,
+			MinConfidence: 0.2,
+			SortOutput:    true,
+		}, log.New(io.Discard, "", 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(results[support].Rules, expected.Rules) {
+			t.Errorf("support %f: expected %v, got %v", support, expected.Rules, results[support].Rules)
+		}
+	}
+	if _, err := arm.MineSweep(args, []float64{0.2, 1.5}); err != arm.ErrMinSupportOutOfRange {
+		t.Errorf("expected ErrMinSupportOutOfRange, got %v", err)
+	}
+	for _, threshold := range []arm.Arguments{{MinSupportPPM: 500}, {MinSupportCount: 2}} {
+		threshold.Input = args.Input
+		if _, err := arm.MineSweep(threshold, supports); err != arm.ErrSweepSupportConflict {
+			t.Errorf("expected ErrSweepSupportConflict, got %v", err)
+		}
+	}
+}
+
+func TestMineSweepMaxItemsets(t *testing.T) {
+	args := arm.Arguments{
+		Input:         writeFile(t, t.TempDir(), "groceries.csv", groceries),
+		MinConfidence: 0.2,
+		SortOutput:    true,
+		MaxItemsets:   5,
+	}
+	supports := []float64{0.2, 0.4}
+	results, err := arm.MineSweep(args, supports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, support := range supports {
+		var itemsets strings.Builder
+		if err := results[support].WriteItemsets(&itemsets, arm.ArgumentsV2{}); err != nil {
+			t.Fatal(err)
+		}
+		expected, err := arm.Mine(arm.ArgumentsV2{
+			ItemsReader:   readerOf(groceries),
+			MinSupport:    support,
+			MinConfidence: 0.2,
+			SortOutput:    true,
+			MaxItemsets:   5,
+		}, log.New(io.Discard, "", 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expectedItemsets strings.Builder
+		if err := expected.WriteItemsets(&expectedItemsets, arm.ArgumentsV2{}); err != nil {
+			t.Fatal(err)
+		}
+		if itemsets.String() != expectedItemsets.String() || !reflect.DeepEqual(results[support].Rules, expected.Rules) {
+			t.Errorf("support %f: expected itemsets %q and rules %v, got %q and %v",
+				support, expectedItemsets.String(), expected.Rules, itemsets.String(), results[support].Rules)
+		}
+	}
+}
+
+func TestMineSweepPivot(t *testing.T) {
+	data := "user=1 item=milk\nuser=1 item=bread\nuser=2 item=milk\nuser=2 item=eggs\n" +
+		"user=3 item=milk\nuser=3 item=bread\nuser=4 item=bread\n"
+	args := arm.Arguments{
+		Input:         writeFile(t, t.TempDir(), "events.log", data),
+		MinConfidence: 0.2,
+		SortOutput:    true,
+		PivotKey:      "item",
+		PivotGroup:    "user",
+	}
+	results, err := arm.MineSweep(args, []float64{0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := arm.Mine(arm.ArgumentsV2{
+		ItemsReader:   readerOf(data),
+		MinSupport:    0.5,
+		MinConfidence: 0.2,
+		SortOutput:    true,
+		PivotKey:      "item",
+		PivotGroup:    "user",
+	}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0.5].NumTransactions != 4 || len(expected.Rules) == 0 {
+		t.Fatalf("expected 4 pivoted transactions with rules, got %d transactions and %v",
+			results[0.5].NumTransactions, expected.Rules)
+	}
+	if !reflect.DeepEqual(results[0.5].Rules, expected.Rules) {
+		t.Errorf("expected %v, got %v", expected.Rules, results[0.5].Rules)
+	}
+}
+
+func TestMineAssociationRulesNegativeCorrelation(t *testing.T) {
+	dir := t.TempDir()
+	args := arm.Arguments{
+		Input:                   writeFile(t, dir, "drinks.csv", "tea\ncoffee\ntea\ncoffee\ntea,coffee,milk\nmilk\n"),
+		Output:                  filepath.Join(dir, "rules"),
+		NegativeCorrelationPath: filepath.Join(dir, "negative"),
+		MinSupport:              0.3,
+		MaxNegativeLift:         0.7,
+	}
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(args.NegativeCorrelationPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Pair,Lift,Support\ntea coffee,0.666667,0.166667\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+}
+
+func TestMineContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	args := arm.ArgumentsV2{
+		ItemsReader: readerOf(groceries),
+		MinSupport:  0.2,
+	}
+	result, err := arm.MineContext(ctx, args, log.New(io.Discard, "", 0))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if result != nil && len(result.Rules) != 0 {
+		t.Errorf("expected no rules, got %d", len(result.Rules))
+	}
+
+	result, err = arm.Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.NumTransactions != 5 || len(result.Rules) == 0 {
+		t.Errorf("expected rules from 5 transactions, got %d from %d", len(result.Rules), result.NumTransactions)
+	}
+}
+
+func TestMineMaxItemsPerTransaction(t *testing.T) {
+	input := groceries + "milk,bread,eggs,jam,tea,coffee\n"
+	for _, skip := range []bool{false, true} {
+		var stats arm.Stats
+		args := arm.ArgumentsV2{
+			ItemsReader:            readerOf(input),
+			MinSupport:             0.1,
+			MaxItemsPerTransaction: 3,
+			SkipLongTransactions:   skip,
+			Stats:                  &stats,
+		}
+		result, err := arm.Mine(args, log.New(io.Discard, "", 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.NumLongTransactions != 1 {
+			t.Errorf("skip %v: expected 1 long transaction, got %d", skip, stats.NumLongTransactions)
+		}
+		numTransactions := 6
+		if skip {
+			numTransactions = 5
+		}
+		if result.NumTransactions != numTransactions {
+			t.Errorf("skip %v: expected %d transactions, got %d", skip, numTransactions, result.NumTransactions)
+		}
+		for _, rule := range result.Rules {
+			for _, item := range append(rule.Antecedent, rule.Consequent...) {
+				if s := result.Itemizer.Itemize([]string{"jam"}); len(s) == 1 && s[0] == item {
+					t.Errorf("skip %v: expected jam to be truncated or skipped", skip)
+				}
+			}
+		}
+	}
+}
+
+func TestMineAssociationRulesGzipOutput(t *testing.T) {
+	dir := t.TempDir()
+	args := arm.Arguments{
+		Input:         writeFile(t, dir, "groceries.csv", groceries),
+		Output:        filepath.Join(dir, "rules.csv.gz"),
+		ItemsetsPath:  filepath.Join(dir, "itemsets.csv"),
+		MinSupport:    0.2,
+		MinConfidence: 0.2,
+	}
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(args.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(rules), "Antecedent => Consequent") {
+		t.Errorf("expected decompressed rules, got %q", rules)
+	}
+	itemsets, err := os.ReadFile(args.ItemsetsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(itemsets), "Itemset,Support") {
+		t.Errorf("expected uncompressed itemsets, got %q", itemsets)
+	}
+}
+
+func TestMineAssociationRulesJSONOutput(t *testing.T) {
+	dir := t.TempDir()
+	for _, encoding := range []string{arm.MetricOverflowJSONString, arm.MetricOverflowJSONNull} {
+		args := arm.Arguments{
+			Input:              writeFile(t, dir, "always.csv", "milk,bread\nmilk,bread\nmilk\n"),
+			Output:             filepath.Join(dir, "rules.jsonl"),
+			OutputFormat:       arm.OutputFormatJSON,
+			MetricOverflowJSON: encoding,
+			MinSupport:         0.5,
+			MinConfidence:      0.5,
+		}
+		if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(args.Output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		convictions := make(map[string]interface{})
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			var rule struct {
+				Antecedent []string
+				Conviction interface{}
+			}
+			if err := json.Unmarshal([]byte(line), &rule); err != nil {
+				t.Fatalf("%s: expected JSON lines, got %q: %v", encoding, line, err)
+			}
+			convictions[strings.Join(rule.Antecedent, " ")] = rule.Conviction
+		}
+		expected := map[string]interface{}{"bread": "Infinity", "milk": 1.0}
+		if encoding == arm.MetricOverflowJSONNull {
+			expected["bread"] = nil
+		}
+		if !reflect.DeepEqual(convictions, expected) {
+			t.Errorf("%s: expected convictions %v, got %v", encoding, expected, convictions)
+		}
+	}
+}
+
+func TestMineAssociationRulesMaxOutputBytes(t *testing.T) {
+	dir := t.TempDir()
+	stats := &arm.Stats{}
+	args := arm.Arguments{
+		Input:          writeFile(t, dir, "groceries.csv", groceries),
+		Output:         filepath.Join(dir, "rules.csv"),
+		MinSupport:     0.2,
+		MinConfidence:  0.2,
+		SortOutput:     true,
+		MaxOutputBytes: 100,
+		Stats:          stats,
+	}
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := os.ReadFile(args.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) > 100 || !strings.HasSuffix(string(rules), "\n") {
+		t.Errorf("expected at most 100 bytes of whole lines, got %q", rules)
+	}
+	if !stats.TruncatedOutput {
+		t.Error("expected TruncatedOutput to be set")
+	}
+}
+
+func TestMineAssociationRulesStreamItemsets(t *testing.T) {
+	dir := t.TempDir()
+	args := arm.Arguments{
+		Input:         writeFile(t, dir, "groceries.csv", groceries),
+		Output:        filepath.Join(dir, "rules.csv"),
+		ItemsetsPath:  filepath.Join(dir, "itemsets.csv"),
+		MinSupport:    0.2,
+		MinConfidence: 0.2,
+	}
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile(args.ItemsetsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args.StreamItemsets = true
+	args.ItemsetsPath = filepath.Join(dir, "streamed.csv")
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := os.ReadFile(args.ItemsetsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(streamed) != string(expected) {
+		t.Errorf("expected streamed itemsets %q, got %q", expected, streamed)
+	}
+	rules, err := os.ReadFile(args.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(rules), "\n") != 1 {
+		t.Errorf("expected only a header in the rules output, got %q", rules)
+	}
+
+	args.ItemsetsPath = ""
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); !errors.Is(err, arm.ErrStreamItemsetsWithoutOutput) {
+		t.Errorf("expected ErrStreamItemsetsWithoutOutput, got %v", err)
+	}
+}
+
+func TestMineAssociationRulesItemStats(t *testing.T) {
+	dir := t.TempDir()
+	args := arm.Arguments{
+		Input:         writeFile(t, dir, "groceries.csv", groceries+"jam\n"),
+		Output:        filepath.Join(dir, "rules.csv"),
+		ItemStatsPath: filepath.Join(dir, "items.csv"),
+		MinSupport:    0.2,
+		MinConfidence: 0.2,
+		DryRun:        true,
+	}
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	items, err := os.ReadFile(args.ItemStatsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Item,Count,Support,Rank\n" +
+		"bread,4,0.666667,1\nmilk,4,0.666667,1\neggs,3,0.500000,3\njam,1,0.166667,4\n"
+	if string(items) != expected {
+		t.Errorf("expected item stats %q, got %q", expected, items)
+	}
+}
+
+func TestMineAssociationRulesClosedMaximal(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name     string
+		closed   bool
+		maximal  bool
+		expected []string
+	}{
+		{"closed", true, false, []string{"bread eggs milk 0.333333", "bread milk 1.000000"}},
+		{"maximal", false, true, []string{"bread eggs milk 0.333333"}},
+	} {
+		args := arm.Arguments{
+			Input:         writeFile(t, dir, "input.csv", "milk,bread\nmilk,bread,eggs\nmilk,bread\n"),
+			Output:        filepath.Join(dir, "rules.csv"),
+			ItemsetsPath:  filepath.Join(dir, "itemsets.csv"),
+			MinSupport:    0.2,
+			MinConfidence: 0.2,
+			Closed:        tt.closed,
+			Maximal:       tt.maximal,
+		}
+		if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+			t.Fatal(err)
+		}
+		itemsets, err := os.ReadFile(args.ItemsetsPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(itemsets)), "\n")[1:]
+		for i, line := range lines {
+			items := strings.Fields(line)
+			sort.Strings(items[:len(items)-1])
+			lines[i] = strings.Join(items, " ")
+		}
+		sort.Strings(lines)
+		if !reflect.DeepEqual(lines, tt.expected) {
+			t.Errorf("%s: expected itemsets %q, got %q", tt.name, tt.expected, lines)
+		}
+		// Rules still come from every frequent itemset, such as eggs => milk.
+		rules, err := os.ReadFile(args.Output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(rules), "eggs => milk,") {
+			t.Errorf("%s: expected the rule eggs => milk, got %q", tt.name, rules)
+		}
+	}
+}
+
+func TestMineAssociationRulesSingleItemOnly(t *testing.T) {
+	dir := t.TempDir()
+	args := arm.Arguments{
+		Input:          writeFile(t, dir, "groceries.csv", groceries+"jam\n"),
+		Output:         filepath.Join(dir, "rules.csv"),
+		ItemsetsPath:   filepath.Join(dir, "itemsets.csv"),
+		MinSupport:     0.2,
+		MinConfidence:  0.2,
+		SingleItemOnly: true,
+	}
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	itemsets, err := os.ReadFile(args.ItemsetsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Itemset,Support\nmilk 0.666667\nbread 0.666667\neggs 0.500000\n"
+	if string(itemsets) != expected {
+		t.Errorf("expected itemsets %q, got %q", expected, itemsets)
+	}
+	if _, err := os.Stat(args.Output); !os.IsNotExist(err) {
+		t.Errorf("expected no rules to be written, got %v", err)
+	}
+
+	args.StreamItemsets = true
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); !errors.Is(err, arm.ErrSingleItemOnlyConflict) {
+		t.Errorf("expected ErrSingleItemOnlyConflict, got %v", err)
+	}
+}
+
+func TestMineTopFrequentItems(t *testing.T) {
+	args := arm.ArgumentsV2{
+		ItemsReader:      readerOf(groceries + "jam\n"),
+		MinSupport:       0.1,
+		MinConfidence:    0.1,
+		TopFrequentItems: 2,
+	}
+	var logged strings.Builder
+	result, err := arm.Mine(args, log.New(&logged, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var itemsets strings.Builder
+	if err := result.WriteItemsets(&itemsets, arm.ArgumentsV2{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(itemsets.String(), "eggs") || strings.Contains(itemsets.String(), "jam") {
+		t.Errorf("expected only milk and bread in itemsets, got %q", itemsets.String())
+	}
+	if want := "Kept the 2 most frequent of 4 items, dropping 4 of 12 item occurrences (33.33%)"; !strings.Contains(logged.String(), want) {
+		t.Errorf("expected %q in the log, got\n%s", want, logged.String())
+	}
+}
+
+func TestMineTransactionFilter(t *testing.T) {
+	args := arm.ArgumentsV2{
+		ItemsReader:   readerOf("mon,milk,bread\nsat,milk,eggs\ntue,milk,bread\nsun,eggs\n"),
+		MinSupport:    0.1,
+		MinConfidence: 0.1,
+		IDColumn:      1,
+		TransactionFilter: func(fields []string) bool {
+			return fields[0] != "sat" && fields[0] != "sun"
+		},
+	}
+	result, err := arm.Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.NumTransactions != 2 {
+		t.Errorf("expected 2 transactions, got %d", result.NumTransactions)
+	}
+	var itemsets strings.Builder
+	if err := result.WriteItemsets(&itemsets, arm.ArgumentsV2{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Itemset,Support\nmilk 1.000000\nmilk bread 1.000000\nbread 1.000000\n"
+	if itemsets.String() != expected {
+		t.Errorf("expected itemsets %q, got %q", expected, itemsets.String())
+	}
+}
+
+func TestMineRejectionStats(t *testing.T) {
+	var stats arm.Stats
+	args := arm.ArgumentsV2{
+		ItemsReader:   readerOf(groceries),
+		MinSupport:    0.2,
+		MinConfidence: 0.6,
+		MinLift:       1,
+		Stats:         &stats,
+	}
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if stats.RejectedByConfidence != 5 || stats.RejectedByLift != 4 || stats.NumRules != 0 {
+		t.Errorf("expected 5 rules rejected by confidence, 4 by lift and none kept, got %d, %d and %d",
+			stats.RejectedByConfidence, stats.RejectedByLift, stats.NumRules)
+	}
+
+	args.MinLift = 0
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if stats.RejectedByConfidence != 5 || stats.RejectedByLift != 0 || stats.NumRules != 4 {
+		t.Errorf("expected 5 rules rejected by confidence, none by lift and 4 kept, got %d, %d and %d",
+			stats.RejectedByConfidence, stats.RejectedByLift, stats.NumRules)
+	}
+}
+
+func TestMineItemsetRejectionStats(t *testing.T) {
+	// {milk, bread} appears 3 times, the other pairs and the triple fewer.
+	var stats arm.Stats
+	args := arm.ArgumentsV2{
+		ItemsReader:         readerOf(groceries),
+		MinSupport:          0.2,
+		MinRuleSupportCount: 3,
+		Stats:               &stats,
+	}
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if stats.ItemsetsRejectedByRuleSupportCount != 3 || stats.NumItemsetsWithoutRules != 0 || stats.NumRules != 2 {
+		t.Errorf("expected 3 itemsets rejected by MinRuleSupportCount and 2 rules, got %d, %d without rules and %d rules",
+			stats.ItemsetsRejectedByRuleSupportCount, stats.NumItemsetsWithoutRules, stats.NumRules)
+	}
+
+	// {milk, bread} is only mined as a subset of {milk, bread, eggs}.
+	args.MinRuleSupportCount = 0
+	args.FocusItem = "eggs"
+	args.ItemsReader = readerOf(groceries)
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if stats.ItemsetsRejectedByFocus != 1 || stats.ItemsetsRejectedByRuleSupportCount != 0 {
+		t.Errorf("expected 1 itemset rejected for lacking the FocusItem, got %d", stats.ItemsetsRejectedByFocus)
+	}
+
+	// Only the pairs holding a label yield rules.
+	args = arm.ArgumentsV2{
+		ItemsReader:    readerOf("yes,milk,bread\nno,milk\nyes,bread,milk\nno,eggs\nyes,bread\n"),
+		MinSupport:     0.2,
+		ClassRulesOnly: true,
+		Stats:          &stats,
+	}
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if stats.ItemsetsRejectedByLabels != 1 || stats.RejectedByLabels == 0 {
+		t.Errorf("expected {bread, milk} rejected for holding no label, and some rules which are not class rules, got %d and %d",
+			stats.ItemsetsRejectedByLabels, stats.RejectedByLabels)
+	}
+}
+
+func TestMineMinTransactions(t *testing.T) {
+	args := arm.ArgumentsV2{
+		ItemsReader:     readerOf(groceries),
+		MinSupport:      0.2,
+		MinConfidence:   0.2,
+		MinTransactions: 6,
+	}
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); !errors.Is(err, arm.ErrTooFewTransactions) {
+		t.Errorf("expected ErrTooFewTransactions, got %v", err)
+	}
+	args.MinTransactions = 5
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Errorf("expected 5 transactions to be enough, got %v", err)
+	}
+}
+
+func TestMineAssociationRulesV2OnProgress(t *testing.T) {
+	for _, workers := range []int{0, 2} {
+		var fractions []float64
+		args := arm.ArgumentsV2{
+			ItemsReader: readerOf(groceries),
+			RulesWriter: func() (io.WriteCloser, error) {
+				return nopWriteCloser{io.Discard}, nil
+			},
+			MinSupport: 0.2,
+			Workers:    workers,
+			OnProgress: func(phase string, fraction float64) {
+				if phase != arm.ProgressFPGrowth {
+					t.Errorf("unexpected phase %q", phase)
+				}
+				fractions = append(fractions, fraction)
+			},
+		}
+		if err := arm.MineAssociationRulesV2(args, log.New(io.Discard, "", 0)); err != nil {
+			t.Fatal(err)
+		}
+		// One call for each of milk, bread and eggs.
+		if len(fractions) != 3 || fractions[2] != 1 {
+			t.Errorf("expected progress up to 1 in 3 steps with %d workers, got %v", workers, fractions)
+		}
+	}
+}
+
+func TestMinePhaseTimeouts(t *testing.T) {
+	for _, phase := range []string{arm.PhaseCount, arm.PhaseFPGrowth, arm.PhaseRules} {
+		args := arm.ArgumentsV2{
+			ItemsReader:   readerOf(groceries),
+			MinSupport:    0.2,
+			PhaseTimeouts: map[string]time.Duration{phase: time.Nanosecond},
+		}
+		_, err := arm.Mine(args, log.New(io.Discard, "", 0))
+		if !errors.Is(err, arm.ErrPhaseTimeout) || !strings.Contains(err.Error(), phase) {
+			t.Errorf("expected ErrPhaseTimeout naming %s, got %v", phase, err)
+		}
+	}
+	args := arm.ArgumentsV2{
+		ItemsReader:   readerOf(groceries),
+		PhaseTimeouts: map[string]time.Duration{"parse": time.Second},
+	}
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != arm.ErrUnknownPhase {
+		t.Errorf("expected ErrUnknownPhase, got %v", err)
+	}
+}
+
+func TestMineAssociationRulesOutputFormatLong(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "rules.csv")
+	args := arm.Arguments{
+		Input:        writeFile(t, dir, "groceries.csv", groceries),
+		Output:       output,
+		MinSupport:   0.2,
+		OutputFormat: arm.OutputFormatLong,
+	}
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := os.ReadFile(output + ".items.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruleLines := strings.Split(strings.TrimSpace(string(rules)), "\n")
+	itemLines := strings.Split(strings.TrimSpace(string(items)), "\n")
+	if ruleLines[0] != "RuleID,Confidence,Lift,Support" || itemLines[0] != "RuleID,Side,Item" {
+		t.Fatalf("unexpected headers %q and %q", ruleLines[0], itemLines[0])
+	}
+	sides := make(map[string]int)
+	for _, line := range itemLines[1:] {
+		fields := strings.Split(line, ",")
+		sides[fields[0]+","+fields[1]]++
+	}
+	for _, line := range ruleLines[1:] {
+		id := strings.SplitN(line, ",", 2)[0]
+		if sides[id+",antecedent"] == 0 || sides[id+",consequent"] == 0 {
+			t.Errorf("expected items on both sides of rule %s", id)
+		}
+	}
+}
+
+func TestMineAssociationRulesOutputShards(t *testing.T) {
+	dir := t.TempDir()
+	args := arm.Arguments{
+		Input:        writeFile(t, dir, "groceries.csv", groceries),
+		Output:       filepath.Join(dir, "rules.csv"),
+		MinSupport:   0.2,
+		OutputShards: 3,
+	}
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	shardOf := make(map[string]int)
+	numRules := 0
+	for shard := 0; shard < 3; shard++ {
+		data, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("rules.%d.csv", shard)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n")[1:] {
+			numRules++
+			antecedent := strings.Split(line, " => ")[0]
+			if other, found := shardOf[antecedent]; found && other != shard {
+				t.Errorf("antecedent %q in shards %d and %d", antecedent, other, shard)
+			}
+			shardOf[antecedent] = shard
+		}
+	}
+
+	args.Output = filepath.Join(dir, "all.csv")
+	args.OutputShards = 0
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(args.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(strings.Split(strings.TrimSpace(string(data)), "\n")) - 1; n != numRules {
+		t.Errorf("expected the %d rules of a single file across the shards, got %d", n, numRules)
+	}
+}
+
+func TestMineAssociationRulesV2PrintSummary(t *testing.T) {
+	var logged strings.Builder
+	var stats arm.Stats
+	args := arm.ArgumentsV2{
+		ItemsReader: readerOf(groceries),
+		RulesWriter: func() (io.WriteCloser, error) {
+			return nopWriteCloser{io.Discard}, nil
+		},
+		MinSupport:   0.2,
+		PrintSummary: true,
+		Stats:        &stats,
+	}
+	if err := arm.MineAssociationRulesV2(args, log.New(&logged, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	i := strings.Index(logged.String(), "Summary:")
+	if i < 0 {
+		t.Fatalf("expected a summary, got\n%s", logged.String())
+	}
+	summary := logged.String()[i:]
+	for _, line := range []string{"Total time", "Transactions  5 in", "Itemsets      7 in", "Rules         12 in"} {
+		if !strings.Contains(summary, line) {
+			t.Errorf("expected %q in the summary, got\n%s", line, summary)
+		}
+	}
+	if stats.CountTime <= 0 || stats.FPGrowthTime <= 0 || stats.RulesTime <= 0 {
+		t.Errorf("expected the time of each phase in Stats, got %+v", stats)
+	}
+}
+
+func TestMineDryRunEstimate(t *testing.T) {
+	var stats arm.Stats
+	args := arm.ArgumentsV2{
+		ItemsReader: readerOf(groceries + "jam\n"),
+		MinSupport:  0.2,
+		DryRun:      true,
+		Stats:       &stats,
+	}
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Estimate == nil {
+		t.Fatal("expected an estimate in Stats with DryRun")
+	}
+	if stats.Estimate.NumTransactions != 6 || stats.Estimate.NumItems != 4 || stats.Estimate.NumFrequentItems != 3 {
+		t.Errorf("expected 6 transactions, 4 items and 3 frequent items, got %+v", *stats.Estimate)
+	}
+
+	stats = arm.Stats{}
+	args.ItemsReader = readerOf(groceries)
+	args.DryRun = false
+	if _, err := arm.Mine(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Estimate != nil {
+		t.Errorf("expected no estimate without DryRun, got %+v", *stats.Estimate)
+	}
+}
+
+func TestMineVocabularyWarnRatio(t *testing.T) {
+	// The first column is a transaction ID, so every transaction has an
+	// item of its own.
+	args := arm.ArgumentsV2{
+		ItemsReader:         readerOf("1,milk\n2,milk\n3,bread\n4,milk\n"),
+		MinSupport:          0.2,
+		DryRun:              true,
+		VocabularyWarnRatio: 1,
+	}
+	var logged strings.Builder
+	if _, err := arm.Mine(args, log.New(&logged, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Warning: 6 distinct items in 4 transactions", "milk (3), 1 (1)", "include: 1, 2, 3, bread, 4"} {
+		if !strings.Contains(logged.String(), want) {
+			t.Errorf("expected %q in the log, got\n%s", want, logged.String())
+		}
+	}
+
+	args.VocabularyWarnRatio = 2
+	logged.Reset()
+	if _, err := arm.Mine(args, log.New(&logged, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(logged.String(), "Warning") {
+		t.Errorf("expected no warning below VocabularyWarnRatio, got\n%s", logged.String())
+	}
+}
+
+func TestMineBytes(t *testing.T) {
+	result, err := arm.MineBytes([]byte(groceries), arm.Arguments{MinSupport: 0.2, MinConfidence: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := arm.Mine(arm.ArgumentsV2{
+		ItemsReader:   readerOf(groceries),
+		MinSupport:    0.2,
+		MinConfidence: 0.5,
+	}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.NumTransactions != 5 || !reflect.DeepEqual(result.Rules, expected.Rules) {
+		t.Errorf("expected the rules of Mine\n%v, got\n%v", expected.Rules, result.Rules)
+	}
+	if _, err := arm.MineBytes(nil, arm.Arguments{MinSupport: 2}); err != arm.ErrMinSupportOutOfRange {
+		t.Errorf("expected ErrMinSupportOutOfRange, got %v", err)
+	}
+}
+
+func TestMaximalAntecedents(t *testing.T) {
+	// {a} => {c} has confidence 1/2 but {a, b} => {c} has 2/3, so only
+	// {b} => {c}, with 3/4, and {a, b} => {c} are maximal.
+	args := arm.ArgumentsV2{
+		ItemsReader: readerOf("a,b,c\na,b,c\na,b\na\nb,c\n"),
+		MinSupport:  0.2,
+	}
+	rules, err := arm.MaximalAntecedents([]string{"c"}, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confidences := make([]float64, len(rules))
+	for i, rule := range rules {
+		confidences[i] = rule.Confidence
+	}
+	sort.Float64s(confidences)
+	if !reflect.DeepEqual(confidences, []float64{2.0 / 3.0, 0.75}) {
+		t.Errorf("expected confidences 2/3 and 3/4, got %v", rules)
+	}
+	if rules, err := arm.MaximalAntecedents([]string{"d"}, args); err != nil || len(rules) != 0 {
+		t.Errorf("expected no rules for an unknown item, got %v, %v", rules, err)
+	}
+}
+
+func TestMineValidationReader(t *testing.T) {
+	// On the validation dataset, milk and bread occur together in 1 of 4
+	// transactions, and milk and eggs never do.
+	args := arm.ArgumentsV2{
+		ItemsReader:      readerOf(groceries),
+		ValidationReader: readerOf("milk,bread\nmilk\nbread\neggs\n"),
+		MinSupport:       0.2,
+		MinConfidence:    0.5,
+	}
+	logger := log.New(io.Discard, "", 0)
+	result, err := arm.Mine(args, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules := result.Index().ByAntecedent("milk"); len(rules) != 2 {
+		t.Fatalf("expected {milk} => {bread} and {milk} => {eggs}, got %v", rules)
+	}
+
+	args.MinValidationConfidence = 0.4
+	result, err = arm.Mine(args, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := result.Index().ByAntecedent("milk")
+	if len(rules) != 1 {
+		t.Fatalf("expected only {milk} => {bread} to pass validation, got %v", rules)
+	}
+	rule := rules[0]
+	if rule.Confidence != 0.75 || rule.ValidationSupport != 0.25 || rule.ValidationConfidence != 0.5 || rule.ValidationLift != 1 {
+		t.Errorf("expected confidence 0.75 and validation measures 0.25, 0.5 and 1, got %v", rule)
+	}
+}
+
+func TestDiffRules(t *testing.T) {
+	old := []arm.Rule{
+		arm.NewRule([]arm.Item{1, 2}, []arm.Item{3}, 0.2, 0.5, 1.5),
+		arm.NewRule([]arm.Item{1}, []arm.Item{2}, 0.4, 0.8, 1.2),
+		arm.NewRule([]arm.Item{4}, []arm.Item{5}, 0.1, 0.5, 2),
+	}
+	new := []arm.Rule{
+		arm.NewRule([]arm.Item{2, 1}, []arm.Item{3}, 0.25, 0.5, 1.5),
+		arm.NewRule([]arm.Item{1}, []arm.Item{2}, 0.4, 0.8, 1.2),
+		arm.NewRule([]arm.Item{5}, []arm.Item{4}, 0.1, 0.5, 2),
+	}
+	added, removed, changed := arm.DiffRules(old, new)
+	if len(added) != 1 || added[0].Old != nil || !reflect.DeepEqual(added[0].New.Antecedent, []arm.Item{5}) {
+		t.Errorf("expected {5} => {4} to be added, got %+v", added)
+	}
+	if len(removed) != 1 || removed[0].New != nil || !reflect.DeepEqual(removed[0].Old.Antecedent, []arm.Item{4}) {
+		t.Errorf("expected {4} => {5} to be removed, got %+v", removed)
+	}
+	if len(changed) != 1 || changed[0].Old != &old[0] || changed[0].New != &new[0] {
+		t.Fatalf("expected {1, 2} => {3} to change, whatever the order of its items, got %+v", changed)
+	}
+	if d := changed[0]; math.Abs(d.SupportDelta-0.05) > 1e-9 || d.ConfidenceDelta != 0 || d.LiftDelta != 0 {
+		t.Errorf("expected only the support to change, by 0.05, got %+v", d)
+	}
+}
+
+func TestDiffRulesMinedSeparately(t *testing.T) {
+	// Each run numbers the items in the order it first sees them, so a
+	// and b swap IDs between the two.
+	mine := func(data string) []arm.Rule {
+		result, err := arm.Mine(arm.ArgumentsV2{ItemsReader: readerOf(data), MinSupport: 0.5}, log.New(io.Discard, "", 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Rules
+	}
+	old := mine("a,b\na,b\na\n")
+	new := mine("b,a\nb,a\nb\n")
+	added, removed, changed := arm.DiffRules(old, new)
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("expected the same rules in both, got %d added and %d removed", len(added), len(removed))
+	}
+	// a => b goes from 2/3 to 1, and b => a from 1 to 2/3.
+	if len(changed) != 2 {
+		t.Fatalf("expected both rules to change, got %+v", changed)
+	}
+	for _, d := range changed {
+		if d.Old.ID != d.New.ID || math.Abs(math.Abs(d.ConfidenceDelta)-1.0/3) > 1e-9 {
+			t.Errorf("expected the confidence of the same rule to change by 1/3, got %+v", d)
+		}
+	}
+}
+
+func TestMergeRuleFiles(t *testing.T) {
+	dir := t.TempDir()
+	header := "Antecedent => Consequent,Confidence,Lift,Support\n"
+	paths := []string{
+		writeFile(t, dir, "rules.0.csv", header+"milk => bread,0.750000,0.937500,0.600000\nbread => milk,0.750000,0.937500,0.600000\n"),
+		writeFile(t, dir, "rules.1.csv", header+"milk => bread,0.500000,0.625000,0.400000\n\neggs => milk,0.666667,0.833333,0.400000\n"),
+	}
+	out := filepath.Join(dir, "merged.csv")
+	if err := arm.MergeRuleFiles(paths, out, arm.Arguments{SortBy: arm.SortByConfidence}); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := header +
+		"bread => milk,0.750000,0.937500,0.600000\n" +
+		"milk => bread,0.750000,0.937500,0.600000\n" +
+		"eggs => milk,0.666667,0.833333,0.400000\n"
+	if string(merged) != expected {
+		t.Errorf("expected %q, got %q", expected, merged)
+	}
+
+	bad := writeFile(t, dir, "bad.csv", header+"milk bread\n")
+	if err := arm.MergeRuleFiles([]string{bad}, out, arm.Arguments{}); !errors.Is(err, arm.ErrMalformedRuleLine) {
+		t.Errorf("expected ErrMalformedRuleLine, got %v", err)
+	}
+}
+
+func TestMineClassRulesOnly(t *testing.T) {
+	args := arm.ArgumentsV2{
+		ItemsReader:    readerOf("yes,milk,bread\nno,milk\nyes,bread,milk\nno,eggs\nyes,bread\n"),
+		MinSupport:     0.2,
+		MinConfidence:  0.5,
+		ClassRulesOnly: true,
+	}
+	result, err := arm.Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rules) == 0 {
+		t.Fatal("expected class rules")
+	}
+	var buf strings.Builder
+	if err := result.WriteRules(&buf, arm.ArgumentsV2{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n")[1:] {
+		rule := strings.SplitN(line, ",", 2)[0]
+		sides := strings.Split(rule, " => ")
+		if sides[1] != "yes" && sides[1] != "no" {
+			t.Errorf("expected a label consequent, got %q", rule)
+		}
+		for _, item := range strings.Split(sides[0], " ") {
+			if item == "yes" || item == "no" {
+				t.Errorf("expected no label in the antecedent, got %q", rule)
+			}
+		}
+	}
+}
+
+func TestRuleIndex(t *testing.T) {
+	args := arm.ArgumentsV2{
+		ItemsReader:   readerOf(groceries),
+		MinSupport:    0.2,
+		MinConfidence: 0.2,
+	}
+	result, err := arm.Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := result.Index()
+	if rules := index.ByAntecedent("eggs", "milk"); len(rules) != 1 {
+		t.Errorf("expected 1 rule from eggs and milk, got %d", len(rules))
+	}
+	if rules := index.ByAntecedent("milk"); len(rules) != 3 {
+		t.Errorf("expected 3 rules from milk, got %d", len(rules))
+	}
+	if rules := index.ByAntecedent("milk", "jam"); rules != nil {
+		t.Errorf("expected no rules with unknown item, got %d", len(rules))
+	}
+	// Two rules each of {milk, bread} and {milk, eggs}, and six of
+	// {milk, bread, eggs}.
+	if rules := index.ContainingItem("milk"); len(rules) != 10 {
+		t.Errorf("expected 10 rules containing milk, got %d", len(rules))
+	}
+}
+
+func TestRuleIndexConcurrent(t *testing.T) {
+	args := arm.ArgumentsV2{
+		ItemsReader:      readerOf(groceries),
+		MinSupport:       0.2,
+		MinConfidence:    0.2,
+		NormalizeUnicode: true,
+	}
+	result, err := arm.Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := result.Index()
+	// Run with -race: lookups fold their items, which must not share
+	// state between goroutines.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if rules := index.ByAntecedent("MILK"); len(rules) != 3 {
+					t.Errorf("expected 3 rules from MILK, got %d", len(rules))
+					return
+				}
+				if rules := index.ContainingItem("Mïlk"); len(rules) != 10 {
+					t.Errorf("expected 10 rules containing Mïlk, got %d", len(rules))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestResultWriteRulesAndItemsets(t *testing.T) {
+	args := arm.ArgumentsV2{
+		ItemsReader:   readerOf(groceries),
+		MinSupport:    0.5,
+		MinConfidence: 0.2,
+	}
+	result, err := arm.Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var csv, sql, itemsets strings.Builder
+	if err := result.WriteRules(&csv, arm.ArgumentsV2{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := result.WriteRules(&sql, arm.ArgumentsV2{OutputFormat: arm.OutputFormatSQL}); err != nil {
+		t.Fatal(err)
+	}
+	if err := result.WriteItemsets(&itemsets, arm.ArgumentsV2{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(csv.String(), "Antecedent => Consequent") || strings.Count(csv.String(), "\n") != 1+len(result.Rules) {
+		t.Errorf("expected CSV header and %d rules, got %q", len(result.Rules), csv.String())
+	}
+	if !strings.Contains(sql.String(), "INSERT INTO") {
+		t.Errorf("expected SQL inserts, got %q", sql.String())
+	}
+	if !strings.HasPrefix(itemsets.String(), "Itemset,Support\n") {
+		t.Errorf("expected itemsets header, got %q", itemsets.String())
+	}
+}
+
+func TestSupportOf(t *testing.T) {
+	args := arm.ArgumentsV2{
+		ItemsReader:   readerOf(groceries),
+		MinSupport:    0.5,
+		MinConfidence: 0.2,
+	}
+	result, err := arm.Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		items    []string
+		expected int
+	}{
+		{[]string{"milk"}, 4},
+		{[]string{"milk", "bread"}, 3},
+		// Below MinSupport, so not mined.
+		{[]string{"milk", "bread", "eggs"}, 1},
+		{[]string{"milk", "jam"}, 0},
+	} {
+		count, err := result.SupportOf(tc.items...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != tc.expected {
+			t.Errorf("%v: expected %d, got %d", tc.items, tc.expected, count)
+		}
+	}
+}
+
+func TestExplainRule(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "groceries.csv", groceries)
+	args := arm.ArgumentsV2{
+		ItemsReader:   readerOf(groceries),
+		MinSupport:    0.2,
+		MinConfidence: 0.2,
+	}
+	result, err := arm.Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := result.Index().ByAntecedent("eggs", "milk")
+	if len(rules) != 1 {
+		t.Fatalf("expected 1 rule from eggs and milk, got %d", len(rules))
+	}
+	examples, err := result.ExplainRule(rules[0], path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{"milk", "bread", "eggs"}}
+	if !reflect.DeepEqual(examples, expected) {
+		t.Errorf("expected %v, got %v", expected, examples)
+	}
+	rules = result.Index().ByAntecedent("milk")
+	examples, err = result.ExplainRule(rules[0], path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) != 1 {
+		t.Errorf("expected 1 transaction with limit 1, got %d", len(examples))
+	}
+}
+
+func TestMineAssociationRulesV2WorkersDeterministic(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var input strings.Builder
+	for i := 0; i < 500; i++ {
+		for j := 0; j < 12; j++ {
+			if r.Intn(3) == 0 {
+				fmt.Fprintf(&input, "item%d,", j)
+			}
+		}
+		input.WriteString("\n")
+	}
+	mine := func(workers int) (string, string) {
+		var rules, itemsets strings.Builder
+		args := arm.ArgumentsV2{
+			ItemsReader: readerOf(input.String()),
+			RulesWriter: func() (io.WriteCloser, error) {
+				return nopWriteCloser{&rules}, nil
+			},
+			ItemsetsWriter: func() (io.WriteCloser, error) {
+				return nopWriteCloser{&itemsets}, nil
+			},
+			MinSupport:    0.02,
+			MinConfidence: 0.1,
+			Workers:       workers,
+		}
+		if err := arm.MineAssociationRulesV2(args, log.New(io.Discard, "", 0)); err != nil {
+			t.Fatal(err)
+		}
+		return rules.String(), itemsets.String()
+	}
+	expectedRules, expectedItemsets := mine(0)
+	for _, workers := range []int{0, 2, 8} {
+		rules, itemsets := mine(workers)
+		if rules != expectedRules || itemsets != expectedItemsets {
+			t.Errorf("output with %d workers differs from the sequential output", workers)
+		}
+	}
+}
+
+func TestMineAssociationRulesOutputItemIDs(t *testing.T) {
+	dir := t.TempDir()
+	args := arm.Arguments{
+		Input:         writeFile(t, dir, "groceries.csv", groceries),
+		Output:        filepath.Join(dir, "rules"),
+		MinSupport:    0.2,
+		MinConfidence: 0.2,
+		OutputItemIDs: true,
+	}
+	if err := arm.MineAssociationRules(args, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := os.ReadFile(args.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(rules), "milk") || !strings.Contains(string(rules), "1 => 2,") {
+		t.Errorf("expected rules with item IDs, got %q", rules)
+	}
+	itemizer, err := os.ReadFile(args.Output + ".itemizer.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `["milk","bread","eggs"]` + "\n"; string(itemizer) != expected {
+		t.Errorf("expected itemizer %q, got %q", expected, itemizer)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCheckpointMismatch is returned when the checkpoint at CheckpointPath
+// was written while mining another dataset, or with other options.
+var ErrCheckpointMismatch = errors.New("checkpoint does not match this run")
+
+// defaultCheckpointInterval is the CheckpointInterval used if it is 0.
+const defaultCheckpointInterval = time.Minute
+
+// checkpoint records the itemsets grown from each frequent item of the
+// header of an FP-tree, once they are all found, to the file at its path,
+// so that a later run can restore them rather than grow them again. The
+// file holds a line of JSON describing the run, then a line for each item
+// grown, in the order they are done. It is safe for concurrent use.
+type checkpoint struct {
+	path     string
+	interval time.Duration
+	itemizer *Itemizer
+	// Itemsets grown by an earlier run, by the rank of their header item
+	// among the frequent items.
+	restored map[int]checkpointRank
+
+	mu      sync.Mutex
+	file    *os.File
+	w       *bufio.Writer
+	flushed time.Time
+	err     error
+}
+
+// checkpointRun identifies the tree and options a checkpoint was written
+// for. A checkpoint is only restored by a run with the same one.
+type checkpointRun struct {
+	MinCount int
+	MaxDepth int
+	Header   []checkpointItem
+}
+
+type checkpointItem struct {
+	Item  string
+	Count int
+}
+
+// checkpointRank holds the itemsets grown from the header item of rank
+// Rank, and whether some were left out by MaxRecursionDepth.
+type checkpointRank struct {
+	Rank      int
+	Truncated bool
+	Itemsets  []checkpointItemset
+}
+
+type checkpointItemset struct {
+	Items []string
+	Count int
+}
+
+// openCheckpoint opens the checkpoint at args.CheckpointPath for growing
+// the frequent items of tree, restoring what it holds if it exists, or
+// creating it otherwise. A last line which was not completely written is
+// dropped. It returns an error wrapping ErrCheckpointMismatch if the file
+// is for another run.
+func openCheckpoint(args ArgumentsV2, tree *fpTree, itemizer *Itemizer, minCount int) (*checkpoint, error) {
+	run := checkpointRun{MinCount: minCount, MaxDepth: args.MaxRecursionDepth}
+	for _, item := range frequentItems(tree, minCount) {
+		run.Header = append(run.Header, checkpointItem{itemizer.toStr(item), tree.counts.get(item)})
+	}
+	cp := &checkpoint{
+		path:     args.CheckpointPath,
+		interval: args.CheckpointInterval,
+		itemizer: itemizer,
+		restored: make(map[int]checkpointRank),
+		flushed:  time.Now(),
+	}
+	if cp.interval == 0 {
+		cp.interval = defaultCheckpointInterval
+	}
+
+	file, err := os.OpenFile(cp.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	size, err := cp.restore(file, run)
+	if err == nil {
+		err = file.Truncate(size)
+	}
+	if err == nil {
+		_, err = file.Seek(size, io.SeekStart)
+	}
+	if err == nil && size == 0 {
+		_, err = writeCheckpointLine(file, run)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	cp.file = file
+	cp.w = bufio.NewWriter(file)
+	return cp, nil
+}
+
+// restore reads the checkpoint in file, which must be for run, and returns
+// the size of its complete lines, or 0 if it is empty.
+func (cp *checkpoint) restore(file *os.File, run checkpointRun) (int64, error) {
+	r := bufio.NewReader(file)
+	var size int64
+	for first := true; ; first = false {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// An incomplete last line was cut short by a crash.
+			return size, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if first {
+			var written checkpointRun
+			if err := json.Unmarshal(line, &written); err != nil || !reflect.DeepEqual(written, run) {
+				return 0, fmt.Errorf("%w: '%s' was written for other items or options", ErrCheckpointMismatch, cp.path)
+			}
+		} else {
+			var rank checkpointRank
+			if err := json.Unmarshal(line, &rank); err != nil {
+				return size, nil
+			}
+			cp.restored[rank.Rank] = rank
+		}
+		size += int64(len(line))
+	}
+}
+
+// writeCheckpointLine writes v to w as a line of JSON, and returns its
+// length.
+func writeCheckpointLine(w io.Writer, v interface{}) (int64, error) {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(line, '\n'))
+	return int64(n), err
+}
+
+// itemsets returns the itemsets an earlier run grew from the header item of
+// rank, and whether it did.
+func (cp *checkpoint) itemsets(rank int) ([]itemsetWithCount, bool, bool) {
+	restored, found := cp.restored[rank]
+	if !found {
+		return nil, false, false
+	}
+	itemsets := make([]itemsetWithCount, len(restored.Itemsets))
+	for i, is := range restored.Itemsets {
+		items := make([]Item, len(is.Items))
+		for j, str := range is.Items {
+			items[j], _ = cp.itemizer.lookup(str)
+		}
+		sort.Slice(items, func(a, b int) bool {
+			return items[a] < items[b]
+		})
+		itemsets[i] = itemsetWithCount{itemset: items, count: is.Count}
+	}
+	return itemsets, restored.Truncated, true
+}
+
+// record appends the itemsets grown from the header item of rank to the
+// checkpoint, and writes it to disk if the interval has passed since it
+// last was. The first error is kept for close.
+func (cp *checkpoint) record(rank int, itemsets []itemsetWithCount, truncated bool) {
+	r := checkpointRank{Rank: rank, Truncated: truncated, Itemsets: make([]checkpointItemset, len(itemsets))}
+	for i, iwc := range itemsets {
+		r.Itemsets[i] = checkpointItemset{cp.itemizer.strings(iwc.itemset), iwc.count}
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.err != nil {
+		return
+	}
+	if _, cp.err = writeCheckpointLine(cp.w, r); cp.err != nil {
+		return
+	}
+	if time.Since(cp.flushed) >= cp.interval {
+		cp.err = cp.sync()
+		cp.flushed = time.Now()
+	}
+}
+
+// sync writes what is buffered to disk.
+func (cp *checkpoint) sync() error {
+	if err := cp.w.Flush(); err != nil {
+		return err
+	}
+	return cp.file.Sync()
+}
+
+// close writes what is buffered to disk and closes the checkpoint, which
+// is removed if complete, as it is no longer needed once every item has
+// been grown.
+func (cp *checkpoint) close(complete bool) error {
+	err := cp.err
+	if err == nil {
+		err = cp.sync()
+	}
+	if cerr := cp.file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && complete {
+		err = os.Remove(cp.path)
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMineCheckpoint(t *testing.T) {
+	const data = "a,b,c\na,b\na,b,c,d\nb,c\na,c,d\n"
+	logger := log.New(io.Discard, "", 0)
+	args := ArgumentsV2{ItemsReader: readerOf(data), MinSupport: 0.2}
+	expected, err := Mine(args, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Record the first header item as a run which crashed while writing the
+	// second one would have.
+	args.CheckpointPath = filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	itemizer, frequency, numTransactions, err := countItems(args, &Stats{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	minCount := minCountFor(args.MinSupport, numTransactions)
+	tree, err := buildTree(context.Background(), args, itemizer, frequency, minCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp, err := openCheckpoint(args, tree, itemizer, minCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemsets, truncated := growItem(tree, frequentItems(tree, minCount)[0], nil, minCount, 0)
+	cp.record(0, itemsets, truncated)
+	if err := cp.close(false); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(args.CheckpointPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"Rank":1,"Truncated":false,"Itemsets":[{"Items":["b"],`)
+	f.Close()
+
+	var stats Stats
+	args.Stats = &stats
+	resumed, err := Mine(args, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.NumRestoredHeaderItems != 1 {
+		t.Errorf("expected 1 header item to be restored, got %d", stats.NumRestoredHeaderItems)
+	}
+	if !reflect.DeepEqual(resumed.itemsets, expected.itemsets) {
+		t.Errorf("expected the itemsets of an uninterrupted run\n%v, got\n%v", expected.itemsets, resumed.itemsets)
+	}
+	if _, err := os.Stat(args.CheckpointPath); !os.IsNotExist(err) {
+		t.Errorf("expected the checkpoint to be removed once complete, got %v", err)
+	}
+}
+
+func TestMineCheckpointMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	if err := os.WriteFile(path, []byte(`{"MinCount":1,"MaxDepth":0,"Header":[{"Item":"x","Count":1}]}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args := ArgumentsV2{ItemsReader: readerOf("a,b\na\n"), MinSupport: 0.5, CheckpointPath: path}
+	if _, err := Mine(args, log.New(io.Discard, "", 0)); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Errorf("expected ErrCheckpointMismatch, got %v", err)
+	}
+}
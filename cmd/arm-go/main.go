@@ -35,9 +35,21 @@ const usage = `Arguments:
   --min-confidence threshold
                         Minimum rule confidence threshold, in range [0,1].
   --min-lift threshold  Minimum rule lift confidence threshold, in range
-                        [1,∞] (optional).
+                        [1,∞], which rules must reach (optional).
+  --lift-exclusive      Drop rules whose lift equals --min-lift, so that
+                        rules must exceed it (optional).
   --itemsets file_path  File path in which to store generated itemsets
                         (optional).
+  --output-format format
+                        Format of the output rules, csv, sql, parquet,
+                        cypher, long, adjacency or json, or tree-json for
+                        itemsets, with rules as csv (optional).
+  --sql-table name      Table the sql output format inserts into
+                        (optional).
+  --cypher-label label  Label of the nodes the cypher output format merges
+                        (optional).
+  --dry-run             Only count items and log an estimate of the cost
+                        of mining (optional).
 `
 
 func main() {
@@ -91,6 +103,37 @@ func parseArgsOrDie() arm.Arguments {
 				result.ItemsetsPath = args[i+1]
 				i++
 			}
+		case "--output-format":
+			{
+				if i+1 >= len(args) {
+					fmt.Println("Expected --output-format to be followed by csv, sql, parquet, tree-json, cypher, long, adjacency or json.")
+					os.Exit(-1)
+				}
+				result.OutputFormat = args[i+1]
+				i++
+			}
+		case "--sql-table":
+			{
+				if i+1 >= len(args) {
+					fmt.Println("Expected --sql-table to be followed by a table name.")
+					os.Exit(-1)
+				}
+				result.SQLTable = args[i+1]
+				i++
+			}
+		case "--cypher-label":
+			{
+				if i+1 >= len(args) {
+					fmt.Println("Expected --cypher-label to be followed by a node label.")
+					os.Exit(-1)
+				}
+				result.CypherLabel = args[i+1]
+				i++
+			}
+		case "--dry-run":
+			result.DryRun = true
+		case "--lift-exclusive":
+			result.LiftExclusive = true
 		case "--min-support":
 			{
 				if i+1 > len(args) {
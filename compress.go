@@ -0,0 +1,82 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Compressions with which output can be written.
+const (
+	OutputCompressionNone = "none"
+	OutputCompressionGzip = "gzip"
+)
+
+// compressionFor returns the compression to write path with. If none was
+// asked for, it is inferred from the extension of path.
+func compressionFor(compression string, path string) string {
+	if compression != "" {
+		return compression
+	}
+	if strings.HasSuffix(path, ".gz") {
+		return OutputCompressionGzip
+	}
+	return OutputCompressionNone
+}
+
+// compressedOutput compresses what is written to output. Closing it
+// flushes the compressor before closing output.
+type compressedOutput struct {
+	*gzip.Writer
+	output io.WriteCloser
+}
+
+func (c compressedOutput) Close() error {
+	err := c.Writer.Close()
+	if cerr := c.output.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Stat reports on output, so that headers are still only written to empty
+// files when appending.
+func (c compressedOutput) Stat() (os.FileInfo, error) {
+	f, ok := c.output.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return nil, errors.New("output has no Stat method")
+	}
+	return f.Stat()
+}
+
+func compress(output io.WriteCloser, compression string) io.WriteCloser {
+	if compression == OutputCompressionGzip {
+		return compressedOutput{gzip.NewWriter(output), output}
+	}
+	return output
+}
+
+// openWriter opens a writer, wrapping it to compress what is written.
+func openWriter(open func() (io.WriteCloser, error), compression string) (io.WriteCloser, error) {
+	output, err := open()
+	if err != nil {
+		return nil, err
+	}
+	return compress(output, compression), nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+// condenseItemsets returns the closed itemsets of itemsets, those with no
+// superset of the same count, or with maximal the maximal ones, those with
+// no superset at all, in their order in itemsets. Every subset of an
+// itemset must be in itemsets too, as fpGrowth finds them. Then a superset
+// of the same count, if there is one, can be found among the supersets with
+// one more item, since counts only shrink as items are added.
+func condenseItemsets(itemsets []itemsetWithCount, maximal bool) []itemsetWithCount {
+	byKey := make(map[string]int, len(itemsets))
+	for i, iwc := range itemsets {
+		byKey[sortedItemsKey(iwc.itemset)] = i
+	}
+	subsumed := make([]bool, len(itemsets))
+	subset := make([]Item, 0)
+	for _, iwc := range itemsets {
+		if len(iwc.itemset) < 2 {
+			continue
+		}
+		for skip := range iwc.itemset {
+			subset = append(subset[:0], iwc.itemset[:skip]...)
+			subset = append(subset, iwc.itemset[skip+1:]...)
+			i, found := byKey[sortedItemsKey(subset)]
+			if found && (maximal || itemsets[i].count == iwc.count) {
+				subsumed[i] = true
+			}
+		}
+	}
+	condensed := make([]itemsetWithCount, 0)
+	for i, iwc := range itemsets {
+		if !subsumed[i] {
+			condensed = append(condensed, iwc)
+		}
+	}
+	return condensed
+}
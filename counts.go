@@ -0,0 +1,109 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"io"
+	"log"
+	"strings"
+)
+
+// ItemCounts holds the number of transactions each item of a dataset, or
+// of a shard of one, appears in.
+type ItemCounts struct {
+	// Itemizer which assigned the items counted.
+	Itemizer        *Itemizer
+	NumTransactions int
+	counts          itemCount
+}
+
+// CountItems counts the items read from itemsReader. This is the first
+// pass of mining, and can be run on shards of a dataset separately and
+// combined with MergeCounts.
+func CountItems(itemsReader ItemsReader) (*ItemCounts, error) {
+	itemizer, frequency, numTransactions, err := countItems(ArgumentsV2{ItemsReader: itemsReader}, &Stats{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ItemCounts{
+		Itemizer:        itemizer,
+		NumTransactions: numTransactions,
+		counts:          *frequency,
+	}, nil
+}
+
+// Count returns the number of transactions item appears in.
+func (c *ItemCounts) Count(item string) int {
+	i, found := c.Itemizer.lookup(item)
+	if !found {
+		return 0
+	}
+	return c.counts.get(i)
+}
+
+// MergeCounts sums the counts of a and b, which need not share an
+// Itemizer. The result keeps a's items and adds b's new items after them;
+// neither a nor b is modified.
+func MergeCounts(a, b *ItemCounts) *ItemCounts {
+	merged := &ItemCounts{
+		Itemizer:        a.Itemizer.clone(),
+		NumTransactions: a.NumTransactions + b.NumTransactions,
+		counts:          a.counts.clone(),
+	}
+	merged.counts.add(&b.counts, merged.Itemizer.Merge(b.Itemizer))
+	return merged
+}
+
+// CountTransactions returns the number of transactions which mining
+// args.Input would count: it reads Input as the first pass of mining does,
+// with the same glob, InputFormat, PivotKey, FieldSplitFunc,
+// TransactionFilter, MaxLineBytes, SkipMalformed and
+// SkipLongTransactions, but without building an Itemizer or counting
+// items. The other options are ignored.
+func CountTransactions(args Arguments) (int, error) {
+	if err := args.Validate(); err != nil {
+		return 0, err
+	}
+	v2, err := argumentsV2(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		return 0, err
+	}
+	if err := prepare(&v2); err != nil {
+		return 0, err
+	}
+	numTransactions := 0
+	_, err = scanTransactions(v2, nil, func(fields []string) bool {
+		if !v2.SkipLongTransactions || !longTransaction(v2, numItems(fields)) {
+			numTransactions++
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return numTransactions, nil
+}
+
+// numItems returns the number of items Itemize makes of fields, which
+// skips those which are blank.
+func numItems(fields []string) int {
+	n := 0
+	for _, field := range fields {
+		if strings.TrimSpace(field) != "" {
+			n++
+		}
+	}
+	return n
+}
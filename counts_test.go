@@ -0,0 +1,137 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm_test
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nokia/arm-go"
+)
+
+func readerOf(data string) arm.ItemsReader {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(data)), nil
+	}
+}
+
+func TestMergeCounts(t *testing.T) {
+	// The shards see the items in different orders, so assign them
+	// different IDs.
+	a, err := arm.CountItems(readerOf("milk,bread\nmilk\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := arm.CountItems(readerOf("eggs,bread\nbread,milk\neggs\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := arm.MergeCounts(a, b)
+	if merged.NumTransactions != 5 {
+		t.Errorf("expected 5 transactions, got %d", merged.NumTransactions)
+	}
+	for item, expected := range map[string]int{"milk": 3, "bread": 3, "eggs": 2, "jam": 0} {
+		if got := merged.Count(item); got != expected {
+			t.Errorf("expected count(%s)=%d, got %d", item, expected, got)
+		}
+	}
+	if a.Count("eggs") != 0 || a.Count("milk") != 2 {
+		t.Error("MergeCounts modified its input")
+	}
+}
+
+func TestCountTransactions(t *testing.T) {
+	dir := t.TempDir()
+	for data, expected := range map[string]int{
+		"":             0,
+		"a,b\n":        1,
+		"a,b\nc":       2,
+		"a,b\n\nc,d\n": 3,
+		groceries:      5,
+	} {
+		n, err := arm.CountTransactions(arm.Arguments{Input: writeFile(t, dir, "data.csv", data)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != expected {
+			t.Errorf("%q: expected %d transactions, got %d", data, expected, n)
+		}
+		counts, err := arm.CountItems(readerOf(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts.NumTransactions != n {
+			t.Errorf("%q: CountItems found %d transactions, CountTransactions %d", data, counts.NumTransactions, n)
+		}
+	}
+}
+
+func TestCountTransactionsOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "part1.csv", "milk,bread\n")
+	writeFile(t, dir, "part2.csv", "milk\neggs,bread,milk\n")
+	tests := []struct {
+		name     string
+		args     arm.Arguments
+		expected int
+	}{
+		{"glob", arm.Arguments{Input: filepath.Join(dir, "part*.csv")}, 3},
+		{"onehot", arm.Arguments{
+			Input:       writeFile(t, dir, "onehot.csv", "milk,bread\n1,0\n1,1\n"),
+			InputFormat: arm.InputFormatOneHot,
+		}, 2},
+		{"jsonl", arm.Arguments{
+			Input:       writeFile(t, dir, "data.jsonl", "[\"milk\"]\n\n[\"bread\"]\n"),
+			InputFormat: arm.InputFormatJSONL,
+		}, 3}, // a blank line is an empty transaction, as in mining
+		{"skip-long", arm.Arguments{
+			Input:                  writeFile(t, dir, "long.csv", "a,b,c\na\na,b\n"),
+			MaxItemsPerTransaction: 2,
+			SkipLongTransactions:   true,
+		}, 2},
+		{"pivot", arm.Arguments{
+			Input:      writeFile(t, dir, "events.log", "user=1 item=milk\nuser=1 item=bread\nuser=2 item=milk\n"),
+			PivotKey:   "item",
+			PivotGroup: "user",
+		}, 2},
+		{"filter", arm.Arguments{
+			Input:             writeFile(t, dir, "days.csv", "mon,milk\nsat,bread\ntue,eggs\n"),
+			TransactionFilter: func(fields []string) bool { return fields[0] != "sat" },
+		}, 2},
+	}
+	for _, tt := range tests {
+		n, err := arm.CountTransactions(tt.args)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if n != tt.expected {
+			t.Errorf("%s: expected %d transactions, got %d", tt.name, tt.expected, n)
+		}
+	}
+
+	malformed := arm.Arguments{
+		Input:       writeFile(t, dir, "malformed.jsonl", "[\"milk\"]\nnot json\n[\"bread\"]\n"),
+		InputFormat: arm.InputFormatJSONL,
+	}
+	if _, err := arm.CountTransactions(malformed); err == nil {
+		t.Error("expected a malformed line to fail")
+	}
+	malformed.SkipMalformed = true
+	if n, err := arm.CountTransactions(malformed); err != nil || n != 2 {
+		t.Errorf("expected 2 transactions skipping the malformed line, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// defaultCypherLabel is the label of the nodes the Cypher output format
+// merges.
+const defaultCypherLabel = "Item"
+
+// writeRulesCypher writes one statement per rule, which merges a node for
+// the antecedent and one for the consequent, and an IMPLIES relationship
+// between them holding the measures of the rule. Nodes are named by their
+// space separated items, as in the CSV output, so that loading the same
+// rules twice changes nothing.
+func writeRulesCypher(w io.Writer, rules [][]Rule, itemizer *Itemizer, label string) error {
+	if label == "" {
+		label = defaultCypherLabel
+	}
+	for _, chunk := range rules {
+		for _, rule := range chunk {
+			if _, err := fmt.Fprintf(w,
+				"MERGE (a:%s {name: %s}) MERGE (c:%s {name: %s}) MERGE (a)-[r:IMPLIES]->(c) "+
+					"SET r.confidence = %f, r.lift = %f, r.support = %f;\n",
+				label, cypherString(itemizer.join(rule.Antecedent, " ")),
+				label, cypherString(itemizer.join(rule.Consequent, " ")),
+				rule.Confidence, rule.Lift, rule.Support); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cypherString quotes s as a Cypher string literal, in which backslashes
+// and quotes are escaped by a backslash.
+func cypherString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,347 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Dataset holds transactions in memory, compressed into a prefix tree, so
+// that they can be mined again as new transactions arrive without reading
+// the old ones again.
+//
+// Transactions are stored as they were parsed, before any threshold is
+// applied, so mining a Dataset yields the same itemsets and rules as
+// mining all the transactions inserted into it at once with the same
+// arguments. Only the order of transactions is lost, which no result
+// depends on. Each InsertTransactions call adds all the transactions read,
+// or none if reading fails.
+type Dataset struct {
+	// Converts between the items of the Dataset and their strings. Items
+	// keep their IDs as transactions are inserted.
+	Itemizer        *Itemizer
+	NumTransactions int
+	// Every transaction inserted, with its items in increasing order.
+	tree *fpTree
+	// Arguments which select how transactions are parsed and itemized.
+	args ArgumentsV2
+	// Number of transactions each item appears in, once counted, until
+	// more transactions are inserted.
+	counts *itemCount
+}
+
+// NewDataset returns an empty Dataset, which parses and itemizes the
+// transactions inserted into it as args ask, as with InputFormat,
+// NormalizeUnicode, CollapseWhitespace, Aliases, IDColumn, PivotKey,
+// LabelColumn, TransactionFilter or SkipMalformed. The other
+// arguments, and the readers and writers, are ignored.
+func NewDataset(args ArgumentsV2) *Dataset {
+	itemizer := itemizerFor(args)
+	return &Dataset{
+		Itemizer: &itemizer,
+		tree:     newTree(),
+		args:     args,
+	}
+}
+
+// InsertTransactions reads the transactions of itemsReader, and adds them
+// to d.
+func (d *Dataset) InsertTransactions(itemsReader ItemsReader) error {
+	args := d.args
+	args.ItemsReader = itemsReader
+	args.pivot()
+
+	// Items are assigned by a copy of the Itemizer, and transactions are
+	// only inserted once they have all been read.
+	itemizer := d.Itemizer.clone()
+	transactions := make([][]Item, 0)
+	_, err := scanTransactions(args, nil, func(fields []string) bool {
+		transactions = append(transactions, itemizer.itemizeLabelled(fields, args))
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	d.Itemizer = itemizer
+	for _, transaction := range transactions {
+		d.insert(transaction, 1)
+	}
+	return nil
+}
+
+func (d *Dataset) insert(transaction []Item, count int) {
+	sort.Slice(transaction, func(i, j int) bool {
+		return transaction[i] < transaction[j]
+	})
+	d.tree.Insert(transaction, count)
+	d.NumTransactions += count
+	d.counts = nil
+}
+
+// ConditionalPatternBase returns the conditional pattern base of item in
+// the prefix tree holding the transactions of d. Items are ordered by
+// their IDs in the tree, so each pattern holds the items of a transaction
+// with a lower ID than item.
+func (d *Dataset) ConditionalPatternBase(item Item) []PatternWithCount {
+	return d.tree.ConditionalPatternBase(item)
+}
+
+// itemCounts returns the number of transactions of d each item appears in.
+func (d *Dataset) itemCounts() *itemCount {
+	if d.counts == nil {
+		counts := makeCounts()
+		d.forEachTransaction(func(transaction []Item, count int) {
+			for _, item := range transaction {
+				counts.increment(item, count)
+			}
+		})
+		d.counts = &counts
+	}
+	return d.counts
+}
+
+// forEachTransaction calls fn with each distinct transaction of d, and the
+// number of times it was inserted.
+func (d *Dataset) forEachTransaction(fn func([]Item, int)) {
+	var walk func(node *fpNode, path []Item)
+	walk = func(node *fpNode, path []Item) {
+		if !isRoot(node) {
+			path = append(path, node.item)
+		}
+		// The transactions which end at node are those not counted by its
+		// children.
+		count := node.count
+		for _, child := range node.children {
+			count -= child.count
+			walk(child, path)
+		}
+		if count > 0 {
+			fn(append([]Item(nil), path...), count)
+		}
+	}
+	walk(d.tree.root, nil)
+}
+
+// Mine mines association rules from the transactions of d, like Mine does
+// from a dataset holding them. The options of args which select how
+// transactions are parsed are those d was created with, and its reader
+// and writers are ignored. The Result cannot compute SupportOf, as it has
+// no dataset to read.
+func (d *Dataset) Mine(args ArgumentsV2, log Logger) (*Result, error) {
+	return d.mine(nil, args, log)
+}
+
+// MineWith mines d like Mine, but as if its transactions held only the
+// items of universe, given by their strings, so that several sets of
+// items can be tried in turn. Strings of no item of d are ignored.
+// Supports are still relative to every transaction of d, even those left
+// without an item. The counts of the items are kept from one call to the
+// next, so that they are only counted again once transactions are
+// inserted, or if MaxItemsPerTransaction is set, as which transactions
+// are too long then depends on universe.
+func (d *Dataset) MineWith(universe []string, args ArgumentsV2, log Logger) (*Result, error) {
+	keep := make(map[Item]bool, len(universe))
+	for _, str := range universe {
+		if item, found := d.Itemizer.lookup(str); found {
+			keep[item] = true
+		}
+	}
+	return d.mine(keep, args, log)
+}
+
+// mine is Mine, keeping only the items in keep if it is not nil.
+func (d *Dataset) mine(keep map[Item]bool, args ArgumentsV2, log Logger) (*Result, error) {
+	if err := args.validateOptions(); err != nil {
+		return nil, err
+	}
+	args.convertMinSupportPPM()
+	args.ItemsReader = nil
+	stats := statsFor(args.Stats)
+	forEachTransaction := func(fn func([]Item, int)) {
+		d.forEachTransaction(func(transaction []Item, count int) {
+			fn(keepItems(transaction, keep), count)
+		})
+	}
+
+	// Count the items as the first pass over a dataset would.
+	frequency := makeCounts()
+	numTransactions := 0
+	if args.MaxItemsPerTransaction == 0 {
+		frequency = d.itemCounts().clone()
+		for idx := range frequency.counts {
+			if keep != nil && !keep[Item(idx)] {
+				frequency.counts[idx] = 0
+			}
+		}
+		numTransactions = d.NumTransactions
+	} else {
+		forEachTransaction(func(transaction []Item, count int) {
+			if longTransaction(args, len(transaction)) {
+				stats.NumLongTransactions += count
+				if !args.SkipLongTransactions {
+					numTransactions += count
+				}
+				return
+			}
+			numTransactions += count
+			for _, item := range transaction {
+				frequency.increment(item, count)
+			}
+		})
+		if stats.NumLongTransactions > 0 && !args.SkipLongTransactions {
+			// Long transactions are truncated by the counts of the others,
+			// as countItems does.
+			short := frequency.clone()
+			frequency.short = &short
+			forEachTransaction(func(transaction []Item, count int) {
+				if longTransaction(args, len(transaction)) {
+					for _, item := range truncateTransaction(args, transaction, d.Itemizer, &frequency) {
+						frequency.increment(item, count)
+					}
+				}
+			})
+		}
+	}
+	args.convertMinSupportCount(numTransactions)
+	recordItemStats(args, d.Itemizer, &frequency, numTransactions, stats)
+	if args.TopFrequentItems > 0 {
+		keepTopFrequentItems(args, d.Itemizer, &frequency, log)
+	}
+	if err := checkTransactions(args, numTransactions); err != nil {
+		return nil, err
+	}
+	result := &Result{
+		Itemizer:        d.Itemizer.clone(),
+		NumTransactions: numTransactions,
+		frequency:       &frequency,
+		args:            args,
+	}
+	if args.DryRun {
+		recordEstimate(estimateCost(result.frequency, numTransactions, args.MinSupport), stats, log)
+		return result, nil
+	}
+
+	log.Println("Generating frequent itemsets via fpGrowth")
+	start := time.Now()
+	minCount := minCountFor(args.MinSupport, numTransactions)
+	less := itemLess(args.ItemOrder, args.TieBreak, result.Itemizer, result.frequency)
+	tree := newTree()
+	forEachTransaction(func(transaction []Item, count int) {
+		transaction, ok := limitTransaction(args, transaction, result.Itemizer, result.frequency, minCount)
+		if !ok || len(transaction) == 0 {
+			return
+		}
+		sort.SliceStable(transaction, func(i, j int) bool {
+			return less(transaction[i], transaction[j])
+		})
+		tree.Insert(transaction, count)
+	})
+	itemsets, err := growTree(context.Background(), args, tree, result.Itemizer, minCount, stats)
+	if err != nil {
+		return nil, err
+	}
+	result.itemsets = itemsets
+	reportItemsets(args, len(result.itemsets), stats, time.Since(start), log)
+	result.itemsets = capItemsets(result.itemsets, args.MaxItemsets, result.Itemizer, log)
+	return mineRules(context.Background(), result.args, result, stats, log)
+}
+
+// keepItems returns the items of transaction in keep, or all of them if keep
+// is nil. It reuses the storage of transaction.
+func keepItems(transaction []Item, keep map[Item]bool) []Item {
+	if keep == nil {
+		return transaction
+	}
+	kept := transaction[:0]
+	for _, item := range transaction {
+		if keep[item] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// datasetJSON is the form in which a Dataset is saved.
+type datasetJSON struct {
+	Itemizer     *Itemizer         `json:"itemizer"`
+	Transactions []transactionJSON `json:"transactions"`
+	// Items read from the label column, with ClassRulesOnly, in
+	// increasing order.
+	Labels []Item `json:"labels,omitempty"`
+}
+
+type transactionJSON struct {
+	Items []Item `json:"items"`
+	Count int    `json:"count"`
+}
+
+// Save writes d to w as JSON, which LoadDataset reads back.
+func (d *Dataset) Save(w io.Writer) error {
+	saved := datasetJSON{
+		Itemizer:     d.Itemizer,
+		Transactions: make([]transactionJSON, 0),
+	}
+	d.forEachTransaction(func(transaction []Item, count int) {
+		saved.Transactions = append(saved.Transactions, transactionJSON{transaction, count})
+	})
+	for item := range d.Itemizer.labels {
+		saved.Labels = append(saved.Labels, item)
+	}
+	sort.Slice(saved.Labels, func(i, j int) bool {
+		return saved.Labels[i] < saved.Labels[j]
+	})
+	return json.NewEncoder(w).Encode(saved)
+}
+
+// LoadDataset reads a Dataset saved by Save from r. The transactions
+// inserted into it later are parsed and itemized as args ask, which should
+// be as the saved Dataset was created with.
+func LoadDataset(r io.Reader, args ArgumentsV2) (*Dataset, error) {
+	var saved datasetJSON
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, err
+	}
+	d := NewDataset(args)
+	if saved.Itemizer != nil {
+		// Adding the strings in order gives each the item it had.
+		for item := Item(1); int(item) <= saved.Itemizer.numItems; item++ {
+			d.Itemizer.add(saved.Itemizer.toStr(item))
+		}
+	}
+	for _, t := range saved.Transactions {
+		for _, item := range t.Items {
+			if item < 1 || int(item) > d.Itemizer.numItems {
+				return nil, fmt.Errorf("unknown item %d in saved Dataset", item)
+			}
+		}
+		d.insert(t.Items, t.Count)
+	}
+	for _, item := range saved.Labels {
+		if item < 1 || int(item) > d.Itemizer.numItems {
+			return nil, fmt.Errorf("unknown label %d in saved Dataset", item)
+		}
+		if d.Itemizer.labels == nil {
+			d.Itemizer.labels = make(map[Item]bool)
+		}
+		d.Itemizer.labels[item] = true
+	}
+	return d, nil
+}
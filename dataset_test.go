@@ -0,0 +1,193 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+)
+
+func TestDatasetInsertTransactions(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	args := ArgumentsV2{MinSupport: 0.2, MinConfidence: 0.2, SortOutput: true}
+	whole := args
+	whole.ItemsReader = readerOf("milk,bread\nmilk,bread,eggs\nbread,eggs\nmilk,eggs\nmilk,bread\n")
+	expected, err := Mine(whole, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDataset(args)
+	if err := d.InsertTransactions(readerOf("milk,bread\nmilk,bread,eggs\n")); err != nil {
+		t.Fatal(err)
+	}
+	var saved bytes.Buffer
+	if err := d.Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+	d, err = LoadDataset(&saved, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.InsertTransactions(readerOf("bread,eggs\nmilk,eggs\nmilk,bread\n")); err != nil {
+		t.Fatal(err)
+	}
+	if d.NumTransactions != 5 {
+		t.Errorf("expected 5 transactions, got %d", d.NumTransactions)
+	}
+	result, err := d.Mine(args, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Rules, expected.Rules) {
+		t.Errorf("expected the rules of mining all transactions at once\n%v, got\n%v", expected.Rules, result.Rules)
+	}
+}
+
+func TestDatasetSaveClassRulesOnly(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	args := ArgumentsV2{MinSupport: 0.2, MinConfidence: 0.5, ClassRulesOnly: true, SortOutput: true}
+	d := NewDataset(args)
+	if err := d.InsertTransactions(readerOf("yes,milk,bread\nno,milk\nyes,bread,milk\nno,eggs\nyes,bread\n")); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := d.Mine(args, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved bytes.Buffer
+	if err := d.Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+	d, err = LoadDataset(&saved, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := d.Mine(args, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expected.Rules) == 0 || !reflect.DeepEqual(result.Rules, expected.Rules) {
+		t.Errorf("expected the class rules of the Dataset before saving\n%v, got\n%v", expected.Rules, result.Rules)
+	}
+}
+
+func TestDatasetMineWith(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	args := ArgumentsV2{MinSupport: 0.2, MinConfidence: 0.2, SortOutput: true}
+	d := NewDataset(args)
+	if err := d.InsertTransactions(readerOf("milk,bread\nmilk,bread,eggs\nbread,eggs\nmilk,eggs\nmilk,bread\n")); err != nil {
+		t.Fatal(err)
+	}
+	for _, maxItems := range []int{0, 0, 2} {
+		args.MaxItemsPerTransaction = maxItems
+		filtered := args
+		filtered.ItemsReader = readerOf("milk,bread\nmilk,bread\nbread\nmilk\nmilk,bread\n")
+		expected, err := Mine(filtered, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := d.MineWith([]string{"milk", "bread", "butter"}, args, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result.Rules, expected.Rules) {
+			t.Errorf("expected the rules of mining without eggs\n%v, got\n%v", expected.Rules, result.Rules)
+		}
+	}
+
+	result, err := d.Mine(ArgumentsV2{MinSupport: 0.2, MinConfidence: 0.2}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rules) != 12 {
+		t.Errorf("expected Mine to keep every item after MineWith, got %d rules", len(result.Rules))
+	}
+}
+
+func TestConditionalPatternBase(t *testing.T) {
+	d := NewDataset(ArgumentsV2{})
+	if err := d.InsertTransactions(readerOf("milk,bread\nmilk,bread,eggs\nbread,eggs\nmilk,eggs\nmilk,bread\n")); err != nil {
+		t.Fatal(err)
+	}
+	milk, eggs := d.Itemizer.strToItem["milk"], d.Itemizer.strToItem["eggs"]
+	counts := make(map[string]int)
+	for _, pattern := range d.ConditionalPatternBase(eggs) {
+		counts[d.Itemizer.join(pattern.Pattern, " ")] += pattern.Count
+	}
+	expected := map[string]int{"milk bread": 1, "bread": 1, "milk": 1}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected pattern base %v, got %v", expected, counts)
+	}
+	if base := d.ConditionalPatternBase(milk); len(base) != 1 || len(base[0].Pattern) != 0 || base[0].Count != 4 {
+		t.Errorf("expected an empty pattern with count 4 for milk, got %v", base)
+	}
+}
+
+func TestDatasetInsertTransactionsFails(t *testing.T) {
+	d := NewDataset(ArgumentsV2{InputFormat: InputFormatJSONL})
+	if err := d.InsertTransactions(readerOf("[\"milk\"]\nnot json\n")); err == nil {
+		t.Fatal("expected a parse error")
+	}
+	if d.NumTransactions != 0 || d.Itemizer.numItems != 0 {
+		t.Errorf("expected no transactions or items to be added, got %d and %d", d.NumTransactions, d.Itemizer.numItems)
+	}
+}
+
+func TestDatasetMineMinSupportCount(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	d := NewDataset(ArgumentsV2{})
+	if err := d.InsertTransactions(readerOf("milk,bread\nmilk,bread,eggs\nbread,eggs\nmilk,eggs\nmilk,bread\n")); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := d.Mine(ArgumentsV2{MinSupport: 0.6, MinConfidence: 0.2}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := d.Mine(ArgumentsV2{MinSupportCount: 3, MinConfidence: 0.2}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expected.Rules) == 0 || !reflect.DeepEqual(result.Rules, expected.Rules) {
+		t.Errorf("expected rules %v with MinSupportCount, got %v", expected.Rules, result.Rules)
+	}
+
+	// Eggs appear in only 3 transactions.
+	var stats Stats
+	if _, err := d.Mine(ArgumentsV2{MinSupportCount: 4, DryRun: true, Stats: &stats}, logger); err != nil {
+		t.Fatal(err)
+	}
+	if stats.NumFrequentItems != 2 {
+		t.Errorf("expected 2 frequent items with MinSupportCount, got %d", stats.NumFrequentItems)
+	}
+}
+
+func TestConvertMinSupportCount(t *testing.T) {
+	for numTransactions := 1; numTransactions <= 1000; numTransactions++ {
+		for _, count := range []int{1, 2, 3, numTransactions / 7, numTransactions / 3, numTransactions - 1, numTransactions} {
+			if count < 1 {
+				continue
+			}
+			args := ArgumentsV2{MinSupportCount: count}
+			args.convertMinSupportCount(numTransactions)
+			if got := minCountFor(args.MinSupport, numTransactions); got != count || args.MinSupportCount != 0 {
+				t.Fatalf("MinSupportCount %d of %d: got MinSupport %v, whose minimum count is %d", count, numTransactions, args.MinSupport, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"sort"
+	"strconv"
+)
+
+// RuleDiff describes a rule which differs between two rule sets.
+type RuleDiff struct {
+	// The rule in the old and in the new set. Old is nil for an added rule,
+	// and New for a removed one.
+	Old *Rule
+	New *Rule
+	// Measures in the new set minus those in the old one, or 0 for an
+	// added or removed rule.
+	SupportDelta    float64
+	ConfidenceDelta float64
+	LiftDelta       float64
+}
+
+// DiffRules compares two rule sets, such as those mined from two periods,
+// matching rules by their antecedent and consequent, whatever the order of
+// their items. It returns the rules only in new, in their order there, the
+// rules only in old, in their order there, and the rules in both whose
+// support, confidence or lift changed, in their order in new. Rules are
+// matched by their ID, which Mine sets from the item strings, so sets
+// mined separately, each with its own Itemizer, compare correctly. Rules
+// without an ID, such as those of GenerateRules, are matched by their
+// items as they are, so both sets must then share an Itemizer.
+func DiffRules(old, new []Rule) (added, removed, changed []RuleDiff) {
+	oldByKey := make(map[string]*Rule, len(old))
+	for i := range old {
+		key := ruleKey(&old[i])
+		if _, found := oldByKey[key]; !found {
+			oldByKey[key] = &old[i]
+		}
+	}
+	matched := make(map[string]bool, len(new))
+	for i := range new {
+		n := &new[i]
+		key := ruleKey(n)
+		if matched[key] {
+			continue
+		}
+		matched[key] = true
+		o, found := oldByKey[key]
+		if !found {
+			added = append(added, RuleDiff{New: n})
+			continue
+		}
+		if o.Support != n.Support || o.Confidence != n.Confidence || o.Lift != n.Lift {
+			changed = append(changed, RuleDiff{
+				Old:             o,
+				New:             n,
+				SupportDelta:    n.Support - o.Support,
+				ConfidenceDelta: n.Confidence - o.Confidence,
+				LiftDelta:       n.Lift - o.Lift,
+			})
+		}
+	}
+	for i := range old {
+		key := ruleKey(&old[i])
+		if !matched[key] {
+			matched[key] = true
+			removed = append(removed, RuleDiff{Old: &old[i]})
+		}
+	}
+	return added, removed, changed
+}
+
+// ruleKey encodes the ID of rule, or if it has none its antecedent and
+// consequent, as a map key, the same whatever the order of their items.
+func ruleKey(rule *Rule) string {
+	if rule.ID != 0 {
+		return "#" + strconv.FormatUint(rule.ID, 16)
+	}
+	antecedent := sortedItemsKey(rule.Antecedent)
+	// Any byte may occur in an items key, so the length of the first one
+	// marks where the second starts.
+	return strconv.Itoa(len(antecedent)) + ":" + antecedent + sortedItemsKey(rule.Consequent)
+}
+
+func sortedItemsKey(items []Item) string {
+	sorted := append([]Item(nil), items...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return itemsKey(sorted)
+}
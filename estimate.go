@@ -0,0 +1,81 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import "sort"
+
+// CostEstimate is a rough projection of the cost of mining a dataset,
+// computed from the item frequencies gathered in the first pass only.
+type CostEstimate struct {
+	NumTransactions  int
+	NumItems         int
+	NumFrequentItems int
+	// Upper bound on the size of the FP-tree: every occurrence of a
+	// frequent item creates at most one node.
+	MaxTreeNodes int
+	// Number of item pairs expected to be frequent if items occurred
+	// independently of each other. Correlated data will have more.
+	FrequentPairs int
+	// Each frequent pair yields up to two rules, one in each direction.
+	// Longer itemsets are not projected, so this is a lower bound on
+	// dense or strongly correlated data.
+	EstimatedRules int
+}
+
+func estimateCost(frequency *itemCount, numTransactions int, minSupport float64) CostEstimate {
+	minCount := minCountFor(minSupport, numTransactions)
+	e := CostEstimate{NumTransactions: numTransactions}
+	frequent := make([]int, 0)
+	for _, count := range frequency.counts {
+		if count == 0 {
+			continue
+		}
+		e.NumItems++
+		if count >= minCount {
+			frequent = append(frequent, count)
+			e.MaxTreeNodes += count
+		}
+	}
+	e.NumFrequentItems = len(frequent)
+
+	// Under independence the expected count of the pair {a, b} is
+	// count(a) * count(b) / numTransactions. With the counts sorted in
+	// decreasing order, the partners of each item that keep the pair
+	// frequent form a prefix which only shrinks as we move down the list.
+	sort.Sort(sort.Reverse(sort.IntSlice(frequent)))
+	threshold := float64(minCount) * float64(numTransactions)
+	last := len(frequent) - 1
+	for i := range frequent {
+		for last > i && float64(frequent[i])*float64(frequent[last]) < threshold {
+			last--
+		}
+		if last <= i {
+			break
+		}
+		e.FrequentPairs += last - i
+	}
+	e.EstimatedRules = 2 * e.FrequentPairs
+	return e
+}
+
+// recordEstimate logs e, and records it in stats.
+func recordEstimate(e CostEstimate, stats *Stats, log Logger) {
+	stats.Estimate = &e
+	log.Printf("Dry run: %d transactions, %d distinct items, %d frequent items",
+		e.NumTransactions, e.NumItems, e.NumFrequentItems)
+	log.Printf("Dry run: FP-tree would have at most %d nodes", e.MaxTreeNodes)
+	log.Printf("Dry run: projected %d frequent pairs and %d rules, assuming items occur independently",
+		e.FrequentPairs, e.EstimatedRules)
+}
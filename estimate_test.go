@@ -0,0 +1,36 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import "testing"
+
+func TestEstimateCost(t *testing.T) {
+	frequency := makeCounts()
+	for item, count := range []int{50, 40, 10, 2} {
+		frequency.increment(Item(item+1), count)
+	}
+	got := estimateCost(&frequency, 100, 0.1)
+	want := CostEstimate{
+		NumTransactions:  100,
+		NumItems:         4,
+		NumFrequentItems: 3,
+		MaxTreeNodes:     100,
+		FrequentPairs:    1,
+		EstimatedRules:   2,
+	}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
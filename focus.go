@@ -0,0 +1,76 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+// focusItem returns the item FocusItem converts to, and whether FocusItem
+// is set. An unknown FocusItem converts to no item, which no transaction
+// contains.
+func focusItem(args ArgumentsV2, itemizer *Itemizer) (Item, bool) {
+	if args.FocusItem == "" {
+		return 0, false
+	}
+	item, _ := itemizer.lookup(args.FocusItem)
+	return item, true
+}
+
+// focusLast returns less, but ordering focus after every other item, so
+// that the paths of the FP-tree which end at focus hold whole transactions.
+func focusLast(less func(a, b Item) bool, focus Item) func(a, b Item) bool {
+	return func(a, b Item) bool {
+		if a == focus || b == focus {
+			return b == focus && a != focus
+		}
+		return less(a, b)
+	}
+}
+
+// focusItemsets returns the frequent itemsets containing focus, grown from
+// tree, which holds only the transactions containing focus, ordered by
+// focusLast. They are followed by the subsets of those without focus,
+// which rules need the counts of. Such subsets are counted by reading the
+// dataset again, testing each of them against each transaction.
+func focusItemsets(args ArgumentsV2, tree *fpTree, focus Item, itemizer *Itemizer, frequency *itemCount, minCount int, stats *Stats) ([]itemsetWithCount, error) {
+	if tree.counts.get(focus) < minCount {
+		return make([]itemsetWithCount, 0), nil
+	}
+	itemsets, truncated := growItem(tree, focus, make([]Item, 0), minCount, args.MaxRecursionDepth)
+	stats.ReachedMaxRecursionDepth = truncated
+
+	index := make(map[string]bool)
+	subsets := make([]itemsetWithCount, 0)
+	for _, iwc := range itemsets {
+		subset := setMinus(iwc.itemset, []Item{focus})
+		if key := itemsKey(subset); len(subset) > 0 && !index[key] {
+			index[key] = true
+			subsets = append(subsets, itemsetWithCount{itemset: subset})
+		}
+	}
+	err := forEachTransaction(args, itemizer, func(fields []string, transaction []Item) bool {
+		transaction, ok := limitTransaction(args, transaction, itemizer, frequency, minCount)
+		if !ok {
+			return true
+		}
+		for i := range subsets {
+			if containsItems(transaction, subsets[i].itemset) {
+				subsets[i].count++
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(itemsets, subsets...), nil
+}
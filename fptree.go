@@ -16,6 +16,11 @@
 
 package arm
 
+import (
+	"context"
+	"sync"
+)
+
 type itemToNodeSlice map[Item][]*fpNode
 
 type fpNode struct {
@@ -104,6 +109,30 @@ func pathFromRootToExcluding(node *fpNode) []Item {
 	}
 }
 
+// PatternWithCount is a prefix path of an FP-tree, and the number of
+// transactions which share it.
+type PatternWithCount struct {
+	Pattern []Item
+	Count   int
+}
+
+// ConditionalPatternBase returns the conditional pattern base of item in
+// tree: for each node of item, the path from the root to it, excluding
+// both, with the count of the node. These are the transactions holding
+// item, projected onto the items preceding it in the tree, from which
+// fpGrowth builds the conditional tree of item.
+func (tree *fpTree) ConditionalPatternBase(item Item) []PatternWithCount {
+	nodes := tree.itemList[item]
+	base := make([]PatternWithCount, len(nodes))
+	for i, node := range nodes {
+		base[i] = PatternWithCount{
+			Pattern: pathFromRootToExcluding(node),
+			Count:   node.count,
+		}
+	}
+	return base
+}
+
 func appendSorted(itemset []Item, item Item) []Item {
 	xs := make([]Item, len(itemset)+1)
 	i := 0
@@ -123,24 +152,157 @@ func appendSorted(itemset []Item, item Item) []Item {
 	return xs
 }
 
-func fpGrowth(tree *fpTree, itemset []Item, minCount int) []itemsetWithCount {
+// fpGrowth returns the itemsets, extending itemset, which appear in at
+// least minCount transactions of tree. Items are grown in increasing
+// order, so the itemsets are always returned in the same order. If
+// maxDepth is non-zero, it stops recursing once itemsets have maxDepth
+// items, and reports whether longer itemsets were left out.
+func fpGrowth(tree *fpTree, itemset []Item, minCount int, maxDepth int) ([]itemsetWithCount, bool) {
 	itemsets := make([]itemsetWithCount, 0)
-	for item, itemList := range tree.itemList {
-		if tree.counts.get(item) < minCount {
+	truncated := fpGrowthEach(tree, itemset, minCount, maxDepth, func(iwc itemsetWithCount) {
+		itemsets = append(itemsets, iwc)
+	})
+	return itemsets, truncated
+}
+
+// fpGrowthEach is fpGrowth, but passes the itemsets to fn as it finds
+// them, in the same order, rather than returning them. Only the
+// conditional trees of the itemsets being grown are held in memory.
+func fpGrowthEach(tree *fpTree, itemset []Item, minCount int, maxDepth int, fn func(itemsetWithCount)) bool {
+	truncated := false
+	for idx, count := range tree.counts.counts {
+		if count < minCount {
 			continue
 		}
-		conditionalTree := newTree()
-		for _, leaf := range itemList {
-			transaction := pathFromRootToExcluding(leaf)
-			conditionalTree.Insert(transaction, leaf.count)
+		truncated = growItemEach(tree, Item(idx), itemset, minCount, maxDepth, fn) || truncated
+	}
+	return truncated
+}
+
+// fpGrowthRoot is fpGrowthEach from the root of tree. If progress is
+// set, it is called after the itemsets of each frequent item of the
+// header have been grown, with the number of items done so far and the
+// number of frequent items in total. Once ctx is done, no further items
+// are grown.
+func fpGrowthRoot(ctx context.Context, tree *fpTree, minCount int, maxDepth int, fn func(itemsetWithCount), progress func(done, total int)) bool {
+	frequent := frequentItems(tree, minCount)
+	truncated := false
+	for i, item := range frequent {
+		if ctx.Err() != nil {
+			break
+		}
+		truncated = growItemEach(tree, item, make([]Item, 0), minCount, maxDepth, fn) || truncated
+		if progress != nil {
+			progress(i+1, len(frequent))
+		}
+	}
+	return truncated
+}
+
+// frequentItems returns the items of the header of tree which appear in
+// at least minCount transactions, in increasing order.
+func frequentItems(tree *fpTree, minCount int) []Item {
+	frequent := make([]Item, 0)
+	for idx, count := range tree.counts.counts {
+		if count >= minCount {
+			frequent = append(frequent, Item(idx))
+		}
+	}
+	return frequent
+}
+
+// growItem returns itemset extended with item, which must be frequent in
+// tree, followed by the frequent itemsets which extend that in turn.
+func growItem(tree *fpTree, item Item, itemset []Item, minCount int, maxDepth int) ([]itemsetWithCount, bool) {
+	itemsets := make([]itemsetWithCount, 0)
+	truncated := growItemEach(tree, item, itemset, minCount, maxDepth, func(iwc itemsetWithCount) {
+		itemsets = append(itemsets, iwc)
+	})
+	return itemsets, truncated
+}
+
+// growItemEach is growItem, but passes the itemsets to fn, like
+// fpGrowthEach.
+func growItemEach(tree *fpTree, item Item, itemset []Item, minCount int, maxDepth int, fn func(itemsetWithCount)) bool {
+	conditionalTree := newTree()
+	for _, pattern := range tree.ConditionalPatternBase(item) {
+		conditionalTree.Insert(pattern.Pattern, pattern.Count)
+	}
+	path := appendSorted(itemset, item)
+	fn(itemsetWithCount{
+		itemset: path,
+		count:   conditionalTree.root.count,
+	})
+	if maxDepth > 0 && len(path) >= maxDepth {
+		return hasFrequentItem(conditionalTree, minCount)
+	}
+	return fpGrowthEach(conditionalTree, path, minCount, maxDepth, fn)
+}
+
+// parallelFPGrowth is fpGrowth from the root of tree, with each of workers
+// goroutines growing the itemsets of one frequent item at a time. The
+// itemsets of each item go into the slot of its rank among the frequent
+// items, and the slots are concatenated in rank order, so the output is
+// identical to that of fpGrowth. Progress is reported as in fpGrowthRoot,
+// as the items are done in whichever order the workers finish them. Once
+// ctx is done, the workers grow no further items, and the output is
+// incomplete. If cp is set, items it holds are restored from it rather than
+// grown, and the others are recorded in it once grown.
+func parallelFPGrowth(ctx context.Context, tree *fpTree, minCount int, maxDepth int, workers int, progress func(done, total int), cp *checkpoint) ([]itemsetWithCount, bool) {
+	frequent := frequentItems(tree, minCount)
+	var mu sync.Mutex
+	done := 0
+	slots := make([][]itemsetWithCount, len(frequent))
+	truncated := make([]bool, len(frequent))
+	ranks := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rank := range ranks {
+				if ctx.Err() != nil {
+					continue
+				}
+				restored := false
+				if cp != nil {
+					slots[rank], truncated[rank], restored = cp.itemsets(rank)
+				}
+				if !restored {
+					slots[rank], truncated[rank] = growItem(tree, frequent[rank], nil, minCount, maxDepth)
+					if cp != nil {
+						cp.record(rank, slots[rank], truncated[rank])
+					}
+				}
+				if progress != nil {
+					mu.Lock()
+					done++
+					progress(done, len(frequent))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for rank := range frequent {
+		ranks <- rank
+	}
+	close(ranks)
+	wg.Wait()
+
+	itemsets := make([]itemsetWithCount, 0)
+	anyTruncated := false
+	for rank, slot := range slots {
+		itemsets = append(itemsets, slot...)
+		anyTruncated = anyTruncated || truncated[rank]
+	}
+	return itemsets, anyTruncated
+}
+
+func hasFrequentItem(tree *fpTree, minCount int) bool {
+	for _, count := range tree.counts.counts {
+		if count >= minCount {
+			return true
 		}
-		path := appendSorted(itemset, item)
-		itemsets = append(itemsets, itemsetWithCount{
-			itemset: path,
-			count:   conditionalTree.root.count,
-		})
-		x := fpGrowth(conditionalTree, path, minCount)
-		itemsets = append(itemsets, x...)
-	}
-	return itemsets
+	}
+	return false
 }
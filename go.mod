@@ -1,3 +1,5 @@
 module github.com/nokia/arm-go
 
 go 1.17
+
+require golang.org/x/text v0.13.0
@@ -0,0 +1,94 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// RuleIndex finds rules by their items, for repeated lookups after mining.
+// It is safe for concurrent use, as long as the rules are not modified.
+type RuleIndex struct {
+	itemizer     *Itemizer
+	byAntecedent map[string][]Rule
+	byItem       map[Item][]Rule
+}
+
+// NewRuleIndex indexes rules, whose items itemizer converts from strings.
+func NewRuleIndex(rules []Rule, itemizer *Itemizer) *RuleIndex {
+	idx := &RuleIndex{
+		itemizer:     itemizer,
+		byAntecedent: make(map[string][]Rule),
+		byItem:       make(map[Item][]Rule),
+	}
+	for _, rule := range rules {
+		key := itemsKey(rule.Antecedent)
+		idx.byAntecedent[key] = append(idx.byAntecedent[key], rule)
+		for _, item := range rule.Antecedent {
+			idx.byItem[item] = append(idx.byItem[item], rule)
+		}
+		for _, item := range rule.Consequent {
+			idx.byItem[item] = append(idx.byItem[item], rule)
+		}
+	}
+	return idx
+}
+
+// Index indexes the rules of r.
+func (r *Result) Index() *RuleIndex {
+	return NewRuleIndex(r.Rules, r.Itemizer)
+}
+
+// ByAntecedent returns the rules whose antecedent is exactly items, in any
+// order.
+func (idx *RuleIndex) ByAntecedent(items ...string) []Rule {
+	antecedent := make([]Item, 0, len(items))
+	for _, s := range items {
+		item, found := idx.itemizer.lookup(s)
+		if !found {
+			return nil
+		}
+		antecedent = append(antecedent, item)
+	}
+	sort.Slice(antecedent, func(i, j int) bool {
+		return antecedent[i] < antecedent[j]
+	})
+	return idx.byAntecedent[itemsKey(antecedent)]
+}
+
+// ContainingItem returns the rules with item in either their antecedent or
+// their consequent.
+func (idx *RuleIndex) ContainingItem(item string) []Rule {
+	i, found := idx.itemizer.lookup(item)
+	if !found {
+		return nil
+	}
+	return idx.byItem[i]
+}
+
+// itemsKey encodes sorted items as a map key. Repeated items are encoded
+// once, as a set.
+func itemsKey(items []Item) string {
+	key := make([]byte, len(items)*binary.MaxVarintLen64)
+	n := 0
+	for i, item := range items {
+		if i > 0 && item == items[i-1] {
+			continue
+		}
+		n += binary.PutUvarint(key[n:], uint64(item))
+	}
+	return string(key[:n])
+}
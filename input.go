@@ -0,0 +1,350 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Formats in which transactions can be read, one transaction per line.
+const (
+	// Items separated by commas. This is the default.
+	InputFormatCSV = "csv"
+	// A JSON array of item strings, such as ["milk","bread"]. Items may
+	// contain commas and quotes.
+	InputFormatJSONL = "jsonl"
+	// A binary matrix with a column per item. The first line holds the
+	// item names, separated by commas, and each following line a 1 or 0
+	// per column, for whether the transaction holds the item. Input may
+	// not be a glob pattern.
+	InputFormatOneHot = "onehot"
+)
+
+// ParseError reports a line of the dataset which could not be parsed.
+type ParseError struct {
+	// 1-based number of the line.
+	Line int
+	// The line, shortened if it is long.
+	Raw string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v: %q", e.Line, e.Err, e.Raw)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// maxRawLen is the length beyond which lines are shortened in ParseErrors.
+const maxRawLen = 80
+
+// parser splits the lines of a dataset into the item strings of each
+// transaction. Every pass over the dataset parses it with a parser built
+// from the same arguments, by scanTransactions.
+type parser struct {
+	format        string
+	skipMalformed bool
+	// 0-based index of the field holding the transaction ID, or -1.
+	idField int
+	// 0-based index of the field holding the class label, which is moved
+	// first, or -1.
+	labelField int
+	// 1-based columns holding items, or nil for all of them.
+	columns []int
+	// Splits lines of the csv and onehot formats into fields, if set.
+	splitFunc func(string) []string
+	// Skips the lines whose fields it rejects, if set.
+	filter func([]string) bool
+	// Logs skipped lines, if set. Only the first pass sets it, so that
+	// each line is logged once.
+	log Logger
+	// Number of the last line parsed.
+	line int
+	// Number of malformed lines skipped.
+	skipped int
+	// Item names of the columns of onehot input, once its first line is
+	// parsed.
+	header []string
+}
+
+func newParser(args ArgumentsV2, log Logger) *parser {
+	return &parser{
+		format:        args.InputFormat,
+		skipMalformed: args.SkipMalformed,
+		idField:       max(args.IDColumn, 0) - 1,
+		labelField:    args.labelColumn() - 1,
+		columns:       args.MineColumns,
+		splitFunc:     args.FieldSplitFunc,
+		filter:        args.TransactionFilter,
+		log:           log,
+	}
+}
+
+// parse returns the item strings of the transaction on the next line. A
+// malformed line is reported as a *ParseError or, with SkipMalformed,
+// skipped, in which case parse returns false. So is a line the filter
+// rejects, though the header of onehot input is never filtered.
+func (p *parser) parse(line string) ([]string, bool, error) {
+	p.line++
+	fields, err := p.split(line)
+	if err == nil && p.filter != nil && (p.format != InputFormatOneHot || p.header != nil) && !p.filter(fields) {
+		return nil, false, nil
+	}
+	if err == nil {
+		fields, err = p.project(fields)
+	}
+	if err == nil {
+		if p.format != InputFormatOneHot {
+			return fields, true, nil
+		}
+		if p.header == nil {
+			p.header = fields
+			return nil, false, nil
+		}
+		if fields, err = p.oneHot(fields); err == nil {
+			return fields, true, nil
+		}
+	}
+	raw := line
+	if len(raw) > maxRawLen {
+		raw = raw[:maxRawLen] + "..."
+	}
+	perr := &ParseError{Line: p.line, Raw: raw, Err: err}
+	if !p.skipMalformed {
+		return nil, false, perr
+	}
+	p.skipped++
+	if p.log != nil {
+		p.log.Printf("Skipping malformed %v", perr)
+	}
+	return nil, false, nil
+}
+
+// scanTransactions reads the dataset of args and calls fn with the item
+// strings of each transaction, until fn returns false. Every pass over the
+// dataset reads it this way, so that they all see the same transactions.
+// Skipped malformed lines are logged to log, if set, and counted.
+func scanTransactions(args ArgumentsV2, log Logger, fn func([]string) bool) (int, error) {
+	file, err := args.ItemsReader()
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	parser := newParser(args, log)
+	scanner := newScanner(file, args.MaxLineBytes)
+	for scanner.Scan() {
+		fields, ok, err := parser.parse(scanner.Text())
+		if err != nil {
+			return parser.skipped, err
+		}
+		if ok && !fn(fields) {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return parser.skipped, parser.scanError(err)
+	}
+	return parser.skipped, nil
+}
+
+// scanError reports an error reading the line after the last one parsed.
+func (p *parser) scanError(err error) error {
+	if errors.Is(err, bufio.ErrTooLong) {
+		err = fmt.Errorf("%w; MaxLineBytes allows longer lines", err)
+	}
+	return &ParseError{Line: p.line + 1, Err: err}
+}
+
+// newScanner returns a scanner of the lines of r, which fails on lines of
+// more than maxLineBytes bytes or, if that is 0, of bufio.MaxScanTokenSize.
+func newScanner(r io.Reader, maxLineBytes int) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	if maxLineBytes > 0 {
+		scanner.Buffer(make([]byte, 0, min(maxLineBytes, bufio.MaxScanTokenSize)), maxLineBytes)
+	}
+	return scanner
+}
+
+// project returns the fields of the columns holding items, with the label
+// first if there is a label column, whether or not it is among the columns.
+func (p *parser) project(fields []string) ([]string, error) {
+	if p.labelField >= 0 {
+		if p.labelField >= len(fields) {
+			return nil, fmt.Errorf("label column %d out of range, line has %d columns", p.labelField+1, len(fields))
+		}
+		projected := []string{fields[p.labelField]}
+		if p.columns == nil {
+			for i, field := range fields {
+				if i != p.labelField && i != p.idField {
+					projected = append(projected, field)
+				}
+			}
+			return projected, nil
+		}
+		for _, column := range p.columns {
+			if column > len(fields) {
+				return nil, fmt.Errorf("column %d out of range, line has %d columns", column, len(fields))
+			}
+			if column-1 != p.idField && column-1 != p.labelField {
+				projected = append(projected, fields[column-1])
+			}
+		}
+		return projected, nil
+	}
+	if p.columns == nil {
+		if p.idField >= 0 && p.idField < len(fields) {
+			// fields may be the caller's, e.g. from a FieldSplitFunc.
+			projected := make([]string, 0, len(fields)-1)
+			projected = append(projected, fields[:p.idField]...)
+			return append(projected, fields[p.idField+1:]...), nil
+		}
+		return fields, nil
+	}
+	projected := make([]string, 0, len(p.columns))
+	for _, column := range p.columns {
+		if column > len(fields) {
+			return nil, fmt.Errorf("column %d out of range, line has %d columns", column, len(fields))
+		}
+		if column-1 != p.idField {
+			projected = append(projected, fields[column-1])
+		}
+	}
+	return projected, nil
+}
+
+// oneHot converts a row of onehot input to the names of the items it
+// holds.
+func (p *parser) oneHot(row []string) ([]string, error) {
+	if len(row) != len(p.header) {
+		return nil, fmt.Errorf("expected %d columns, got %d", len(p.header), len(row))
+	}
+	items := make([]string, 0)
+	for i, value := range row {
+		switch strings.TrimSpace(value) {
+		case "1":
+			items = append(items, p.header[i])
+		case "0", "":
+		default:
+			return nil, fmt.Errorf("expected 0 or 1 in column %d", i+1)
+		}
+	}
+	return items, nil
+}
+
+func (p *parser) split(line string) ([]string, error) {
+	switch p.format {
+	case InputFormatJSONL:
+		if strings.TrimSpace(line) == "" {
+			return nil, nil
+		}
+		var fields []string
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			return nil, err
+		}
+		return fields, nil
+	default:
+		if p.splitFunc != nil {
+			return p.splitFunc(line), nil
+		}
+		return strings.Split(line, ","), nil
+	}
+}
+
+// inputFiles returns the files pattern matches, sorted, if it is a glob
+// pattern, or pattern itself otherwise.
+func inputFiles(pattern string) ([]string, error) {
+	if !isGlob(pattern) {
+		return []string{pattern}, nil
+	}
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInputMatchesNoFiles, pattern)
+	}
+	return paths, nil
+}
+
+// isGlob reports whether pattern is a glob pattern rather than a path.
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// openInputs opens the concatenation of the files at paths. Each file is
+// opened once the previous one is read, and a newline is added after any
+// file which does not end with one, so that no line spans two files.
+func openInputs(paths []string) (io.ReadCloser, error) {
+	if len(paths) == 1 {
+		return os.Open(paths[0])
+	}
+	file, err := os.Open(paths[0])
+	if err != nil {
+		return nil, err
+	}
+	return &multiFile{paths: paths[1:], file: file, last: '\n'}, nil
+}
+
+type multiFile struct {
+	// Paths of the files not opened yet.
+	paths []string
+	file  *os.File
+	// Last byte read from file.
+	last byte
+}
+
+func (m *multiFile) Read(p []byte) (int, error) {
+	for m.file != nil {
+		n, err := m.file.Read(p)
+		if n > 0 {
+			m.last = p[n-1]
+			return n, nil
+		}
+		if err != io.EOF {
+			return 0, err
+		}
+		if m.last != '\n' && len(p) > 0 {
+			m.last = '\n'
+			p[0] = '\n'
+			return 1, nil
+		}
+		m.file.Close()
+		m.file = nil
+		if len(m.paths) > 0 {
+			if m.file, err = os.Open(m.paths[0]); err != nil {
+				return 0, err
+			}
+			m.paths = m.paths[1:]
+		}
+	}
+	return 0, io.EOF
+}
+
+func (m *multiFile) Close() error {
+	if m.file == nil {
+		return nil
+	}
+	return m.file.Close()
+}
@@ -0,0 +1,332 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCountItemsJSONL(t *testing.T) {
+	args := ArgumentsV2{
+		ItemsReader: readerOf(`["milk","bread, sliced"]` + "\n\n" + `["bread, sliced","say \"cheese\""]` + "\n"),
+		InputFormat: InputFormatJSONL,
+	}
+	itemizer, frequency, numTransactions, err := countItems(args, &Stats{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numTransactions != 3 {
+		t.Errorf("expected 3 transactions, got %d", numTransactions)
+	}
+	for str, expected := range map[string]int{"milk": 1, "bread, sliced": 2, `say "cheese"`: 1} {
+		if got := frequency.get(itemizer.strToItem[str]); got != expected {
+			t.Errorf("expected count(%s)=%d, got %d", str, expected, got)
+		}
+	}
+
+	args.ItemsReader = readerOf("milk,bread\n")
+	if _, _, _, err := countItems(args, &Stats{}, nil); err == nil {
+		t.Error("expected error parsing CSV as JSON Lines")
+	}
+}
+
+func TestCountItemsFieldSplitFunc(t *testing.T) {
+	var split [][]string
+	args := ArgumentsV2{
+		ItemsReader: readerOf("t1\tmilk,bread\nt2\tbread,eggs\n"),
+		IDColumn:    1,
+		FieldSplitFunc: func(line string) []string {
+			fields := strings.FieldsFunc(line, func(r rune) bool { return r == '\t' || r == ',' })
+			split = append(split, fields)
+			return fields
+		},
+	}
+	itemizer, frequency, numTransactions, err := countItems(args, &Stats{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numTransactions != 2 {
+		t.Errorf("expected 2 transactions, got %d", numTransactions)
+	}
+	for str, expected := range map[string]int{"milk": 1, "bread": 2, "eggs": 1, "t1": 0} {
+		if got := frequency.get(itemizer.strToItem[str]); got != expected {
+			t.Errorf("expected count(%s)=%d, got %d", str, expected, got)
+		}
+	}
+	expected := [][]string{{"t1", "milk", "bread"}, {"t2", "bread", "eggs"}}
+	if !reflect.DeepEqual(split, expected) {
+		t.Errorf("expected FieldSplitFunc results %v to be left intact, got %v", expected, split)
+	}
+}
+
+func TestParseErrorLine(t *testing.T) {
+	input := `["milk"]` + "\n" + `["bread",` + "\n" + `["eggs"]` + "\n"
+	args := ArgumentsV2{ItemsReader: readerOf(input), InputFormat: InputFormatJSONL}
+	_, _, _, err := countItems(args, &Stats{}, nil)
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected *ParseError, got %v", err)
+	}
+	if perr.Line != 2 || perr.Raw != `["bread",` {
+		t.Errorf("expected line 2, got %d %q", perr.Line, perr.Raw)
+	}
+
+	args.SkipMalformed = true
+	var logged strings.Builder
+	var stats Stats
+	_, _, numTransactions, err := countItems(args, &stats, log.New(&logged, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numTransactions != 2 || stats.NumMalformedLines != 1 {
+		t.Errorf("expected 2 transactions and 1 malformed line, got %d and %d", numTransactions, stats.NumMalformedLines)
+	}
+	if !strings.Contains(logged.String(), "line 2") {
+		t.Errorf("expected skipped line to be logged, got %q", logged.String())
+	}
+}
+
+func TestCountItemsIDColumn(t *testing.T) {
+	args := ArgumentsV2{
+		ItemsReader: readerOf("1001,milk,bread\n1002,milk\n"),
+		IDColumn:    1,
+	}
+	itemizer, frequency, _, err := countItems(args, &Stats{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := itemizer.lookup("1001"); found {
+		t.Error("expected the transaction ID not to be an item")
+	}
+	if got := frequency.get(itemizer.strToItem["milk"]); got != 2 {
+		t.Errorf("expected count(milk)=2, got %d", got)
+	}
+
+	args.ItemsReader = readerOf("1001,milk,bread\n1002,milk\n")
+	args.IDColumn = -1
+	itemizer, _, _, err = countItems(args, &Stats{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := itemizer.lookup("1001"); !found {
+		t.Error("expected IDColumn -1 to mine every column")
+	}
+}
+
+func TestCountItemsLabelColumn(t *testing.T) {
+	args := ArgumentsV2{
+		ItemsReader:    readerOf("milk,yes,bread\neggs,no\n"),
+		LabelColumn:    2,
+		ClassRulesOnly: true,
+	}
+	itemizer, frequency, _, err := countItems(args, &Stats{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, label := range []string{"yes", "no"} {
+		if !itemizer.labels[itemizer.strToItem[label]] {
+			t.Errorf("expected %s to be a label", label)
+		}
+	}
+	if itemizer.labels[itemizer.strToItem["milk"]] {
+		t.Error("expected milk not to be a label")
+	}
+	if got := frequency.get(itemizer.strToItem["yes"]); got != 1 {
+		t.Errorf("expected count(yes)=1, got %d", got)
+	}
+}
+
+func TestCountItemsOneHot(t *testing.T) {
+	args := ArgumentsV2{
+		ItemsReader: readerOf("id,milk,bread,eggs\n1,1,1,0\n2,1,0,0\n3,0,1,1\n"),
+		InputFormat: InputFormatOneHot,
+		IDColumn:    1,
+	}
+	itemizer, frequency, numTransactions, err := countItems(args, &Stats{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numTransactions != 3 {
+		t.Errorf("expected 3 transactions, got %d", numTransactions)
+	}
+	for item, expected := range map[string]int{"milk": 2, "bread": 2, "eggs": 1} {
+		if got := frequency.get(itemizer.strToItem[item]); got != expected {
+			t.Errorf("expected count(%s)=%d, got %d", item, expected, got)
+		}
+	}
+
+	args.ItemsReader = readerOf("milk,bread\n1,1\n1,2\n")
+	args.IDColumn = 0
+	_, _, _, err = countItems(args, &Stats{}, nil)
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Line != 3 {
+		t.Errorf("expected a ParseError on line 3, got %v", err)
+	}
+}
+
+func TestCountItemsMineColumns(t *testing.T) {
+	args := ArgumentsV2{
+		ItemsReader: readerOf("1001,2023-01-01,milk,bread\n1002,2023-01-02,milk,eggs\n"),
+		IDColumn:    1,
+		MineColumns: []int{1, 3, 4},
+	}
+	itemizer, frequency, _, err := countItems(args, &Stats{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ignored := range []string{"1001", "2023-01-01"} {
+		if _, found := itemizer.lookup(ignored); found {
+			t.Errorf("expected %s not to be an item", ignored)
+		}
+	}
+	if got := frequency.get(itemizer.strToItem["milk"]); got != 2 {
+		t.Errorf("expected count(milk)=2, got %d", got)
+	}
+
+	args.ItemsReader = readerOf("1001,2023-01-01,milk,bread\n1002,2023-01-02,milk\n")
+	_, _, _, err = countItems(args, &Stats{}, nil)
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Line != 2 {
+		t.Errorf("expected a ParseError on line 2, got %v", err)
+	}
+}
+
+func TestCountItemsPivot(t *testing.T) {
+	args := ArgumentsV2{
+		ItemsReader: readerOf("user=1 action=buy item=milk\nuser=1 item=bread\n" +
+			"action=login\nuser=2 item=milk\nuser=3 action=view\nuser=1 item=eggs item=milk\n"),
+		PivotKey:   "item",
+		PivotGroup: "user",
+	}
+	args.pivot()
+	itemizer, frequency, numTransactions, err := countItems(args, &Stats{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// User 1 reappears after the others, so their last line is another
+	// transaction, and user 3 has no items.
+	if numTransactions != 4 {
+		t.Errorf("expected 4 transactions, got %d", numTransactions)
+	}
+	for item, expected := range map[string]int{"milk": 3, "bread": 1, "eggs": 1} {
+		if got := frequency.get(itemizer.strToItem[item]); got != expected {
+			t.Errorf("expected count(%s)=%d, got %d", item, expected, got)
+		}
+	}
+	if _, found := itemizer.lookup("buy"); found {
+		t.Error("expected values of other keys not to be items")
+	}
+}
+
+func TestCountItemsMaxLineBytes(t *testing.T) {
+	// A onehot row of 50000 columns is about 100KB, longer than
+	// bufio.Scanner allows by default.
+	header := make([]string, 50000)
+	row := make([]string, len(header))
+	for i := range header {
+		header[i] = "i" + strconv.Itoa(i)
+		row[i] = "1"
+	}
+	data := strings.Join(header, ",") + "\n" + strings.Join(row, ",") + "\n"
+	args := ArgumentsV2{
+		ItemsReader: readerOf(data),
+		InputFormat: InputFormatOneHot,
+	}
+	_, _, _, err := countItems(args, &Stats{}, nil)
+	var perr *ParseError
+	if !errors.As(err, &perr) || !errors.Is(err, bufio.ErrTooLong) {
+		t.Fatalf("expected a ParseError for a long line, got %v", err)
+	}
+
+	args.ItemsReader = readerOf(data)
+	args.MaxLineBytes = 1 << 20
+	_, frequency, numTransactions, err := countItems(args, &Stats{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numTransactions != 1 || frequency.max() != 1 {
+		t.Errorf("expected 1 transaction, got %d", numTransactions)
+	}
+}
+
+func TestMaxItemsPerTransactionConsistent(t *testing.T) {
+	// The long transaction is truncated to a and b, the most frequent items
+	// of the others, so c stays below MinSupport in both passes.
+	data := "a,b\na,b\na,b\nc\na\nc,d,b,a\n"
+	args := ArgumentsV2{
+		ItemsReader:            readerOf(data),
+		MinSupport:             0.3,
+		MaxItemsPerTransaction: 2,
+	}
+	stats := Stats{}
+	itemizer, frequency, numTransactions, err := countItems(args, &stats, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numTransactions != 6 || stats.NumLongTransactions != 1 {
+		t.Errorf("expected 6 transactions, 1 long, got %d, %d", numTransactions, stats.NumLongTransactions)
+	}
+	for item, expected := range map[string]int{"a": 5, "b": 4, "c": 1, "d": 0} {
+		if got := frequency.get(itemizer.strToItem[item]); got != expected {
+			t.Errorf("expected count(%s)=%d, got %d", item, expected, got)
+		}
+	}
+
+	itemsets := func(result *Result) map[string]int {
+		counts := make(map[string]int)
+		for _, iwc := range result.itemsets {
+			counts[result.Itemizer.join(iwc.itemset, " ")] = iwc.count
+		}
+		return counts
+	}
+	expected := map[string]int{"a": 5, "b": 4, "a b": 4}
+	logger := log.New(io.Discard, "", 0)
+	result, err := Mine(args, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := itemsets(result); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected itemsets %v, got %v", expected, got)
+	}
+	single := args
+	single.SingleItemOnly = true
+	result, err = Mine(single, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := itemsets(result); got["a"] != 5 || got["b"] != 4 || len(got) != 2 {
+		t.Errorf("expected the same single items with SingleItemOnly, got %v", got)
+	}
+
+	d := NewDataset(ArgumentsV2{})
+	if err := d.InsertTransactions(readerOf(data)); err != nil {
+		t.Fatal(err)
+	}
+	args.ItemsReader = nil
+	result, err = d.Mine(args, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := itemsets(result); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected the same itemsets from a Dataset, got %v", got)
+	}
+}
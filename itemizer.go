@@ -16,10 +16,19 @@
 
 package arm
 
-import "strings"
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 type itemCount struct {
 	counts []int
+	// The counts of the transactions which are not long, by which long
+	// transactions are truncated, if there are any.
+	short *itemCount
 }
 
 func makeCounts() itemCount {
@@ -40,6 +49,29 @@ func (ic *itemCount) increment(item Item, count int) {
 	ic.counts[idx] += count
 }
 
+// add adds the counts in other to ic, translating other's items through
+// mapping.
+func (ic *itemCount) add(other *itemCount, mapping map[Item]Item) {
+	for idx, count := range other.counts {
+		if count != 0 {
+			ic.increment(mapping[Item(idx)], count)
+		}
+	}
+}
+
+func (ic *itemCount) clone() itemCount {
+	return itemCount{counts: append([]int(nil), ic.counts...), short: ic.short}
+}
+
+// max returns the highest count of any item.
+func (ic *itemCount) max() int {
+	m := 0
+	for _, count := range ic.counts {
+		m = max(m, count)
+	}
+	return m
+}
+
 func (ic *itemCount) get(item Item) int {
 	idx := int(item)
 	if idx >= len(ic.counts) {
@@ -53,6 +85,19 @@ type Itemizer struct {
 	strToItem map[string]Item
 	itemToStr map[Item]string
 	numItems  int
+	// If set, strings are matched to items by their folded form, and
+	// each item is converted back to the first string seen for it.
+	fold func(string) string
+	// Maps the key of an alias to the canonical string it stands for.
+	aliases map[string]string
+	// If set, strings starting with ! are negations of the rest.
+	negation bool
+	// If set, runs of whitespace within strings become a single space.
+	collapse bool
+	// If set, decimal numbers are written in their shortest form.
+	numeric bool
+	// Items read from the label column, with ClassRulesOnly.
+	labels map[Item]bool
 }
 
 // Itemize converts a slice of strings to a slice of Items.
@@ -66,6 +111,68 @@ func (it *Itemizer) Itemize(values []string) []Item {
 	return items[:j]
 }
 
+// Merge adds the items of other which it does not know yet, and returns
+// a mapping from each of other's items to the corresponding item of it.
+// Items are matched by their string, so the two Itemizers may have
+// assigned different items to the same string.
+func (it *Itemizer) Merge(other *Itemizer) map[Item]Item {
+	mapping := make(map[Item]Item, len(other.itemToStr))
+	for item, str := range other.itemToStr {
+		mapping[item] = it.add(str)
+	}
+	return mapping
+}
+
+func (it *Itemizer) clone() *Itemizer {
+	c := newItemizer()
+	for key, item := range it.strToItem {
+		c.strToItem[key] = item
+	}
+	for item, str := range it.itemToStr {
+		c.itemToStr[item] = str
+	}
+	c.numItems = it.numItems
+	c.fold = it.fold
+	c.aliases = it.aliases
+	c.negation = it.negation
+	c.collapse = it.collapse
+	c.numeric = it.numeric
+	if it.labels != nil {
+		c.labels = make(map[Item]bool, len(it.labels))
+		for item := range it.labels {
+			c.labels[item] = true
+		}
+	}
+	return &c
+}
+
+// itemizeLabelled is Itemize, but also records the item of the first of
+// values as a label, if there is a label column, which the parser moves
+// first.
+func (it *Itemizer) itemizeLabelled(values []string, args ArgumentsV2) []Item {
+	items := it.Itemize(values)
+	if args.labelColumn() > 0 && len(values) > 0 && strings.TrimSpace(values[0]) != "" {
+		if it.labels == nil {
+			it.labels = make(map[Item]bool)
+		}
+		it.labels[items[0]] = true
+	}
+	return items
+}
+
+// withIDs returns an Itemizer with the same items, which converts each
+// item to the decimal string of its ID.
+func (it *Itemizer) withIDs() *Itemizer {
+	c := newItemizer()
+	for item := range it.itemToStr {
+		id := strconv.Itoa(int(item))
+		c.strToItem[id] = item
+		c.itemToStr[item] = id
+	}
+	c.numItems = it.numItems
+	return &c
+}
+
 func (it *Itemizer) toStr(item Item) string {
 	s, found := it.itemToStr[item]
 	if !found {
@@ -74,14 +181,25 @@ func (it *Itemizer) toStr(item Item) string {
 	return s
 }
 
-func (it *Itemizer) filter(tokens []string, filter func(Item) bool) []Item {
-	items := make([]Item, 0, len(tokens))
-	it.forEachItem(tokens, func(i Item) {
-		if filter(i) {
-			items = append(items, i)
+// join converts items to strings and joins them with sep.
+func (it *Itemizer) join(items []Item, sep string) string {
+	var b strings.Builder
+	for i, item := range items {
+		if i > 0 {
+			b.WriteString(sep)
 		}
-	})
-	return items
+		b.WriteString(it.toStr(item))
+	}
+	return b.String()
+}
+
+// strings converts items to strings.
+func (it *Itemizer) strings(items []Item) []string {
+	strs := make([]string, len(items))
+	for i, item := range items {
+		strs[i] = it.toStr(item)
+	}
+	return strs
 }
 
 func (it *Itemizer) forEachItem(tokens []string, fn func(Item)) {
@@ -90,15 +208,99 @@ func (it *Itemizer) forEachItem(tokens []string, fn func(Item)) {
 		if len(val) == 0 {
 			continue
 		}
-		itemID, found := it.strToItem[val]
-		if !found {
-			it.numItems++
-			itemID = Item(it.numItems)
-			it.strToItem[val] = itemID
-			it.itemToStr[itemID] = val
+		fn(it.add(it.canonical(val)))
+	}
+}
+
+// add returns the item for val, assigning a new one if val is unknown.
+func (it *Itemizer) add(val string) Item {
+	key := it.key(val)
+	itemID, found := it.strToItem[key]
+	if !found {
+		it.numItems++
+		itemID = Item(it.numItems)
+		it.strToItem[key] = itemID
+		it.itemToStr[itemID] = val
+	}
+	return itemID
+}
+
+// lookup returns the item for val, if there is one.
+func (it *Itemizer) lookup(val string) (Item, bool) {
+	item, found := it.strToItem[it.key(it.canonical(val))]
+	return item, found
+}
+
+// canonical returns the string val is an alias of, or val if it is not an
+// alias. A negation is the negation of the canonical string of the rest.
+func (it *Itemizer) canonical(val string) string {
+	if it.collapse {
+		val = collapseSpace(val)
+	}
+	if it.numeric {
+		val = canonicalNumber(val)
+	}
+	if it.negation && len(val) > 1 && val[0] == '!' {
+		negated := it.canonical(strings.TrimSpace(val[1:]))
+		if len(negated) > 1 && negated[0] == '!' {
+			return negated[1:]
 		}
-		fn(itemID)
+		return "!" + negated
+	}
+	if c, found := it.aliases[it.key(val)]; found {
+		return c
+	}
+	return val
+}
+
+// collapseSpace replaces each run of whitespace in val with a single space,
+// and removes leading and trailing whitespace.
+func collapseSpace(val string) string {
+	return strings.Join(strings.Fields(val), " ")
+}
+
+// canonicalNumber returns val in its shortest form if it is a decimal
+// number, such as "1" for "1.0", "01" or "+1.00", and "0" for "-0", or val
+// itself otherwise. Digits are never rounded, so distinct numbers stay
+// distinct however long they are.
+func canonicalNumber(val string) string {
+	if !decimalNumber.MatchString(val) {
+		return val
+	}
+	sign := ""
+	switch val[0] {
+	case '-':
+		sign = "-"
+		val = val[1:]
+	case '+':
+		val = val[1:]
+	}
+	whole, fraction := val, ""
+	if i := strings.IndexByte(val, '.'); i >= 0 {
+		whole, fraction = val[:i], val[i+1:]
+	}
+	whole = strings.TrimLeft(whole, "0")
+	fraction = strings.TrimRight(fraction, "0")
+	if whole == "" {
+		whole = "0"
+	}
+	if whole == "0" && fraction == "" {
+		return "0"
+	}
+	if fraction != "" {
+		return sign + whole + "." + fraction
 	}
+	return sign + whole
+}
+
+// decimalNumber matches decimal numbers without an exponent.
+var decimalNumber = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)$`)
+
+func (it *Itemizer) key(val string) string {
+	if it.fold == nil {
+		return val
+	}
+	return it.fold(val)
 }
 
 func (it *Itemizer) cmp(a Item, b Item) bool {
@@ -112,3 +314,59 @@ func newItemizer() Itemizer {
 		numItems:  0,
 	}
 }
+
+// itemizerFor returns an Itemizer which normalizes items as args ask.
+func itemizerFor(args ArgumentsV2) Itemizer {
+	it := newItemizer()
+	it.negation = args.RespectNegation
+	it.collapse = args.CollapseWhitespace
+	it.numeric = args.NumericItemPolicy == NumericItemCanonical
+	if args.ClassRulesOnly {
+		it.labels = make(map[Item]bool)
+	}
+	if args.NormalizeUnicode {
+		it.fold = foldUnicode()
+	}
+	if len(args.Aliases) > 0 {
+		it.aliases = make(map[string]string, len(args.Aliases))
+		for alias, c := range args.Aliases {
+			alias, c = strings.TrimSpace(alias), strings.TrimSpace(c)
+			if it.collapse {
+				alias, c = collapseSpace(alias), collapseSpace(c)
+			}
+			if it.numeric {
+				alias, c = canonicalNumber(alias), canonicalNumber(c)
+			}
+			it.aliases[it.key(alias)] = c
+		}
+	}
+	return it
+}
+
+// MarshalJSON encodes the Itemizer as an array of the item strings, where
+// the string for item i is at index i-1.
+func (it *Itemizer) MarshalJSON() ([]byte, error) {
+	strs := make([]string, it.numItems)
+	for item, str := range it.itemToStr {
+		strs[item-1] = str
+	}
+	return json.Marshal(strs)
+}
+
+// UnmarshalJSON decodes an Itemizer encoded by MarshalJSON, so that each
+// string maps to the same item as before.
+func (it *Itemizer) UnmarshalJSON(data []byte) error {
+	var strs []string
+	if err := json.Unmarshal(data, &strs); err != nil {
+		return err
+	}
+	decoded := newItemizer()
+	for _, str := range strs {
+		if _, found := decoded.strToItem[str]; found {
+			return fmt.Errorf("duplicate item %q in Itemizer", str)
+		}
+		decoded.add(str)
+	}
+	*it = decoded
+	return nil
+}
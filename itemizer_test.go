@@ -0,0 +1,151 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestItemizerJSON(t *testing.T) {
+	itemizer := newItemizer()
+	items := itemizer.Itemize([]string{"milk", "bread", "eggs"})
+	data, err := json.Marshal(&itemizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `["milk","bread","eggs"]` {
+		t.Errorf("unexpected encoding %s", data)
+	}
+
+	var decoded Itemizer
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !itemSliceEquals(decoded.Itemize([]string{"milk", "bread", "eggs"}), items) {
+		t.Error("decoded Itemizer maps strings to different items")
+	}
+	for _, a := range items {
+		for _, b := range items {
+			if decoded.cmp(a, b) != itemizer.cmp(a, b) {
+				t.Errorf("decoded Itemizer orders %d and %d differently", a, b)
+			}
+		}
+	}
+	if next := decoded.Itemize([]string{"jam"}); next[0] != Item(4) {
+		t.Errorf("expected new item 4, got %d", next[0])
+	}
+
+	if err := json.Unmarshal([]byte(`["milk","milk"]`), &decoded); err == nil {
+		t.Error("expected error decoding duplicate items")
+	}
+}
+
+func TestItemizerNormalizeUnicode(t *testing.T) {
+	itemizer := itemizerFor(ArgumentsV2{NormalizeUnicode: true})
+	items := itemizer.Itemize([]string{"Café", "CAFE", "cafe", "ﬁg", "fig", "tea"})
+	expected := []Item{1, 1, 1, 2, 2, 3}
+	if !itemSliceEquals(items, expected) {
+		t.Fatalf("expected items %v, got %v", expected, items)
+	}
+	if s := itemizer.toStr(1); s != "Café" {
+		t.Errorf("expected first seen form Café, got %s", s)
+	}
+	if item, found := itemizer.lookup("CAFÉ"); !found || item != 1 {
+		t.Errorf("expected CAFÉ to find item 1, got %d %v", item, found)
+	}
+
+	plain := newItemizer()
+	if items := plain.Itemize([]string{"Café", "cafe"}); items[0] == items[1] {
+		t.Error("expected items to differ without NormalizeUnicode")
+	}
+}
+
+func TestItemizerCollapseWhitespace(t *testing.T) {
+	itemizer := itemizerFor(ArgumentsV2{
+		CollapseWhitespace: true,
+		Aliases:            map[string]string{"blue  shirt": "shirt"},
+	})
+	items := itemizer.Itemize([]string{"red   shirt", "red shirt", "red\t shirt", "blue shirt", "shirt"})
+	expected := []Item{1, 1, 1, 2, 2}
+	if !itemSliceEquals(items, expected) {
+		t.Fatalf("expected items %v, got %v", expected, items)
+	}
+	if s := itemizer.toStr(1); s != "red shirt" {
+		t.Errorf("expected collapsed form \"red shirt\", got %q", s)
+	}
+	if item, found := itemizer.lookup("red  shirt"); !found || item != 1 {
+		t.Errorf("expected lookup to collapse whitespace, got %v %v", item, found)
+	}
+}
+
+func TestItemizerNumericItemPolicy(t *testing.T) {
+	values := []string{"1", "1.0", "01", "+1.00", "0", "00", "-0.0", ".50", "0.5", "1e3", "1.0.0", "12345678901234567890.10"}
+	literal := itemizerFor(ArgumentsV2{})
+	if items := literal.Itemize(values); literal.numItems != len(values) {
+		t.Errorf("expected every literal to be its own item, got %v", items)
+	}
+	itemizer := itemizerFor(ArgumentsV2{NumericItemPolicy: NumericItemCanonical})
+	items := itemizer.Itemize(values)
+	expected := []Item{1, 1, 1, 1, 2, 2, 2, 3, 3, 4, 5, 6}
+	if !itemSliceEquals(items, expected) {
+		t.Fatalf("expected items %v, got %v", expected, items)
+	}
+	for item, str := range map[Item]string{1: "1", 2: "0", 3: "0.5", 4: "1e3", 6: "12345678901234567890.1"} {
+		if s := itemizer.toStr(item); s != str {
+			t.Errorf("expected item %d to be %q, got %q", item, str, s)
+		}
+	}
+}
+
+func TestItemizerAliases(t *testing.T) {
+	itemizer := itemizerFor(ArgumentsV2{
+		Aliases: map[string]string{"coke": "coca-cola", "cocacola": "coca-cola"},
+	})
+	items := itemizer.Itemize([]string{"coke", "pepsi", " cocacola ", "coca-cola"})
+	expected := []Item{1, 2, 1, 1}
+	if !itemSliceEquals(items, expected) {
+		t.Fatalf("expected items %v, got %v", expected, items)
+	}
+	if s := itemizer.toStr(1); s != "coca-cola" {
+		t.Errorf("expected canonical name coca-cola, got %s", s)
+	}
+	if item, found := itemizer.lookup("coke"); !found || item != 1 {
+		t.Errorf("expected coke to find item 1, got %d %v", item, found)
+	}
+}
+
+func TestItemizerRespectNegation(t *testing.T) {
+	itemizer := itemizerFor(ArgumentsV2{
+		RespectNegation:  true,
+		NormalizeUnicode: true,
+		Aliases:          map[string]string{"whole milk": "milk"},
+	})
+	items := itemizer.Itemize([]string{"milk", "!milk", "! Whole Milk", "!!milk", "!"})
+	expected := []Item{1, 2, 2, 1, 3}
+	if !itemSliceEquals(items, expected) {
+		t.Fatalf("expected items %v, got %v", expected, items)
+	}
+	if s := itemizer.toStr(2); s != "!milk" {
+		t.Errorf("expected negation !milk, got %s", s)
+	}
+
+	plain := itemizerFor(ArgumentsV2{Aliases: map[string]string{"whole milk": "milk"}})
+	items = plain.Itemize([]string{"!milk", "! whole milk", "!!milk"})
+	expected = []Item{1, 2, 3}
+	if !itemSliceEquals(items, expected) {
+		t.Errorf("expected items %v without RespectNegation, got %v", expected, items)
+	}
+}
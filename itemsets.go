@@ -130,7 +130,6 @@ func setMinus(a []Item, b []Item) []Item {
 			ai++
 		} else if b[bi] < a[ai] {
 			panic("Tried to remove item that's not in set!")
-			bi++
 		} else {
 			ai++
 			bi++
@@ -20,6 +20,7 @@ package arm
 import (
 	"io"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -120,8 +121,8 @@ func TestFPGrowth(t *testing.T) {
 	input := func() (io.ReadCloser, error) {
 		return os.Open("datasets/kosarak.csv")
 	}
-	itemizer, frequency, numTransactions, _ := countItems(input)
-	itemsets, _ := generateFrequentItemsets(input, 0.05, itemizer, frequency, numTransactions)
+	itemizer, frequency, numTransactions, _ := countItems(ArgumentsV2{ItemsReader: input}, &Stats{}, nil)
+	itemsets, _ := generateFrequentItemsets(ArgumentsV2{ItemsReader: input, MinSupport: 0.05}, itemizer, frequency, numTransactions, &Stats{})
 
 	if len(itemsets) != len(expectedItemsets) {
 		t.Error("Result=")
@@ -150,3 +151,96 @@ func TestSetMinus(t *testing.T) {
 		}
 	}
 }
+
+func readerOf(data string) ItemsReader {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(data)), nil
+	}
+}
+
+func TestFPGrowthItemOrder(t *testing.T) {
+	input := readerOf("a,b,c\na,b\nb,c,d\na,c,d\nb,d\na,b,c,d\n")
+	increasingFrequency := func(a, b Item, freqA, freqB int) bool {
+		return freqA < freqB
+	}
+	mine := func(order ItemOrder) []itemsetWithCount {
+		args := ArgumentsV2{ItemsReader: input, MinSupport: 0.3, ItemOrder: order}
+		itemizer, frequency, numTransactions, err := countItems(args, &Stats{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		itemsets, err := generateFrequentItemsets(args, itemizer, frequency, numTransactions, &Stats{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return itemsets
+	}
+	expected := mine(nil)
+	observed := mine(increasingFrequency)
+	if len(observed) != len(expected) {
+		t.Fatalf("expected %d itemsets, got %d", len(expected), len(observed))
+	}
+	for _, iwc := range observed {
+		if !containsIWC(expected, iwc) {
+			t.Error("Generated unexpected itemset ", iwc)
+		}
+	}
+}
+
+func TestFPGrowthMaxRecursionDepth(t *testing.T) {
+	args := ArgumentsV2{
+		ItemsReader:       readerOf("a,b,c\na,b,c\na,b\n"),
+		MinSupport:        0.5,
+		MaxRecursionDepth: 2,
+	}
+	var stats Stats
+	itemizer, frequency, numTransactions, err := countItems(args, &stats, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemsets, err := generateFrequentItemsets(args, itemizer, frequency, numTransactions, &stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a, b, c, ab, ac and bc, but not abc.
+	if len(itemsets) != 6 {
+		t.Errorf("expected 6 itemsets, got %d", len(itemsets))
+	}
+	for _, iwc := range itemsets {
+		if len(iwc.itemset) > 2 {
+			t.Error("Generated itemset deeper than MaxRecursionDepth ", iwc)
+		}
+	}
+	if !stats.ReachedMaxRecursionDepth {
+		t.Error("expected ReachedMaxRecursionDepth to be set")
+	}
+}
+
+func TestFPGrowthTieBreak(t *testing.T) {
+	// Every item has the same frequency, so only the tie break orders them.
+	input := readerOf("d,c,b,a\nc,a\nd,b\nb,a,d,c\na,b\nc,d\n")
+	mine := func(tieBreak string) []itemsetWithCount {
+		args := ArgumentsV2{ItemsReader: input, MinSupport: 0.3, TieBreak: tieBreak}
+		itemizer, frequency, numTransactions, err := countItems(args, &Stats{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		itemsets, err := generateFrequentItemsets(args, itemizer, frequency, numTransactions, &Stats{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return itemsets
+	}
+	expected := mine(TieBreakLexical)
+	for _, tieBreak := range []string{TieBreakInsertion, TieBreakReverseLexical} {
+		observed := mine(tieBreak)
+		if len(observed) != len(expected) {
+			t.Fatalf("%s: expected %d itemsets, got %d", tieBreak, len(expected), len(observed))
+		}
+		for _, iwc := range observed {
+			if !containsIWC(expected, iwc) {
+				t.Errorf("%s: generated unexpected itemset %v", tieBreak, iwc)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonRule is a rule as OutputFormatJSON writes it.
+type jsonRule struct {
+	Antecedent        []string       `json:"antecedent"`
+	Consequent        []string       `json:"consequent"`
+	Support           json.Marshaler `json:"support"`
+	Confidence        json.Marshaler `json:"confidence"`
+	Lift              json.Marshaler `json:"lift"`
+	AntecedentSupport json.Marshaler `json:"antecedentSupport"`
+	Leverage          json.Marshaler `json:"leverage"`
+	Conviction        json.Marshaler `json:"conviction"`
+	Kulczynski        json.Marshaler `json:"kulczynski"`
+}
+
+// writeRulesJSON writes one JSON object per rule and line, with infinite
+// and NaN measures, such as the conviction of rules which always hold,
+// encoded as encoding, a MetricOverflowJSON.
+func writeRulesJSON(w io.Writer, rules [][]Rule, itemizer *Itemizer, encoding string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, chunk := range rules {
+		for _, rule := range chunk {
+			if err := encoder.Encode(jsonRule{
+				Antecedent:        itemizer.strings(rule.Antecedent),
+				Consequent:        itemizer.strings(rule.Consequent),
+				Support:           jsonMeasure(rule.Support, encoding),
+				Confidence:        jsonMeasure(rule.Confidence, encoding),
+				Lift:              jsonMeasure(rule.Lift, encoding),
+				AntecedentSupport: jsonMeasure(rule.AntecedentSupport, encoding),
+				Leverage:          jsonMeasure(rule.Leverage, encoding),
+				Conviction:        jsonMeasure(rule.Conviction, encoding),
+				Kulczynski:        jsonMeasure(rule.Kulczynski, encoding),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
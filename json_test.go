@@ -0,0 +1,72 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteRulesJSON(t *testing.T) {
+	itemizer := newItemizer()
+	items := itemizer.Itemize([]string{"milk", "eggs", "<bread>"})
+	rule := NewRule(items[:2], items[2:], 0.25, 1, 2)
+	rule.AntecedentSupport = 0.25
+	rule.Leverage = 0.125
+	rule.Conviction = math.Inf(1)
+	rule.Kulczynski = math.NaN()
+	rules := [][]Rule{{rule}}
+
+	tests := []struct {
+		encoding   string
+		conviction interface{}
+		kulczynski interface{}
+	}{
+		{"", "Infinity", "NaN"},
+		{MetricOverflowJSONString, "Infinity", "NaN"},
+		{MetricOverflowJSONNull, nil, nil},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := writeRulesJSON(&b, rules, &itemizer, tt.encoding); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+		if len(lines) != 1 {
+			t.Fatalf("%q: expected one line, got %q", tt.encoding, b.String())
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil {
+			t.Fatalf("%q: expected valid JSON, got %v", tt.encoding, err)
+		}
+		expected := map[string]interface{}{
+			"antecedent":        []interface{}{"milk", "eggs"},
+			"consequent":        []interface{}{"<bread>"},
+			"support":           0.25,
+			"confidence":        1.0,
+			"lift":              2.0,
+			"antecedentSupport": 0.25,
+			"leverage":          0.125,
+			"conviction":        tt.conviction,
+			"kulczynski":        tt.kulczynski,
+		}
+		if !reflect.DeepEqual(decoded, expected) {
+			t.Errorf("%q: expected %v, got %v", tt.encoding, expected, decoded)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// writeRulesLong writes the rules in the long format: a row per rule to
+// w, with the ID of the rule and its measures, and a row per item of each
+// rule to RuleItemsWriter, with the ID of the rule and the side the item
+// is on. Joining the two on RuleID gives back the rules, however many
+// items they have. Headers are written as for the CSV format, if header
+// is set for w.
+func writeRulesLong(w io.Writer, header bool, rules [][]Rule, args ArgumentsV2, itemizer *Itemizer) (err error) {
+	output, err := openWriter(args.RuleItemsWriter, args.OutputCompression)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(output, &err)
+	iw := bufio.NewWriter(output)
+
+	columns := make([]ruleColumn, 0)
+	for _, column := range ruleColumns(args) {
+		// The ID is the first column already.
+		if column.name != "ID" {
+			columns = append(columns, column)
+		}
+	}
+	if header {
+		if _, err := fmt.Fprint(w, "RuleID,Confidence,Lift,Support"); err != nil {
+			return err
+		}
+		for _, column := range columns {
+			if _, err := fmt.Fprintf(w, ",%s", column.name); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	if writeHeader(output, args.AppendOutput) {
+		if _, err := fmt.Fprintln(iw, "RuleID,Side,Item"); err != nil {
+			return err
+		}
+	}
+	for _, chunk := range rules {
+		for _, rule := range chunk {
+			id := fmt.Sprintf("%016x", rule.ID)
+			support := args.supportFormat().format(rule.Support)
+			if _, err := fmt.Fprintf(w, "%s,%f,%f,%s", id, rule.Confidence, rule.Lift, support); err != nil {
+				return err
+			}
+			for _, column := range columns {
+				if _, err := fmt.Fprintf(w, ",%s", column.value(&rule)); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+			for _, side := range []struct {
+				name  string
+				items []Item
+			}{{"antecedent", rule.Antecedent}, {"consequent", rule.Consequent}} {
+				for _, item := range side.items {
+					if _, err := fmt.Fprintf(iw, "%s,%s,%s\n", id, side.name, itemizer.toStr(item)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return iw.Flush()
+}
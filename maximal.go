@@ -0,0 +1,80 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"io"
+	"log"
+	"sort"
+)
+
+// MaximalAntecedents mines association rules from args.ItemsReader like
+// Mine, and returns those whose consequent is exactly consequent, in any
+// order, and whose antecedent is maximal: no rule with a larger
+// antecedent, holding all of its items, reaches the same confidence or a
+// higher one. These explain consequent without repeating a rule through
+// its subsets. Rules are returned in the order they are generated, and none
+// if an item of consequent never occurs.
+func MaximalAntecedents(consequent []string, args ArgumentsV2) ([]Rule, error) {
+	result, err := Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(consequent))
+	for _, s := range consequent {
+		item, found := result.Itemizer.lookup(s)
+		if !found {
+			return nil, nil
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i] < items[j]
+	})
+	return maximalAntecedents(result.Rules, items), nil
+}
+
+// maximalAntecedents returns the rules with consequent whose antecedent is
+// maximal among them, as MaximalAntecedents describes.
+func maximalAntecedents(rules []Rule, consequent []Item) []Rule {
+	key := itemsKey(consequent)
+	candidates := make([]Rule, 0)
+	for _, rule := range rules {
+		if itemsKey(rule.Consequent) == key {
+			candidates = append(candidates, rule)
+		}
+	}
+	maximal := make([]Rule, 0, len(candidates))
+	for _, rule := range candidates {
+		if !subsumed(&rule, candidates) {
+			maximal = append(maximal, rule)
+		}
+	}
+	return maximal
+}
+
+// subsumed reports whether a rule of others has a larger antecedent than
+// rule, holding all of its items, and at least its confidence.
+func subsumed(rule *Rule, others []Rule) bool {
+	for i := range others {
+		other := &others[i]
+		if len(other.Antecedent) > len(rule.Antecedent) &&
+			other.Confidence >= rule.Confidence &&
+			containsItems(other.Antecedent, rule.Antecedent) {
+			return true
+		}
+	}
+	return false
+}
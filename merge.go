@@ -0,0 +1,228 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrMalformedRuleLine is returned by MergeRuleFiles for a line which is
+// not a rule in the CSV format opts describe.
+var ErrMalformedRuleLine = errors.New("malformed rule line")
+
+// MergeRuleFiles merges the rules of the CSV files at paths, such as the
+// shards written with OutputShards, into a single file at out, written as
+// opts ask. The files must have been written with the RuleArrow,
+// ItemSeparator, SeparateRuleColumns, SupportAsPercent and RulesOnlyText of
+// opts, but without SupportBins, and are gunzipped if their name ends with
+// .gz. A rule in several
+// files is written once, with the measures of its instance with the
+// highest support. Rules are sorted by SortBy, and TopK applies. Measures
+// the files do not hold, such as Conviction, are derived from confidence,
+// lift and support, up to the precision written; other extra columns are
+// read if they hold measures, and ignored otherwise. opts.Input and
+// opts.OutputShards are ignored.
+func MergeRuleFiles(paths []string, out string, opts Arguments) error {
+	opts.Input, opts.Output, opts.OutputShards = "", out, 0
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	args, err := argumentsV2(opts, log.New(io.Discard, "", 0))
+	if err != nil {
+		return err
+	}
+	if err := args.Validate(); err != nil {
+		return err
+	}
+	itemizer := newItemizer()
+	rules := make([]Rule, 0)
+	index := make(map[string]int)
+	numTransactions := 0
+	for _, path := range paths {
+		n, err := readRuleFile(path, args, &itemizer, func(rule Rule) {
+			key := ruleKey(&rule)
+			if i, found := index[key]; !found {
+				index[key] = len(rules)
+				rules = append(rules, rule)
+			} else if rule.Support > rules[i].Support {
+				rules[i] = rule
+			}
+		})
+		if err != nil {
+			return err
+		}
+		numTransactions = max(numTransactions, n)
+	}
+	ranked := rankRules([][]Rule{rules}, args.SortBy, args.TopK, &itemizer)
+	return writeRules(ranked, args, &itemizer, numTransactions)
+}
+
+// readRuleFile parses the rules of the CSV file at path, written as args
+// describe, and passes each to fn with its items added to itemizer. It
+// returns the NumTransactions of the metadata header, or 0 if there is
+// none.
+func readRuleFile(path string, args ArgumentsV2, itemizer *Itemizer, fn func(Rule)) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	arrow := orDefault(args.RuleArrow, " => ")
+	if args.SeparateRuleColumns {
+		arrow = ","
+	}
+	sep := orDefault(args.ItemSeparator, " ")
+	header := "Antecedent" + arrow + "Consequent,"
+	numTransactions := 0
+	var columns []string
+	scanner := newScanner(r, args.MaxLineBytes)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// Between groups of GroupByAntecedent.
+		case strings.HasPrefix(line, "#"):
+			for _, field := range strings.Fields(line) {
+				if strings.HasPrefix(field, "NumTransactions=") {
+					numTransactions, _ = strconv.Atoi(strings.TrimPrefix(field, "NumTransactions="))
+				}
+			}
+		case strings.HasPrefix(line, header):
+			columns = strings.Split(strings.TrimPrefix(line, header), ",")
+		default:
+			rule, err := parseRule(line, arrow, sep, columns, args, itemizer)
+			if err != nil {
+				return 0, fmt.Errorf("%w: %s:%d: %q", err, path, lineNo, line)
+			}
+			fn(rule)
+		}
+	}
+	return numTransactions, scanner.Err()
+}
+
+// parseRule parses a line of the rules of a CSV file, whose columns after
+// the items are named by columns.
+func parseRule(line, arrow, sep string, columns []string, args ArgumentsV2, itemizer *Itemizer) (Rule, error) {
+	i := strings.Index(line, arrow)
+	if i < 0 {
+		return Rule{}, ErrMalformedRuleLine
+	}
+	fields := strings.Split(line[i+len(arrow):], ",")
+	parseItems := func(s string) []Item {
+		items := make([]Item, 0)
+		for _, str := range strings.Split(s, sep) {
+			if str != "" {
+				items = append(items, itemizer.add(str))
+			}
+		}
+		return items
+	}
+	rule := Rule{Antecedent: parseItems(line[:i]), Consequent: parseItems(fields[0])}
+	rule.ID = ruleID(&rule, itemizer)
+	if args.RulesOnlyText {
+		return rule, nil
+	}
+	if len(fields) < 4 {
+		return Rule{}, ErrMalformedRuleLine
+	}
+	support := strings.TrimSuffix(fields[3], "%")
+	measures := []*float64{&rule.Confidence, &rule.Lift, &rule.Support}
+	for j, s := range []string{fields[1], fields[2], support} {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Rule{}, ErrMalformedRuleLine
+		}
+		*measures[j] = v
+	}
+	if support != fields[3] {
+		rule.Support /= 100
+	}
+	deriveMeasures(&rule)
+	for j, name := range columns {
+		if j+4 >= len(fields) {
+			break
+		}
+		if measure := columnMeasure(&rule, name); measure != nil {
+			v, err := strconv.ParseFloat(fields[j+4], 64)
+			if err != nil {
+				return Rule{}, ErrMalformedRuleLine
+			}
+			*measure = v
+		}
+	}
+	return rule, nil
+}
+
+// deriveMeasures sets the measures of rule which follow from its
+// confidence, lift and support.
+func deriveMeasures(rule *Rule) {
+	if rule.Confidence == 0 || rule.Lift == 0 {
+		return
+	}
+	// The supports of the antecedent and of the consequent.
+	a := rule.Support / rule.Confidence
+	c := rule.Confidence / rule.Lift
+	rule.AntecedentSupport = a
+	rule.Leverage = rule.Support - a*c
+	if rule.Confidence < 1 {
+		rule.Conviction = (1 - c) / (1 - rule.Confidence)
+	} else {
+		rule.Conviction = math.Inf(1)
+	}
+	rule.Kulczynski = (rule.Confidence + rule.Support/c) / 2
+	rule.CorrectedConfidence = rule.Confidence
+	rule.ConfidenceLift = rule.Confidence - c
+	rule.Jaccard = rule.Support / (a + c - rule.Support)
+}
+
+// columnMeasure returns the measure of rule written in the extra column
+// name, or nil if the column holds none.
+func columnMeasure(rule *Rule, name string) *float64 {
+	switch name {
+	case "CorrectedConfidence":
+		return &rule.CorrectedConfidence
+	case "ConfidenceLift":
+		return &rule.ConfidenceLift
+	case "ExcessCount":
+		return &rule.ExcessCount
+	case "Jaccard":
+		return &rule.Jaccard
+	case "ValidationSupport":
+		return &rule.ValidationSupport
+	case "ValidationConfidence":
+		return &rule.ValidationConfidence
+	case "ValidationLift":
+		return &rule.ValidationLift
+	}
+	return nil
+}
@@ -0,0 +1,589 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrTooFewTransactions is returned when the dataset has fewer than
+// MinTransactions transactions.
+var ErrTooFewTransactions = errors.New("dataset has too few transactions")
+
+// ErrPhaseTimeout is returned when a phase of mining takes longer than its
+// timeout in PhaseTimeouts.
+var ErrPhaseTimeout = errors.New("phase timed out")
+
+// Result holds the itemsets and rules mined from a dataset.
+type Result struct {
+	// Converts the items of the rules to strings.
+	Itemizer        *Itemizer
+	NumTransactions int
+	// Rules in the order they were generated, or ranked if TopK or
+	// SortOutput is set.
+	Rules []Rule
+
+	frequency *itemCount
+	itemsets  []itemsetWithCount
+	// Arguments the dataset was mined with, to read it again.
+	args ArgumentsV2
+}
+
+// Mine mines association rules from args.ItemsReader and returns them,
+// rather than writing them. The writers in args are ignored, except for
+// ItemsetsWriter with StreamItemsets.
+func Mine(args ArgumentsV2, log Logger) (*Result, error) {
+	return MineContext(context.Background(), args, log)
+}
+
+// MineBytes mines association rules from data, which holds what a file at
+// args.Input would, and returns them like Mine. args.Input is ignored, and
+// nothing is written or logged, except for ItemsetsPath with
+// StreamItemsets. As data is in memory, the dataset is never read from
+// disk.
+func MineBytes(data []byte, args Arguments) (*Result, error) {
+	args.Input = ""
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+	logger := log.New(io.Discard, "", 0)
+	v2, err := argumentsV2(args, logger)
+	if err != nil {
+		return nil, err
+	}
+	v2.ItemsReader = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return Mine(v2, logger)
+}
+
+// MineContext is like Mine, but stops when ctx is done. If that happens
+// while rules are being generated, the rules generated so far are returned
+// along with ctx.Err(). Such partial results are incomplete, and are never
+// ranked, even if TopK or SortOutput is set.
+func MineContext(ctx context.Context, args ArgumentsV2, log Logger) (*Result, error) {
+	if err := prepare(&args); err != nil {
+		return nil, err
+	}
+	stats := statsFor(args.Stats)
+	result, err := mineItemsets(ctx, args, stats, log)
+	if err != nil || args.DryRun || args.SingleItemOnly {
+		return result, err
+	}
+	return mineRules(ctx, result.args, result, stats, log)
+}
+
+// mineRules generates the rules of the itemsets of result, and ranks them
+// if args ask for it.
+func mineRules(ctx context.Context, args ArgumentsV2, result *Result, stats *Stats, log Logger) (*Result, error) {
+	log.Println("Generating association rules...")
+	start := time.Now()
+	opts := ruleOptions(args, stats, result.Itemizer, log)
+	rulesCtx, cancel := phaseContext(ctx, args, PhaseRules)
+	rules, err := generateRules(rulesCtx, result.itemsets, result.NumTransactions, opts)
+	cancel()
+	stats.RulesTime = time.Since(start)
+	err = phaseError(ctx, args, PhaseRules, err)
+	for _, chunk := range rules {
+		for i := range chunk {
+			chunk[i].ID = ruleID(&chunk[i], result.Itemizer)
+		}
+	}
+	numRules := countRules(rules)
+	if err != nil {
+		log.Printf("Stopped after generating %d association rules: %v", numRules, err)
+		result.Rules = flattenRules(rules)
+		stats.NumRules = numRules
+		return result, err
+	}
+	log.Printf("Generated %d association rules in %s", numRules, stats.RulesTime)
+
+	if args.ValidationReader != nil {
+		start := time.Now()
+		validated, numValidation, err := validateRules(args, rules, result.Itemizer)
+		if err != nil {
+			return nil, fmt.Errorf("validating rules: %w", err)
+		}
+		rules = validated
+		n := countRules(rules)
+		log.Printf("Validated %d association rules on %d transactions in %s, keeping %d",
+			numRules, numValidation, time.Since(start), n)
+		numRules = n
+	}
+
+	if args.TopK > 0 || args.SortOutput {
+		rules = rankRules(rules, args.SortBy, args.TopK, result.Itemizer)
+		if n := countRules(rules); n < numRules {
+			log.Printf("Retained the top %d rules by %s", n, sortByOrDefault(args.SortBy))
+			numRules = n
+		}
+	}
+	result.Rules = flattenRules(rules)
+	stats.NumRules = numRules
+	return result, nil
+}
+
+// MineRulesChan mines association rules from args.ItemsReader like Mine,
+// but sends them on the returned channel as they are generated, so that
+// they can be consumed before mining completes. The channel is unbuffered,
+// and is closed when mining ends, after which the error channel yields the
+// error mining failed with, if any. Callers must drain the rule channel.
+// Rules are sent in the order they are generated: TopK and SortOutput are
+// ignored, and so is ValidationReader.
+func MineRulesChan(args ArgumentsV2) (<-chan Rule, <-chan error) {
+	rules := make(chan Rule)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(rules)
+		if err := streamRules(args, func(rule Rule) { rules <- rule }); err != nil {
+			errs <- err
+		}
+	}()
+	return rules, errs
+}
+
+func streamRules(args ArgumentsV2, emit func(Rule)) error {
+	if err := prepare(&args); err != nil {
+		return err
+	}
+	logger := log.New(io.Discard, "", 0)
+	stats := statsFor(args.Stats)
+	result, err := mineItemsets(context.Background(), args, stats, logger)
+	if err != nil || args.DryRun || args.SingleItemOnly {
+		return err
+	}
+	opts := ruleOptions(args, stats, result.Itemizer, logger)
+	opts.emit = func(rule Rule) {
+		stats.NumRules++
+		rule.ID = ruleID(&rule, result.Itemizer)
+		emit(rule)
+	}
+	_, err = generateRules(context.Background(), result.itemsets, result.NumTransactions, opts)
+	return err
+}
+
+// prepare checks args before mining, and converts MinSupportPPM.
+func prepare(args *ArgumentsV2) error {
+	if args.ItemsReader == nil {
+		return ErrItemsReaderIsNil
+	}
+	if err := args.validateOptions(); err != nil {
+		return err
+	}
+	args.convertMinSupportPPM()
+	args.pivot()
+	return nil
+}
+
+// mineItemsets runs the two passes over the dataset, which count the items
+// and generate the frequent itemsets. With DryRun, only the first pass
+// runs, and the Result has no itemsets. With SingleItemOnly, only the
+// first pass runs too, and the itemsets are the frequent items.
+func mineItemsets(ctx context.Context, args ArgumentsV2, stats *Stats, log Logger) (*Result, error) {
+	log.Println("First pass, counting Item frequencies...")
+	start := time.Now()
+	countCtx, cancel := phaseContext(ctx, args, PhaseCount)
+	itemizer, frequency, numTransactions, err := countItemsContext(countCtx, args, stats, log)
+	cancel()
+	if err != nil {
+		return nil, phaseError(ctx, args, PhaseCount, err)
+	}
+	stats.CountTime = time.Since(start)
+	log.Printf("First pass finished in %s", stats.CountTime)
+	if n := stats.NumMalformedLines; n > 0 {
+		log.Printf("Skipped %d malformed lines", n)
+	}
+	if n := stats.NumLongTransactions; n > 0 {
+		if args.SkipLongTransactions {
+			log.Printf("Skipped %d transactions with more than %d items", n, args.MaxItemsPerTransaction)
+		} else {
+			log.Printf("Truncated %d transactions with more than %d items to their most frequent items",
+				n, args.MaxItemsPerTransaction)
+		}
+	}
+	args.convertMinSupportCount(numTransactions)
+	recordItemStats(args, itemizer, frequency, numTransactions, stats)
+	if args.VocabularyWarnRatio > 0 && float64(stats.NumDistinctItems) > args.VocabularyWarnRatio*float64(numTransactions) {
+		warnVocabulary(itemizer, frequency, numTransactions, log)
+	}
+	if args.TopFrequentItems > 0 {
+		keepTopFrequentItems(args, itemizer, frequency, log)
+	}
+	if err := checkTransactions(args, numTransactions); err != nil {
+		return nil, err
+	}
+	result := &Result{
+		Itemizer:        itemizer,
+		NumTransactions: numTransactions,
+		frequency:       frequency,
+		args:            args,
+	}
+
+	if args.DryRun {
+		recordEstimate(estimateCost(frequency, numTransactions, args.MinSupport), stats, log)
+		return result, nil
+	}
+	if args.SingleItemOnly {
+		itemsets := singleItemsets(frequency, minCountFor(args.MinSupport, numTransactions))
+		log.Printf("Kept %d frequent items, skipping fpGrowth", len(itemsets))
+		stats.NumItemsets = len(itemsets)
+		result.itemsets = capItemsets(itemsets, args.MaxItemsets, itemizer, log)
+		return result, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	log.Println("Generating frequent itemsets via fpGrowth")
+	start = time.Now()
+	growCtx, cancel := phaseContext(ctx, args, PhaseFPGrowth)
+	defer cancel()
+
+	if args.StreamItemsets {
+		n, err := streamFrequentItemsets(growCtx, args, itemizer, frequency, numTransactions, stats)
+		if err != nil {
+			return nil, phaseError(ctx, args, PhaseFPGrowth, err)
+		}
+		reportItemsets(args, n, stats, time.Since(start), log)
+		result.itemsets = make([]itemsetWithCount, 0)
+		return result, nil
+	}
+	itemsWithCount, err := generateFrequentItemsetsContext(growCtx, args, itemizer, frequency, numTransactions, stats)
+	if err != nil {
+		return nil, phaseError(ctx, args, PhaseFPGrowth, err)
+	}
+	reportItemsets(args, len(itemsWithCount), stats, time.Since(start), log)
+	itemsWithCount = capItemsets(itemsWithCount, args.MaxItemsets, itemizer, log)
+	result.itemsets = itemsWithCount
+
+	if args.VerifySupports {
+		start = time.Now()
+		if err := verifySupports(args, result); err != nil {
+			return nil, err
+		}
+		log.Printf("Verified the support of %d itemsets in %s", len(itemsWithCount), time.Since(start))
+	}
+	return result, nil
+}
+
+// phaseContext returns a context for phase derived from ctx, which is also
+// done once the timeout of phase in PhaseTimeouts has passed, if it has
+// one.
+func phaseContext(ctx context.Context, args ArgumentsV2, phase string) (context.Context, context.CancelFunc) {
+	if timeout := args.PhaseTimeouts[phase]; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// phaseError returns err, which phase failed with, wrapped in an error
+// naming phase if it is due to the timeout of phase rather than ctx.
+func phaseError(ctx context.Context, args ArgumentsV2, phase string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return fmt.Errorf("%w: the %s phase took longer than %s", ErrPhaseTimeout, phase, args.PhaseTimeouts[phase])
+	}
+	return err
+}
+
+// checkTransactions returns an error wrapping ErrTooFewTransactions if
+// numTransactions is below MinTransactions.
+func checkTransactions(args ArgumentsV2, numTransactions int) error {
+	if numTransactions < args.MinTransactions {
+		return fmt.Errorf("%w: %d transactions, MinTransactions is %d",
+			ErrTooFewTransactions, numTransactions, args.MinTransactions)
+	}
+	return nil
+}
+
+// recordItemStats records in stats what the first pass found.
+func recordItemStats(args ArgumentsV2, itemizer *Itemizer, frequency *itemCount, numTransactions int, stats *Stats) {
+	stats.NumTransactions = numTransactions
+	if numTransactions > 0 {
+		stats.MaxSingleItemSupport = float64(frequency.max()) / float64(numTransactions)
+	}
+	stats.NumDistinctItems = itemizer.numItems
+	minCount := minCountFor(args.MinSupport, numTransactions)
+	for _, count := range frequency.counts {
+		if count > 0 && count >= minCount {
+			stats.NumFrequentItems++
+		}
+	}
+}
+
+// keepTopFrequentItems zeroes the count of every item but the
+// TopFrequentItems most frequent, ties broken as in the FP-tree, so that
+// the others are dropped from transactions like infrequent items are. It
+// logs how many of the occurrences of items are dropped, which bounds how
+// far the supports of the itemsets mined are from complete.
+func keepTopFrequentItems(args ArgumentsV2, itemizer *Itemizer, frequency *itemCount, log Logger) {
+	items := make([]Item, 0)
+	total := 0
+	for idx, count := range frequency.counts {
+		if count > 0 {
+			items = append(items, Item(idx))
+			total += count
+		}
+	}
+	if len(items) <= args.TopFrequentItems {
+		return
+	}
+	less := itemLess(nil, args.TieBreak, itemizer, frequency)
+	sort.Slice(items, func(i, j int) bool {
+		return less(items[i], items[j])
+	})
+	dropped := 0
+	for _, item := range items[args.TopFrequentItems:] {
+		dropped += frequency.get(item)
+		frequency.counts[item] = 0
+	}
+	log.Printf("Kept the %d most frequent of %d items, dropping %d of %d item occurrences (%.2f%%)",
+		args.TopFrequentItems, len(items), dropped, total, 100*float64(dropped)/float64(total))
+}
+
+// singleItemsets returns the 1-itemsets of the items which appear in at
+// least minCount transactions, in increasing order of their items.
+func singleItemsets(frequency *itemCount, minCount int) []itemsetWithCount {
+	itemsets := make([]itemsetWithCount, 0)
+	for idx, count := range frequency.counts {
+		if count > 0 && count >= minCount {
+			itemsets = append(itemsets, itemsetWithCount{itemset: []Item{Item(idx)}, count: count})
+		}
+	}
+	return itemsets
+}
+
+// vocabularySample is the number of items of each kind warnVocabulary
+// lists.
+const vocabularySample = 5
+
+// warnVocabulary logs a warning that the items of itemizer are many for
+// numTransactions, listing the most frequent items and some which occur
+// once, as the column holding unique values is likely among the latter.
+func warnVocabulary(itemizer *Itemizer, frequency *itemCount, numTransactions int, log Logger) {
+	items := make([]Item, 0)
+	singletons := make([]string, 0, vocabularySample)
+	for idx, count := range frequency.counts {
+		if count == 0 {
+			continue
+		}
+		items = append(items, Item(idx))
+		if count == 1 && len(singletons) < vocabularySample {
+			singletons = append(singletons, itemizer.toStr(Item(idx)))
+		}
+	}
+	numItems := len(items)
+	sort.SliceStable(items, func(i, j int) bool {
+		return frequency.get(items[i]) > frequency.get(items[j])
+	})
+	if len(items) > vocabularySample {
+		items = items[:vocabularySample]
+	}
+	frequent := make([]string, len(items))
+	for i, item := range items {
+		frequent[i] = fmt.Sprintf("%s (%d)", itemizer.toStr(item), frequency.get(item))
+	}
+	log.Printf("Warning: %d distinct items in %d transactions; a column of unique values such as an ID may be "+
+		"read as items. Most frequent items: %s. Items occurring once include: %s",
+		numItems, numTransactions, strings.Join(frequent, ", "), strings.Join(singletons, ", "))
+}
+
+// reportItemsets logs and records in stats the number of frequent itemsets
+// fpGrowth generated in elapsed.
+func reportItemsets(args ArgumentsV2, numItemsets int, stats *Stats, elapsed time.Duration, log Logger) {
+	log.Printf("fpGrowth generated %d frequent patterns in %s", numItemsets, elapsed)
+	if n := stats.NumRestoredHeaderItems; n > 0 {
+		log.Printf("Restored the patterns of %d header items from checkpoint '%s'", n, args.CheckpointPath)
+	}
+	stats.FPGrowthTime = elapsed
+	if stats.ReachedMaxRecursionDepth {
+		log.Printf("Warning: fpGrowth stopped at MaxRecursionDepth %d; longer frequent itemsets were not generated",
+			args.MaxRecursionDepth)
+	}
+	stats.NumItemsets = numItemsets
+	if numItemsets == 0 {
+		log.Printf("Warning: no itemsets reach MinSupport %f; the most frequent item has support %f, "+
+			"so try a MinSupport no higher than that", args.MinSupport, stats.MaxSingleItemSupport)
+	}
+}
+
+// ruleOptions returns the options of args which select rules, which also
+// record in stats the itemsets yielding no rule, and the candidate rules
+// each threshold rejects.
+func ruleOptions(args ArgumentsV2, stats *Stats, itemizer *Itemizer, log Logger) RuleOptions {
+	opts := RuleOptions{
+		MinConfidence:        args.MinConfidence,
+		MinLift:              args.MinLift,
+		MinConfidenceLift:    args.MinConfidenceLift,
+		MinExcessCount:       args.MinExcessCount,
+		MinJaccard:           args.MinJaccard,
+		LaplaceSmoothing:     args.LaplaceSmoothing,
+		MetricOverflow:       args.MetricOverflow,
+		MinRuleSupportCount:  args.MinRuleSupportCount,
+		BothDirections:       args.BothDirections,
+		IncludeBaselineRules: args.IncludeBaselineRules,
+		LiftExclusive:        args.LiftExclusive,
+		Log:                  log,
+		rejected:             stats.countRejection,
+		NoRules: func(is Itemset) {
+			stats.NumItemsetsWithoutRules++
+			if args.RecordItemsetsWithoutRules {
+				stats.ItemsetsWithoutRules = append(stats.ItemsetsWithoutRules, itemizer.strings(is.Items))
+			}
+		},
+	}
+	if item, focused := focusItem(args, itemizer); focused {
+		opts.focus = []Item{item}
+	}
+	if args.AttributeSeparator != "" {
+		opts.attributes = itemAttributes(itemizer, args.AttributeSeparator)
+	}
+	if args.ClassRulesOnly {
+		opts.labels = itemizer.labels
+	}
+	return opts
+}
+
+// itemAttributes maps each item of itemizer whose string holds sep to the
+// index of its attribute, the part of the string before sep.
+func itemAttributes(itemizer *Itemizer, sep string) map[Item]int {
+	indexes := make(map[string]int)
+	attributes := make(map[Item]int)
+	for item, str := range itemizer.itemToStr {
+		i := strings.Index(str, sep)
+		if i < 0 {
+			continue
+		}
+		index, found := indexes[str[:i]]
+		if !found {
+			index = len(indexes)
+			indexes[str[:i]] = index
+		}
+		attributes[item] = index
+	}
+	return attributes
+}
+
+// MineSweep mines association rules from args.Input at each of supports,
+// which replace args.MinSupport, so MinSupportPPM and MinSupportCount may
+// not be set. The dataset is read and the FP-tree built once, at the
+// lowest support, and the itemsets of each higher support are those of the
+// lowest which reach it, of which MaxItemsets then retains the most
+// frequent. Nothing is written, and Stats and DryRun are ignored.
+func MineSweep(args Arguments, supports []float64) (map[float64]*Result, error) {
+	if args.MinSupportPPM != 0 || args.MinSupportCount != 0 {
+		return nil, ErrSweepSupportConflict
+	}
+	args.MinSupport = 0
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+	if len(supports) == 0 {
+		return map[float64]*Result{}, nil
+	}
+	lowest := supports[0]
+	for _, support := range supports {
+		if support < 0 || support > 1 {
+			return nil, ErrMinSupportOutOfRange
+		}
+		if support < lowest {
+			lowest = support
+		}
+	}
+	logger := log.New(io.Discard, "", 0)
+	base, err := argumentsV2(args, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := prepare(&base); err != nil {
+		return nil, err
+	}
+	base.MinSupport = lowest
+	base.Stats = nil
+	base.DryRun = false
+	// Itemsets are retained per support, as an independent run would.
+	lowestArgs := base
+	lowestArgs.MaxItemsets = 0
+	lowestResult, err := mineItemsets(context.Background(), lowestArgs, &Stats{}, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[float64]*Result, len(supports))
+	for _, support := range supports {
+		sweepArgs := base
+		sweepArgs.MinSupport = support
+		minCount := minCountFor(support, lowestResult.NumTransactions)
+		itemsets := make([]itemsetWithCount, 0)
+		for _, iwc := range lowestResult.itemsets {
+			if iwc.count >= minCount {
+				itemsets = append(itemsets, iwc)
+			}
+		}
+		result := *lowestResult
+		result.itemsets = capItemsets(itemsets, sweepArgs.MaxItemsets, lowestResult.Itemizer, logger)
+		result.args = sweepArgs
+		if _, err := mineRules(context.Background(), sweepArgs, &result, &Stats{}, logger); err != nil {
+			return nil, err
+		}
+		results[support] = &result
+	}
+	return results, nil
+}
+
+// WriteRules writes the rules of r to w, formatted as opts ask, as with
+// OutputFormat, RulesOnlyText, GroupByAntecedent, OutputItemIDs or
+// OutputCompression. The readers and writers in opts are ignored, except
+// for RuleItemsWriter with OutputFormatLong, as is OutputShards, and w is
+// not closed.
+func (r *Result) WriteRules(w io.Writer, opts ArgumentsV2) error {
+	opts.RulesWriter = writerTo(w)
+	opts.OutputShards = 0
+	return writeRules([][]Rule{r.Rules}, opts, r.outputItemizer(opts), r.NumTransactions)
+}
+
+// WriteItemsets writes the frequent itemsets of r to w, formatted as opts
+// ask, like WriteRules.
+func (r *Result) WriteItemsets(w io.Writer, opts ArgumentsV2) error {
+	opts.ItemsetsWriter = writerTo(w)
+	return writeItemsets(r.itemsets, opts, r.outputItemizer(opts), r.NumTransactions)
+}
+
+// outputItemizer returns the Itemizer to write the items of r with.
+func (r *Result) outputItemizer(opts ArgumentsV2) *Itemizer {
+	if opts.OutputItemIDs {
+		return r.Itemizer.withIDs()
+	}
+	return r.Itemizer
+}
+
+// writerTo returns a writer function which opens w, without ever closing it.
+func writerTo(w io.Writer) func() (io.WriteCloser, error) {
+	return func() (io.WriteCloser, error) {
+		return nopWriteCloser{w}, nil
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,117 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+)
+
+// defaultMaxNegativeLift is the lift below which pairs are reported as
+// negatively correlated when MaxNegativeLift is not set.
+const defaultMaxNegativeLift = 0.5
+
+// negativePair is a pair of frequent items which appear together in far
+// fewer transactions than they would if they were independent.
+type negativePair struct {
+	a, b  Item
+	count int
+	lift  float64
+}
+
+// countPairs counts the transactions each pair of frequent items appears
+// in. Unlike fpGrowth, this counts pairs which are not frequent themselves,
+// down to those which appear together only once.
+func countPairs(args ArgumentsV2, itemizer *Itemizer, frequency *itemCount, minCount int) (map[[2]Item]int, error) {
+	pairs := make(map[[2]Item]int)
+	_, err := scanTransactions(args, nil, func(fields []string) bool {
+		transaction, ok := limitTransaction(args, itemizer.Itemize(fields), itemizer, frequency, minCount)
+		if !ok {
+			return true
+		}
+		sort.Slice(transaction, func(i, j int) bool {
+			return transaction[i] < transaction[j]
+		})
+		for i := range transaction {
+			if i > 0 && transaction[i] == transaction[i-1] {
+				continue
+			}
+			for j := i + 1; j < len(transaction); j++ {
+				if transaction[j] == transaction[j-1] {
+					continue
+				}
+				pairs[[2]Item{transaction[i], transaction[j]}]++
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// findNegativePairs returns the pairs of frequent items whose lift is at
+// most maxLift, most negatively correlated first. Pairs which never appear
+// together have a lift of 0.
+func findNegativePairs(frequency *itemCount, pairs map[[2]Item]int, numTransactions int, minCount int, maxLift float64) []negativePair {
+	frequent := make([]Item, 0)
+	for idx, count := range frequency.counts {
+		if count > 0 && count >= minCount {
+			frequent = append(frequent, Item(idx))
+		}
+	}
+	n := float64(numTransactions)
+	found := make([]negativePair, 0)
+	for i, a := range frequent {
+		for _, b := range frequent[i+1:] {
+			count := pairs[[2]Item{a, b}]
+			lift := float64(count) * n / (float64(frequency.get(a)) * float64(frequency.get(b)))
+			if lift <= maxLift {
+				found = append(found, negativePair{a: a, b: b, count: count, lift: lift})
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].lift < found[j].lift
+	})
+	return found
+}
+
+func writeNegativePairs(pairs []negativePair, args ArgumentsV2, itemizer *Itemizer, numTransactions int) (err error) {
+	output, err := openWriter(args.NegativeCorrelationWriter, args.OutputCompression)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(output, &err)
+	w := bufio.NewWriter(output)
+	if err := writeMetadata(w, args, numTransactions); err != nil {
+		return err
+	}
+	if writeHeader(output, args.AppendOutput) {
+		if _, err := fmt.Fprintln(w, "Pair,Lift,Support"); err != nil {
+			return err
+		}
+	}
+	n := float64(numTransactions)
+	for _, p := range pairs {
+		if _, err := fmt.Fprintf(w, "%s %s,%f,%f\n",
+			itemizer.toStr(p.a), itemizer.toStr(p.b), p.lift, float64(p.count)/n); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,48 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// foldUnicode returns a function which folds strings to lower case without
+// diacritics, so "Café" and "CAFE" both become "cafe". Compatibility
+// decomposition also folds ligatures and width variants, such as "ﬁ" to
+// "fi". The returned function is safe for concurrent use: the transformer
+// chain keeps state between calls, so each call takes one of its own from
+// a pool.
+func foldUnicode() func(string) string {
+	pool := sync.Pool{
+		New: func() interface{} {
+			return transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+		},
+	}
+	return func(s string) string {
+		t := pool.Get().(transform.Transformer)
+		defer pool.Put(t)
+		folded, _, err := transform.String(t, s)
+		if err != nil {
+			folded = s
+		}
+		return strings.ToLower(folded)
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// This file implements just enough of Apache Parquet to write flat tables
+// of required string and double columns: PLAIN encoding, no compression,
+// one data page per column chunk. The metadata is Thrift, in the compact
+// protocol, as defined by parquet.thrift.
+
+// Parquet physical and converted types used here.
+const (
+	parquetDouble    = 5
+	parquetByteArray = 6
+	parquetUTF8      = 0
+)
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// parquetRowGroupRows is the number of rows buffered before a row group is
+// written, which bounds memory use and the size of each page.
+const parquetRowGroupRows = 1 << 20
+
+var parquetMagic = []byte("PAR1")
+
+type parquetColumn struct {
+	name string
+	typ  int32
+	data bytes.Buffer
+}
+
+type parquetColumnChunk struct {
+	column    *parquetColumn
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+type parquetRowGroup struct {
+	chunks  []parquetColumnChunk
+	numRows int64
+}
+
+// parquetWriter writes rows to a Parquet file. Values of each row are
+// appended column by column, after which endRow is called.
+type parquetWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []*parquetColumn
+	rows      int64
+	rowGroups []parquetRowGroup
+	err       error
+}
+
+func newParquetWriter(w io.Writer, columns []*parquetColumn) *parquetWriter {
+	pw := &parquetWriter{w: w, columns: columns}
+	pw.write(parquetMagic)
+	return pw
+}
+
+func (pw *parquetWriter) write(p []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(p)
+	pw.offset += int64(n)
+	pw.err = err
+}
+
+func (pw *parquetWriter) appendString(col int, s string) {
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(s)))
+	pw.columns[col].data.Write(length[:])
+	pw.columns[col].data.WriteString(s)
+}
+
+func (pw *parquetWriter) appendDouble(col int, f float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+	pw.columns[col].data.Write(b[:])
+}
+
+func (pw *parquetWriter) endRow() error {
+	pw.rows++
+	if pw.rows == parquetRowGroupRows {
+		pw.flushRowGroup()
+	}
+	return pw.err
+}
+
+func (pw *parquetWriter) flushRowGroup() {
+	if pw.rows == 0 {
+		return
+	}
+	rg := parquetRowGroup{numRows: pw.rows}
+	for _, col := range pw.columns {
+		var header thriftWriter
+		header.fieldI32(1, 0) // DATA_PAGE
+		header.fieldI32(2, int32(col.data.Len()))
+		header.fieldI32(3, int32(col.data.Len()))
+		header.fieldStruct(5)
+		header.fieldI32(1, int32(pw.rows))
+		header.fieldI32(2, 0) // PLAIN
+		header.fieldI32(3, 3) // RLE
+		header.fieldI32(4, 3) // RLE
+		header.endStruct()
+		header.endStruct()
+
+		chunk := parquetColumnChunk{
+			column:    col,
+			offset:    pw.offset,
+			size:      int64(header.buf.Len() + col.data.Len()),
+			numValues: pw.rows,
+		}
+		pw.write(header.buf.Bytes())
+		pw.write(col.data.Bytes())
+		col.data.Reset()
+		rg.chunks = append(rg.chunks, chunk)
+	}
+	pw.rowGroups = append(pw.rowGroups, rg)
+	pw.rows = 0
+}
+
+// close writes the remaining rows and the file footer. It does not close
+// the underlying writer.
+func (pw *parquetWriter) close() error {
+	pw.flushRowGroup()
+
+	var meta thriftWriter
+	meta.fieldI32(1, 1) // version
+	meta.fieldList(2, thriftStruct, 1+len(pw.columns))
+	meta.beginStruct()
+	meta.fieldBinary(4, "schema")
+	meta.fieldI32(5, int32(len(pw.columns)))
+	meta.endStruct()
+	for _, col := range pw.columns {
+		meta.beginStruct()
+		meta.fieldI32(1, col.typ)
+		meta.fieldI32(3, 0) // REQUIRED
+		meta.fieldBinary(4, col.name)
+		if col.typ == parquetByteArray {
+			meta.fieldI32(6, parquetUTF8)
+		}
+		meta.endStruct()
+	}
+	var numRows int64
+	for _, rg := range pw.rowGroups {
+		numRows += rg.numRows
+	}
+	meta.fieldI64(3, numRows)
+	meta.fieldList(4, thriftStruct, len(pw.rowGroups))
+	for _, rg := range pw.rowGroups {
+		meta.beginStruct()
+		meta.fieldList(1, thriftStruct, len(rg.chunks))
+		var size int64
+		for _, chunk := range rg.chunks {
+			size += chunk.size
+			meta.beginStruct()
+			meta.fieldI64(2, chunk.offset)
+			meta.fieldStruct(3)
+			meta.fieldI32(1, chunk.column.typ)
+			meta.fieldList(2, thriftI32, 1)
+			meta.i32(0) // PLAIN
+			meta.fieldList(3, thriftBinary, 1)
+			meta.binary(chunk.column.name)
+			meta.fieldI32(4, 0) // UNCOMPRESSED
+			meta.fieldI64(5, chunk.numValues)
+			meta.fieldI64(6, chunk.size)
+			meta.fieldI64(7, chunk.size)
+			meta.fieldI64(9, chunk.offset)
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.fieldI64(2, size)
+		meta.fieldI64(3, rg.numRows)
+		meta.endStruct()
+	}
+	meta.fieldBinary(6, "arm-go")
+	meta.endStruct()
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(meta.buf.Len()))
+	pw.write(meta.buf.Bytes())
+	pw.write(length[:])
+	pw.write(parquetMagic)
+	return pw.err
+}
+
+// thriftWriter encodes structs in the Thrift compact protocol. Fields must
+// be written in increasing order of their ids.
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastIDs []int16
+	lastID  int16
+}
+
+func (tw *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	tw.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (tw *thriftWriter) i32(v int32) {
+	tw.varint(uint64(uint32((v << 1) ^ (v >> 31))))
+}
+
+func (tw *thriftWriter) i64(v int64) {
+	tw.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (tw *thriftWriter) binary(s string) {
+	tw.varint(uint64(len(s)))
+	tw.buf.WriteString(s)
+}
+
+func (tw *thriftWriter) field(id int16, typ byte) {
+	if delta := id - tw.lastID; delta > 0 && delta <= 15 {
+		tw.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		tw.buf.WriteByte(typ)
+		tw.i32(int32(id))
+	}
+	tw.lastID = id
+}
+
+func (tw *thriftWriter) fieldI32(id int16, v int32) {
+	tw.field(id, thriftI32)
+	tw.i32(v)
+}
+
+func (tw *thriftWriter) fieldI64(id int16, v int64) {
+	tw.field(id, thriftI64)
+	tw.i64(v)
+}
+
+func (tw *thriftWriter) fieldBinary(id int16, s string) {
+	tw.field(id, thriftBinary)
+	tw.binary(s)
+}
+
+func (tw *thriftWriter) fieldList(id int16, elemType byte, size int) {
+	tw.field(id, thriftList)
+	if size < 15 {
+		tw.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		tw.buf.WriteByte(0xf0 | elemType)
+		tw.varint(uint64(size))
+	}
+}
+
+func (tw *thriftWriter) fieldStruct(id int16) {
+	tw.field(id, thriftStruct)
+	tw.beginStruct()
+}
+
+// beginStruct starts a struct which is a list element, or, through
+// fieldStruct, a field.
+func (tw *thriftWriter) beginStruct() {
+	tw.lastIDs = append(tw.lastIDs, tw.lastID)
+	tw.lastID = 0
+}
+
+func (tw *thriftWriter) endStruct() {
+	tw.buf.WriteByte(0)
+	if n := len(tw.lastIDs); n > 0 {
+		tw.lastID = tw.lastIDs[n-1]
+		tw.lastIDs = tw.lastIDs[:n-1]
+	}
+}
+
+func parquetRuleColumns() []*parquetColumn {
+	return []*parquetColumn{
+		{name: "antecedent", typ: parquetByteArray},
+		{name: "consequent", typ: parquetByteArray},
+		{name: "confidence", typ: parquetDouble},
+		{name: "lift", typ: parquetDouble},
+		{name: "support", typ: parquetDouble},
+	}
+}
+
+// writeRulesParquet writes rules as Parquet, in the fixed schema of
+// parquetRuleColumns: the options which add columns to the CSV output,
+// such as EmitRuleIDs or ValidationInput, are ignored. The antecedent and
+// consequent are space separated item lists, as in the CSV output.
+func writeRulesParquet(w io.Writer, rules [][]Rule, itemizer *Itemizer) error {
+	pw := newParquetWriter(w, parquetRuleColumns())
+	for _, chunk := range rules {
+		for _, rule := range chunk {
+			pw.appendString(0, itemizer.join(rule.Antecedent, " "))
+			pw.appendString(1, itemizer.join(rule.Consequent, " "))
+			pw.appendDouble(2, rule.Confidence)
+			pw.appendDouble(3, rule.Lift)
+			pw.appendDouble(4, rule.Support)
+			if err := pw.endRow(); err != nil {
+				return err
+			}
+		}
+	}
+	return pw.close()
+}
+
+func writeItemsetsParquet(w io.Writer, itemsets []itemsetWithCount, itemizer *Itemizer, numTransactions int) error {
+	pw := newParquetWriter(w, []*parquetColumn{
+		{name: "itemset", typ: parquetByteArray},
+		{name: "support", typ: parquetDouble},
+	})
+	n := float64(numTransactions)
+	for _, iwc := range itemsets {
+		pw.appendString(0, itemizer.join(iwc.itemset, " "))
+		pw.appendDouble(1, float64(iwc.count)/n)
+		if err := pw.endRow(); err != nil {
+			return err
+		}
+	}
+	return pw.close()
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// pivot makes args read the transactions which PivotKey and PivotGroup ask
+// for from ItemsReader, as InputFormatJSONL, so that the rest of mining
+// need not know about them.
+func (args *ArgumentsV2) pivot() {
+	if args.PivotKey == "" {
+		return
+	}
+	args.ItemsReader = pivotItems(args.ItemsReader, args.PivotKey, args.PivotGroup, args.MaxLineBytes)
+	if args.ValidationReader != nil {
+		args.ValidationReader = pivotItems(args.ValidationReader, args.PivotKey, args.PivotGroup, args.MaxLineBytes)
+	}
+	args.InputFormat = InputFormatJSONL
+	args.PivotKey, args.PivotGroup = "", ""
+}
+
+func pivotItems(open ItemsReader, key, group string, maxLineBytes int) ItemsReader {
+	return func() (io.ReadCloser, error) {
+		r, err := open()
+		if err != nil {
+			return nil, err
+		}
+		return &pivotReader{
+			r:       r,
+			scanner: newScanner(r, maxLineBytes),
+			key:     key,
+			group:   group,
+			items:   make([]string, 0),
+		}, nil
+	}
+}
+
+// pivotReader reads lines of space separated key=value pairs, and returns a
+// JSON array of item strings for each run of consecutive lines with the
+// same value of group, holding the values of key in those lines. Lines
+// without group are ignored.
+type pivotReader struct {
+	r          io.ReadCloser
+	scanner    *bufio.Scanner
+	key, group string
+	// Whether a transaction is being gathered, the value of group it is
+	// for, and its items so far.
+	started bool
+	current string
+	items   []string
+	// Transactions converted but not read yet.
+	pending []byte
+	done    bool
+}
+
+func (p *pivotReader) Read(b []byte) (int, error) {
+	for len(p.pending) == 0 {
+		if p.done {
+			return 0, io.EOF
+		}
+		if err := p.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+// next reads lines until a transaction is complete, and adds it to
+// pending.
+func (p *pivotReader) next() error {
+	for p.scanner.Scan() {
+		group, items, ok := p.fields(p.scanner.Text())
+		if !ok {
+			continue
+		}
+		if p.started && group != p.current {
+			err := p.flush()
+			p.current, p.items = group, items
+			return err
+		}
+		p.started = true
+		p.current = group
+		p.items = append(p.items, items...)
+	}
+	if err := p.scanner.Err(); err != nil {
+		return err
+	}
+	p.done = true
+	if !p.started {
+		return nil
+	}
+	return p.flush()
+}
+
+// fields returns the value of group in line, and the values of key.
+func (p *pivotReader) fields(line string) (string, []string, bool) {
+	group, found := "", false
+	items := make([]string, 0, 1)
+	for _, pair := range strings.Fields(line) {
+		i := strings.IndexByte(pair, '=')
+		if i < 0 {
+			continue
+		}
+		switch pair[:i] {
+		case p.group:
+			group, found = pair[i+1:], true
+		case p.key:
+			items = append(items, pair[i+1:])
+		}
+	}
+	return group, items, found
+}
+
+func (p *pivotReader) flush() error {
+	line, err := json.Marshal(p.items)
+	if err != nil {
+		return err
+	}
+	p.pending = append(append(p.pending, line...), '\n')
+	return nil
+}
+
+func (p *pivotReader) Close() error {
+	return p.r.Close()
+}
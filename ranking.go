@@ -0,0 +1,133 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"sort"
+	"strings"
+)
+
+// ruleMeasure returns the measure of a rule named by sortBy, or nil if
+// there is no such measure.
+func ruleMeasure(sortBy string) func(*Rule) float64 {
+	switch sortByOrDefault(sortBy) {
+	case SortByConfidence:
+		return func(r *Rule) float64 { return r.Confidence }
+	case SortByLift:
+		return func(r *Rule) float64 { return r.Lift }
+	case SortBySupport:
+		return func(r *Rule) float64 { return r.Support }
+	case SortByConviction:
+		return func(r *Rule) float64 { return r.Conviction }
+	case SortByLeverage:
+		return func(r *Rule) float64 { return r.Leverage }
+	case SortByKulczynski:
+		return func(r *Rule) float64 { return r.Kulczynski }
+	case SortByAntecedentSupport:
+		return func(r *Rule) float64 { return r.AntecedentSupport }
+	}
+	return nil
+}
+
+func sortByOrDefault(sortBy string) string {
+	if sortBy == "" {
+		return SortByConfidence
+	}
+	return sortBy
+}
+
+// flattenRules copies the chunks of rules into a single slice.
+func flattenRules(rules [][]Rule) []Rule {
+	flat := make([]Rule, 0, countRules(rules))
+	for _, chunk := range rules {
+		flat = append(flat, chunk...)
+	}
+	return flat
+}
+
+// rankRules sorts rules by decreasing sortBy measure and, if topK is
+// non-zero, retains only the first topK of them. Rules with the same
+// measure are ordered by the names of their items, so the order does not
+// depend on how the rules were generated. SortByAntecedentSupport orders
+// rules with the same antecedent support by decreasing support first.
+func rankRules(rules [][]Rule, sortBy string, topK int, itemizer *Itemizer) [][]Rule {
+	ranked := flattenRules(rules)
+	measure := ruleMeasure(sortBy)
+	bySupport := sortBy == SortByAntecedentSupport
+	sort.SliceStable(ranked, func(i, j int) bool {
+		mi, mj := measure(&ranked[i]), measure(&ranked[j])
+		if mi != mj {
+			return mi > mj
+		}
+		if si, sj := ranked[i].Support, ranked[j].Support; bySupport && si != sj {
+			return si > sj
+		}
+		return ruleNameLess(&ranked[i], &ranked[j], itemizer)
+	})
+	if topK > 0 && topK < len(ranked) {
+		ranked = ranked[:topK]
+	}
+	return [][]Rule{ranked}
+}
+
+// capItemsets retains the maxItemsets itemsets with the highest count, if
+// there are more. Itemsets with the same count are ordered by size, then
+// by the names of their items, so that every subset of a retained itemset
+// is retained too, as rules need its count.
+func capItemsets(itemsets []itemsetWithCount, maxItemsets int, itemizer *Itemizer, log Logger) []itemsetWithCount {
+	if maxItemsets == 0 || len(itemsets) <= maxItemsets {
+		return itemsets
+	}
+	sort.SliceStable(itemsets, func(i, j int) bool {
+		a, b := itemsets[i], itemsets[j]
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		if len(a.itemset) != len(b.itemset) {
+			return len(a.itemset) < len(b.itemset)
+		}
+		return compareItemNames(a.itemset, b.itemset, itemizer) < 0
+	})
+	log.Printf("Retained the %d most frequent of %d itemsets", maxItemsets, len(itemsets))
+	return itemsets[:maxItemsets]
+}
+
+// groupByAntecedent sorts rules by the names of the items of their
+// antecedent, keeping the order of rules with the same antecedent.
+func groupByAntecedent(rules [][]Rule, itemizer *Itemizer) [][]Rule {
+	grouped := flattenRules(rules)
+	sort.SliceStable(grouped, func(i, j int) bool {
+		return compareItemNames(grouped[i].Antecedent, grouped[j].Antecedent, itemizer) < 0
+	})
+	return [][]Rule{grouped}
+}
+
+// ruleNameLess orders rules by the names of the items of their antecedent,
+// then of their consequent.
+func ruleNameLess(a, b *Rule, itemizer *Itemizer) bool {
+	if c := compareItemNames(a.Antecedent, b.Antecedent, itemizer); c != 0 {
+		return c < 0
+	}
+	return compareItemNames(a.Consequent, b.Consequent, itemizer) < 0
+}
+
+func compareItemNames(a, b []Item, itemizer *Itemizer) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(itemizer.toStr(a[i]), itemizer.toStr(b[i])); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
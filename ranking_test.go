@@ -0,0 +1,122 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestRankRules(t *testing.T) {
+	itemizer := newItemizer()
+	itemizer.Itemize([]string{"a", "b", "c"})
+	rules := [][]Rule{
+		{
+			{Antecedent: []Item{1}, Consequent: []Item{2}, Confidence: 0.5, Leverage: 0.01},
+			{Antecedent: []Item{2}, Consequent: []Item{1}, Confidence: 0.9, Leverage: 0.02},
+		},
+		{
+			{Antecedent: []Item{1}, Consequent: []Item{3}, Confidence: 0.7, Leverage: 0.03},
+		},
+	}
+	tests := []struct {
+		sortBy   string
+		topK     int
+		expected []Item
+	}{
+		{"", 0, []Item{1, 3, 2}},
+		{SortByConfidence, 2, []Item{1, 3}},
+		{SortByLeverage, 0, []Item{3, 1, 2}},
+		{SortByLeverage, 5, []Item{3, 1, 2}},
+	}
+	for _, tt := range tests {
+		ranked := rankRules(rules, tt.sortBy, tt.topK, &itemizer)
+		if len(ranked) != 1 || len(ranked[0]) != len(tt.expected) {
+			t.Errorf("sortBy=%q topK=%d: expected %d rules, got %v", tt.sortBy, tt.topK, len(tt.expected), ranked)
+			continue
+		}
+		for i, rule := range ranked[0] {
+			if rule.Consequent[0] != tt.expected[i] {
+				t.Errorf("sortBy=%q topK=%d: expected consequents %v, got %v", tt.sortBy, tt.topK, tt.expected, ranked[0])
+				break
+			}
+		}
+	}
+}
+
+func TestRankRulesTieBreak(t *testing.T) {
+	itemizer := newItemizer()
+	items := itemizer.Itemize([]string{"c", "b", "a"})
+	rules := [][]Rule{{
+		{Antecedent: items[:1], Consequent: items[1:2], Confidence: 0.5},
+		{Antecedent: items[1:2], Consequent: items[:1], Confidence: 0.5},
+		{Antecedent: items[1:2], Consequent: items[2:], Confidence: 0.5},
+	}}
+	ranked := rankRules(rules, "", 0, &itemizer)[0]
+	expected := []string{"b => a", "b => c", "c => b"}
+	for i, rule := range ranked {
+		name := itemizer.join(rule.Antecedent, " ") + " => " + itemizer.join(rule.Consequent, " ")
+		if name != expected[i] {
+			t.Errorf("expected rule %d to be %s, got %s", i, expected[i], name)
+		}
+	}
+}
+
+func TestRankRulesAntecedentSupport(t *testing.T) {
+	itemizer := newItemizer()
+	items := itemizer.Itemize([]string{"a", "b", "c"})
+	rules := [][]Rule{{
+		{Antecedent: items[:1], Consequent: items[2:], AntecedentSupport: 0.5, Support: 0.2},
+		{Antecedent: items[1:2], Consequent: items[2:], AntecedentSupport: 0.6, Support: 0.1},
+		{Antecedent: items[:1], Consequent: items[1:2], AntecedentSupport: 0.5, Support: 0.3},
+		{Antecedent: items[2:], Consequent: items[:1], AntecedentSupport: 0.5, Support: 0.3},
+	}}
+	ranked := rankRules(rules, SortByAntecedentSupport, 0, &itemizer)[0]
+	expected := []string{"b => c", "a => b", "c => a", "a => c"}
+	for i, rule := range ranked {
+		name := itemizer.join(rule.Antecedent, " ") + " => " + itemizer.join(rule.Consequent, " ")
+		if name != expected[i] {
+			t.Errorf("expected rule %d to be %s, got %s", i, expected[i], name)
+		}
+	}
+}
+
+func TestMineMaxItemsets(t *testing.T) {
+	lines := make([]string, 0)
+	for _, transaction := range GenerateSyntheticDataset(200, 10, 0.5, 3) {
+		lines = append(lines, strings.Join(transaction, ","))
+	}
+	// Rule generation panics if a subset of a retained itemset is missing.
+	result, err := Mine(ArgumentsV2{
+		ItemsReader:   readerOf(strings.Join(lines, "\n")),
+		MinSupport:    0.05,
+		MinConfidence: 0.1,
+		MaxItemsets:   25,
+	}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.itemsets) != 25 {
+		t.Fatalf("expected 25 itemsets, got %d", len(result.itemsets))
+	}
+	for i := 1; i < len(result.itemsets); i++ {
+		if result.itemsets[i].count > result.itemsets[i-1].count {
+			t.Errorf("expected itemsets by decreasing count, got %v", result.itemsets)
+			break
+		}
+	}
+}
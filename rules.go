@@ -17,18 +17,130 @@
 package arm
 
 import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
 	"sort"
 	"time"
 )
 
 // Rule represents an antecedent implies consequent rule, and stores its
-// support, confidence, and lift.
+// support, confidence, lift and other measures of interest.
 type Rule struct {
 	Antecedent []Item
 	Consequent []Item
 	Support    float64
 	Confidence float64
 	Lift       float64
+	// Support of the antecedent alone.
+	AntecedentSupport float64
+	// Difference between the support of the rule and the support it would
+	// have if antecedent and consequent were independent.
+	Leverage float64
+	// Ratio of how often the rule would be wrong if antecedent and
+	// consequent were independent to how often it is wrong. Infinite for
+	// rules which always hold.
+	Conviction float64
+	// Average of the confidence of the rule and of its reverse.
+	Kulczynski float64
+	// Confidence with Laplace smoothing, which discounts rules with little
+	// support. Equal to Confidence without smoothing.
+	CorrectedConfidence float64
+	// Confidence minus the support of the consequent, also known as added
+	// value: how much more often the consequent holds given the antecedent
+	// than in general.
+	ConfidenceLift float64
+	// Number of transactions the rule holds in beyond those expected if
+	// antecedent and consequent were independent, which is Leverage times
+	// the number of transactions.
+	ExcessCount float64
+	// Support of the rule over the support of either its antecedent or
+	// its consequent, which is the same for the rule and its reverse, so
+	// that it measures how similar the two sides are.
+	Jaccard float64
+	// Support, confidence and lift of the rule on the validation dataset,
+	// if ValidationReader is set, to compare with those it was mined with.
+	// 0 where the antecedent or consequent never occurs in it.
+	ValidationSupport    float64
+	ValidationConfidence float64
+	ValidationLift       float64
+	// Hash of the strings of the items of the antecedent and of the
+	// consequent, which is the same for the same rule in any run, whatever
+	// the order of its items. Set by Mine, but not by GenerateRules.
+	ID uint64
+}
+
+// MarshalJSON encodes the rule as encoding/json encodes its fields, except
+// that infinite and NaN measures, which JSON numbers cannot hold, are
+// encoded as the strings "Infinity", "-Infinity" and "NaN", so that the
+// JSON stays loadable, as with MetricOverflowJSONString. MetricOverflowClamp
+// and MetricOverflowSkip keep such measures out of rules in the first
+// place.
+func (r Rule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Antecedent           []Item
+		Consequent           []Item
+		Support              jsonFloat
+		Confidence           jsonFloat
+		Lift                 jsonFloat
+		AntecedentSupport    jsonFloat
+		Leverage             jsonFloat
+		Conviction           jsonFloat
+		Kulczynski           jsonFloat
+		CorrectedConfidence  jsonFloat
+		ConfidenceLift       jsonFloat
+		ExcessCount          jsonFloat
+		Jaccard              jsonFloat
+		ValidationSupport    jsonFloat
+		ValidationConfidence jsonFloat
+		ValidationLift       jsonFloat
+		ID                   uint64
+	}{
+		r.Antecedent, r.Consequent,
+		jsonFloat(r.Support), jsonFloat(r.Confidence), jsonFloat(r.Lift),
+		jsonFloat(r.AntecedentSupport), jsonFloat(r.Leverage), jsonFloat(r.Conviction),
+		jsonFloat(r.Kulczynski), jsonFloat(r.CorrectedConfidence), jsonFloat(r.ConfidenceLift),
+		jsonFloat(r.ExcessCount), jsonFloat(r.Jaccard), jsonFloat(r.ValidationSupport),
+		jsonFloat(r.ValidationConfidence), jsonFloat(r.ValidationLift), r.ID,
+	})
+}
+
+// jsonFloat is a float64 which encodes infinities and NaN as strings.
+type jsonFloat float64
+
+// jsonNullFloat is a float64 which encodes infinities and NaN as null.
+type jsonNullFloat float64
+
+func (f jsonNullFloat) MarshalJSON() ([]byte, error) {
+	if v := float64(f); math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(float64(f))
+}
+
+// jsonMeasure returns v, to be encoded as the MetricOverflowJSON encoding
+// asks.
+func jsonMeasure(v float64, encoding string) json.Marshaler {
+	if encoding == MetricOverflowJSONNull {
+		return jsonNullFloat(v)
+	}
+	return jsonFloat(v)
+}
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	switch v := float64(f); {
+	case math.IsNaN(v):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(v, 1):
+		return []byte(`"Infinity"`), nil
+	case math.IsInf(v, -1):
+		return []byte(`"-Infinity"`), nil
+	default:
+		return json.Marshal(v)
+	}
 }
 
 // NewRule creates a new rule.
@@ -42,68 +154,351 @@ func NewRule(antecedent []Item, consequent []Item, support float64, confidence f
 	}
 }
 
-type itemsetWithSupport struct {
-	itemset []Item
-	support float64
+// Itemset is a set of items, and the number of transactions it appears
+// in.
+type Itemset struct {
+	Items []Item
+	Count int
 }
 
-func (isl itemsetSupportLookup) Len() int {
-	return len(isl.itemsets)
+// RuleOptions selects the rules GenerateRules generates.
+type RuleOptions struct {
+	MinConfidence float64
+	// Rules must reach MinLift, or exceed it if LiftExclusive is set.
+	MinLift       float64
+	LiftExclusive bool
+	// Minimum ConfidenceLift of a rule. 0 means no minimum (optional).
+	MinConfidenceLift float64
+	// Minimum ExcessCount of a rule. 0 means no minimum (optional).
+	MinExcessCount int
+	// Minimum Jaccard of a rule. 0 means no minimum (optional).
+	MinJaccard float64
+	// Pseudo-count of Laplace smoothing for CorrectedConfidence (optional).
+	LaplaceSmoothing float64
+	// What to do with rules which have an infinite or NaN measure, as
+	// Arguments.MetricOverflow (optional).
+	MetricOverflow string
+	// Minimum number of transactions in which a rule holds (optional).
+	MinRuleSupportCount int
+	// Generate both rules of a pair of items if either of them qualifies
+	// (optional).
+	BothDirections bool
+	// Also generate the rule {} => B of each item B, whatever its measures
+	// (optional).
+	IncludeBaselineRules bool
+	// Logs progress on long runs (optional).
+	Log Logger
+	// Called with each itemset of two or more items which yields no rule,
+	// because every candidate rule of it is rejected: below MinConfidence,
+	// MinConfidenceLift, MinExcessCount, MinJaccard or MinLift, with an
+	// attribute on both sides, not a class rule, or with a measure
+	// MetricOverflow skips. With BothDirections, it is called for a pair
+	// when neither of its rules qualifies. It is not called for itemsets
+	// below MinRuleSupportCount, nor for those skipped for lacking the
+	// focus item or a label (optional).
+	NoRules func(Itemset)
+	// If set, only itemsets containing all of focus yield rules.
+	focus []Item
+	// If set, maps items to the index of their attribute, and rules with
+	// the same attribute on both sides are dropped.
+	attributes map[Item]int
+	// If set, only rules from items which are not labels to labels are
+	// generated.
+	labels map[Item]bool
+	// If set, rules are passed to emit as they are generated, rather than
+	// returned.
+	emit func(Rule)
+	// If set, called with the threshold each candidate rule is rejected
+	// by.
+	rejected func(rejection)
 }
 
-func (isl *itemsetSupportLookup) Swap(i, j int) {
-	isl.itemsets[i], isl.itemsets[j] = isl.itemsets[j], isl.itemsets[i]
+// rejection is the threshold a candidate rule is rejected by.
+type rejection int
+
+const (
+	notRejected rejection = iota
+	rejectedByConfidence
+	rejectedByConfidenceLift
+	rejectedByExcessCount
+	rejectedByJaccard
+	rejectedByLift
+	rejectedByAttributes
+	rejectedByLabels
+	rejectedByMetricOverflow
+	// Whole itemsets skipped before any of their candidate rules is made.
+	itemsetRejectedByRuleSupportCount
+	itemsetRejectedByFocus
+	itemsetRejectedByLabels
+)
+
+// reject records that a candidate rule, or a whole itemset, is rejected by
+// r, unless r is notRejected.
+func (opts RuleOptions) reject(r rejection) {
+	if r != notRejected && opts.rejected != nil {
+		opts.rejected(r)
+	}
 }
 
-func (isl *itemsetSupportLookup) Less(i, j int) bool {
-	return itemSliceLess(isl.itemsets[i].itemset, isl.itemsets[j].itemset)
+// GenerateRules generates the rules supported by itemsets, which may come
+// from any miner, and computes their measures. Every non-empty subset of
+// an itemset must be in itemsets too, with its count, or GenerateRules
+// panics.
+func GenerateRules(itemsets []Itemset, numTransactions int, opts RuleOptions) []Rule {
+	converted := make([]itemsetWithCount, len(itemsets))
+	for i, is := range itemsets {
+		items := append([]Item(nil), is.Items...)
+		sort.Slice(items, func(i, j int) bool {
+			return items[i] < items[j]
+		})
+		converted[i] = itemsetWithCount{items, is.Count}
+	}
+	if opts.Log == nil {
+		opts.Log = log.New(io.Discard, "", 0)
+	}
+	rules, _ := generateRules(context.Background(), converted, numTransactions, opts)
+	return flattenRules(rules)
 }
 
-type itemsetSupportLookup struct {
-	itemsets []itemsetWithSupport
+func (icl itemsetCountLookup) Len() int {
+	return len(icl.itemsets)
 }
 
-func newItemsetSupportLookup() *itemsetSupportLookup {
-	return &itemsetSupportLookup{
-		itemsets: make([]itemsetWithSupport, 0),
-	}
+func (icl *itemsetCountLookup) Swap(i, j int) {
+	icl.itemsets[i], icl.itemsets[j] = icl.itemsets[j], icl.itemsets[i]
 }
 
-func (isl *itemsetSupportLookup) insert(itemset []Item, support float64) {
-	isl.itemsets = append(isl.itemsets, itemsetWithSupport{itemset: itemset, support: support})
+func (icl *itemsetCountLookup) Less(i, j int) bool {
+	return itemSliceLess(icl.itemsets[i].itemset, icl.itemsets[j].itemset)
 }
 
-func (isl *itemsetSupportLookup) sort() {
-	sort.Sort(isl)
+// itemsetCountLookup finds the number of transactions containing a
+// frequent itemset. Counts are kept as integers, and only converted to
+// support when a rule is made, so that measures of rules are computed
+// with as few rounding steps as possible.
+type itemsetCountLookup struct {
+	itemsets []itemsetWithCount
 }
 
-func (isl *itemsetSupportLookup) lookup(itemset []Item) float64 {
-	idx := sort.Search(len(isl.itemsets), func(idx int) bool {
-		return !itemSliceLess(isl.itemsets[idx].itemset, itemset)
+func (icl *itemsetCountLookup) sort() {
+	sort.Sort(icl)
+}
+
+func (icl *itemsetCountLookup) lookup(itemset []Item) int {
+	idx := sort.Search(len(icl.itemsets), func(idx int) bool {
+		return !itemSliceLess(icl.itemsets[idx].itemset, itemset)
 	})
-	if !itemSliceEquals(isl.itemsets[idx].itemset, itemset) {
-		panic("Failed to retrieve itemset support")
+	if !itemSliceEquals(icl.itemsets[idx].itemset, itemset) {
+		panic("Failed to retrieve itemset count")
+	}
+	return icl.itemsets[idx].count
+}
+
+func createCountLookup(itemsets []itemsetWithCount) *itemsetCountLookup {
+	icl := &itemsetCountLookup{
+		itemsets: append([]itemsetWithCount(nil), itemsets...),
+	}
+	icl.sort()
+	return icl
+}
+
+// makeRule makes the rule a => c, where a and c together appear in acCount
+// of numTransactions transactions. The corrected confidence adds smoothing
+// to the count of transactions where the rule holds and where it does not.
+func makeRule(a []Item, c []Item, acCount int, countLookup *itemsetCountLookup, numTransactions int, smoothing float64) Rule {
+	aCount := countLookup.lookup(a)
+	cCount := countLookup.lookup(c)
+	n := float64(numTransactions)
+	ac := float64(acCount)
+	confidence := ac / float64(aCount)
+	rule := NewRule(a, c, ac/n, confidence, ac*n/(float64(aCount)*float64(cCount)))
+	rule.AntecedentSupport = float64(aCount) / n
+	rule.Leverage = (ac - float64(aCount)*float64(cCount)/n) / n
+	if acCount < aCount {
+		rule.Conviction = (n - float64(cCount)) * float64(aCount) / (n * float64(aCount-acCount))
+	} else {
+		rule.Conviction = math.Inf(1)
+	}
+	rule.Kulczynski = (confidence + ac/float64(cCount)) / 2
+	rule.CorrectedConfidence = (ac + smoothing) / (float64(aCount) + 2*smoothing)
+	rule.ConfidenceLift = confidence - float64(cCount)/n
+	// Exact when the excess is a whole number of transactions, so that it
+	// compares equal to MinExcessCount.
+	rule.ExcessCount = (ac*n - float64(aCount)*float64(cCount)) / n
+	rule.Jaccard = ac / (float64(aCount) + float64(cCount) - ac)
+	return rule
+}
+
+// bothDirections returns the two rules of the pair itemset, if either of
+// them is generated on its own, or else none, in which case both are
+// recorded as rejected.
+func bothDirections(itemset itemsetWithCount, countLookup *itemsetCountLookup, numTransactions int, opts RuleOptions) []Rule {
+	a, b := []Item{itemset.itemset[0]}, []Item{itemset.itemset[1]}
+	pair := []Rule{
+		makeRule(a, b, itemset.count, countLookup, numTransactions, opts.LaplaceSmoothing),
+		makeRule(b, a, itemset.count, countLookup, numTransactions, opts.LaplaceSmoothing),
+	}
+	var rejections [2]rejection
+	for i := range pair {
+		rejections[i] = rejectedByConfidence
+		if confident(&pair[i], itemset.count, countLookup, opts.MinConfidence) {
+			rejections[i] = rejectionOf(&pair[i], opts)
+		}
+		if rejections[i] == notRejected {
+			return pair
+		}
+	}
+	for _, r := range rejections {
+		opts.reject(r)
+	}
+	return nil
+}
+
+// confident reports whether rule, which holds in acCount transactions,
+// reaches minConfidence. A minConfidence of 1 keeps exactly the rules which
+// always hold, which is decided by comparing counts rather than rounded
+// ratios, and regardless of LaplaceSmoothing.
+func confident(rule *Rule, acCount int, countLookup *itemsetCountLookup, minConfidence float64) bool {
+	if minConfidence >= 1 {
+		return countLookup.lookup(rule.Antecedent) == acCount
+	}
+	return rule.CorrectedConfidence >= minConfidence
+}
+
+// rejectionOf returns what rule is rejected by, or notRejected if it is
+// to be output: if it improves enough on independence, its antecedent and
+// consequent share no attribute, and it is a class rule if labels are set.
+func rejectionOf(rule *Rule, opts RuleOptions) rejection {
+	if r := improves(rule, opts); r != notRejected {
+		return r
+	}
+	if !disjointAttributes(rule, opts.attributes) {
+		return rejectedByAttributes
+	}
+	if !classRule(rule, opts.labels) {
+		return rejectedByLabels
+	}
+	return notRejected
+}
+
+// classRule reports whether the consequent of rule holds only labels, and
+// its antecedent none, or whether labels is nil.
+func classRule(rule *Rule, labels map[Item]bool) bool {
+	if labels == nil {
+		return true
+	}
+	for _, a := range rule.Antecedent {
+		if labels[a] {
+			return false
+		}
+	}
+	for _, c := range rule.Consequent {
+		if !labels[c] {
+			return false
+		}
 	}
-	return isl.itemsets[idx].support
+	return true
 }
 
-func createSupportLookup(itemsets []itemsetWithCount, numTransactions int) *itemsetSupportLookup {
-	isl := newItemsetSupportLookup()
-	f := float64(numTransactions)
-	for _, is := range itemsets {
-		isl.insert(is.itemset, float64(is.count)/f)
+// disjointAttributes reports whether no item of the antecedent of rule has
+// the same attribute as an item of its consequent. Items without an
+// attribute share none.
+func disjointAttributes(rule *Rule, attributes map[Item]int) bool {
+	if attributes == nil {
+		return true
+	}
+	for _, a := range rule.Antecedent {
+		attribute, found := attributes[a]
+		if !found {
+			continue
+		}
+		for _, c := range rule.Consequent {
+			if other, found := attributes[c]; found && other == attribute {
+				return false
+			}
+		}
 	}
-	isl.sort()
+	return true
+}
 
-	return isl
+// hasLabel reports whether itemset holds an item of labels.
+func hasLabel(itemset []Item, labels map[Item]bool) bool {
+	for _, item := range itemset {
+		if labels[item] {
+			return true
+		}
+	}
+	return false
 }
 
-func makeStats(a []Item, c []Item, ac []Item, acSup float64, supportLookup *itemsetSupportLookup) (float64, float64) {
-	aSup := supportLookup.lookup(a)
-	confidence := acSup / aSup
-	cSup := supportLookup.lookup(c)
-	lift := acSup / (aSup * cSup)
-	return confidence, lift
+// improves returns the first of the MinConfidenceLift, MinExcessCount,
+// MinJaccard and MinLift of opts which rule falls below, or notRejected if
+// it passes them all.
+func improves(rule *Rule, opts RuleOptions) rejection {
+	if opts.MinConfidenceLift != 0 && rule.ConfidenceLift < opts.MinConfidenceLift {
+		return rejectedByConfidenceLift
+	}
+	if opts.MinExcessCount != 0 && rule.ExcessCount < float64(opts.MinExcessCount) {
+		return rejectedByExcessCount
+	}
+	if opts.MinJaccard != 0 && rule.Jaccard < opts.MinJaccard {
+		return rejectedByJaccard
+	}
+	if opts.LiftExclusive {
+		if !(rule.Lift > opts.MinLift) {
+			return rejectedByLift
+		}
+	} else if !(rule.Lift >= opts.MinLift) {
+		return rejectedByLift
+	}
+	return notRejected
+}
+
+// limitMetrics applies the MetricOverflow policy to the measures of rule,
+// and reports whether rule is kept.
+func limitMetrics(rule *Rule, policy string) bool {
+	if policy == "" || policy == MetricOverflowEmit {
+		return true
+	}
+	metrics := []*float64{
+		&rule.Support, &rule.Confidence, &rule.Lift, &rule.Leverage,
+		&rule.Conviction, &rule.Kulczynski, &rule.CorrectedConfidence,
+		&rule.ConfidenceLift, &rule.ExcessCount, &rule.Jaccard,
+	}
+	for _, m := range metrics {
+		if !math.IsInf(*m, 0) && !math.IsNaN(*m) {
+			continue
+		}
+		if policy == MetricOverflowSkip {
+			return false
+		}
+		switch {
+		case math.IsNaN(*m):
+			*m = 0
+		case *m > 0:
+			*m = maxMetric
+		default:
+			*m = -maxMetric
+		}
+	}
+	return true
+}
+
+// ruleID returns the ID of rule, a 64-bit FNV-1a hash of the sorted
+// strings of the antecedent, then of the consequent.
+func ruleID(rule *Rule, itemizer *Itemizer) uint64 {
+	h := fnv.New64a()
+	for _, side := range [][]Item{rule.Antecedent, rule.Consequent} {
+		strs := itemizer.strings(side)
+		sort.Strings(strs)
+		for _, s := range strs {
+			h.Write([]byte(s))
+			h.Write([]byte{0})
+		}
+		// Tell the sides apart, so that a => c and c => a differ.
+		h.Write([]byte{1})
+	}
+	return h.Sum64()
 }
 
 func itemSliceLess(a, b []Item) bool {
@@ -145,7 +540,10 @@ func prefixMatchLen(a []Item, b []Item) int {
 	return len(a)
 }
 
-func generateRules(itemsets []itemsetWithCount, numTransactions int, minConfidence float64, minLift float64, log Logger) [][]Rule {
+// generateRules generates the rules which itemsets support. If ctx is done
+// before all itemsets are processed, the rules generated so far are
+// returned along with ctx.Err().
+func generateRules(ctx context.Context, itemsets []itemsetWithCount, numTransactions int, opts RuleOptions) ([][]Rule, error) {
 	// Output rules are stored in a slice of slices. As we generate rules, we
 	// store them in a slice with capacity `chunkSize`. When the slice fills up,
 	// we append it to the output set. If we instead stuck all the rules in a
@@ -154,36 +552,89 @@ func generateRules(itemsets []itemsetWithCount, numTransactions int, minConfiden
 	output := make([][]Rule, 0)
 	const chunkSize int = 10000
 	rules := make([]Rule, 0, chunkSize)
-	itemsetSupport := createSupportLookup(itemsets, numTransactions)
+	lookupItemsets := itemsets
+	if opts.IncludeBaselineRules {
+		// The empty antecedent of baseline rules holds in every transaction.
+		lookupItemsets = append(itemsets[:len(itemsets):len(itemsets)],
+			itemsetWithCount{itemset: []Item{}, count: numTransactions})
+	}
+	itemsetCount := createCountLookup(lookupItemsets)
+
+	add := func(rule Rule) {
+		if !limitMetrics(&rule, opts.MetricOverflow) {
+			opts.reject(rejectedByMetricOverflow)
+			return
+		}
+		if opts.emit != nil {
+			opts.emit(rule)
+			return
+		}
+		rules = append(rules, rule)
+		if len(rules) == chunkSize {
+			output = append(output, rules)
+			rules = make([]Rule, 0, chunkSize)
+		}
+	}
 
 	lastFeedback := time.Now()
 
 	for index, itemset := range itemsets {
-		support := float64(itemset.count) / float64(numTransactions)
+		if err := ctx.Err(); err != nil {
+			if len(rules) > 0 {
+				output = append(output, rules)
+			}
+			return output, err
+		}
 		if time.Since(lastFeedback).Seconds() > 20 {
 			lastFeedback = time.Now()
 			percentComplete := int(float64(index)/float64(countRules(output)+len(rules))*100 + 0.5)
-			log.Printf("Progress: %d of %d itemsets processed (%d%%), generated %d rules so far",
+			opts.Log.Printf("Progress: %d of %d itemsets processed (%d%%), generated %d rules so far",
 				index, len(itemsets), percentComplete, len(rules))
 		}
+		if opts.IncludeBaselineRules && len(itemset.itemset) == 1 && (opts.labels == nil || opts.labels[itemset.itemset[0]]) {
+			add(makeRule([]Item{}, itemset.itemset, itemset.count, itemsetCount, numTransactions, opts.LaplaceSmoothing))
+		}
 		if len(itemset.itemset) < 2 {
 			continue
 		}
+		if itemset.count < opts.MinRuleSupportCount {
+			opts.reject(itemsetRejectedByRuleSupportCount)
+			continue
+		}
+		if opts.focus != nil && !containsItems(itemset.itemset, opts.focus) {
+			opts.reject(itemsetRejectedByFocus)
+			continue
+		}
+		if opts.labels != nil && !hasLabel(itemset.itemset, opts.labels) {
+			opts.reject(itemsetRejectedByLabels)
+			continue
+		}
+		if opts.BothDirections && len(itemset.itemset) == 2 {
+			pair := bothDirections(itemset, itemsetCount, numTransactions, opts)
+			for _, rule := range pair {
+				add(rule)
+			}
+			if pair == nil && opts.NoRules != nil {
+				opts.NoRules(Itemset{Items: itemset.itemset, Count: itemset.count})
+			}
+			continue
+		}
+		found := false
 		// First generation is all possible rules with consequents of size 1.
 		candidates := make([][]Item, 0)
 		for _, item := range itemset.itemset {
 			consequent := []Item{item}
 			antecedent := setMinus(itemset.itemset, consequent)
-			confidence, lift := makeStats(antecedent, consequent, itemset.itemset, support, itemsetSupport)
-			if confidence < minConfidence {
+			rule := makeRule(antecedent, consequent, itemset.count, itemsetCount, numTransactions, opts.LaplaceSmoothing)
+			if !confident(&rule, itemset.count, itemsetCount, opts.MinConfidence) {
+				opts.reject(rejectedByConfidence)
 				continue
 			}
-			if lift >= minLift {
-				rules = append(rules, NewRule(antecedent, consequent, support, confidence, lift))
-				if len(rules) == chunkSize {
-					output = append(output, rules)
-					rules = make([]Rule, 0, chunkSize)
-				}
+			if r := rejectionOf(&rule, opts); r == notRejected {
+				found = true
+				add(rule)
+			} else {
+				opts.reject(r)
 			}
 			candidates = append(candidates, consequent)
 		}
@@ -211,27 +662,30 @@ func generateRules(itemsets []itemsetWithCount, numTransactions int, minConfiden
 					consequent := union(c1, candidates[idx2])
 					antecedent := setMinus(itemset.itemset, consequent)
 
-					confidence, lift := makeStats(antecedent, consequent, itemset.itemset, support, itemsetSupport)
-					if confidence < minConfidence {
+					rule := makeRule(antecedent, consequent, itemset.count, itemsetCount, numTransactions, opts.LaplaceSmoothing)
+					if !confident(&rule, itemset.count, itemsetCount, opts.MinConfidence) {
+						opts.reject(rejectedByConfidence)
 						continue
 					}
 					nextGen = append(nextGen, consequent)
-					if lift >= minLift {
-						rules = append(rules, NewRule(antecedent, consequent, support, confidence, lift))
-						if len(rules) == chunkSize {
-							output = append(output, rules)
-							rules = make([]Rule, 0, chunkSize)
-						}
+					if r := rejectionOf(&rule, opts); r == notRejected {
+						found = true
+						add(rule)
+					} else {
+						opts.reject(r)
 					}
 				}
 			}
 			candidates = nextGen
 			sortCandidates(candidates)
 		}
+		if !found && opts.NoRules != nil {
+			opts.NoRules(Itemset{Items: itemset.itemset, Count: itemset.count})
+		}
 	}
 
 	if len(rules) > 0 {
 		output = append(output, rules)
 	}
-	return output
+	return output, nil
 }
@@ -17,8 +17,15 @@
 package arm
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"math"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -83,67 +90,70 @@ func TestGenerateRules(t *testing.T) {
 	}
 
 	expectedRules := []Rule{
-		Rule{[]Item{6}, []Item{1, 11}, 0.0870, 0.143, 1.542},
-		Rule{[]Item{11}, []Item{1, 6}, 0.0870, 0.236, 1.772},
-		Rule{[]Item{218}, []Item{148}, 0.059, 0.664, 9.400},
-		Rule{[]Item{148, 218}, []Item{6}, 0.057, 0.966, 1.591},
-		Rule{[]Item{1, 6}, []Item{11}, 0.087, 0.652, 1.772},
-		Rule{[]Item{11, 218}, []Item{6, 148}, 0.050, 0.809, 12.366},
-		Rule{[]Item{11}, []Item{7}, 0.058, 0.157, 1.786},
-		Rule{[]Item{11}, []Item{6, 148, 218}, 0.050, 0.137, 2.386},
-		Rule{[]Item{11}, []Item{148, 218}, 0.051, 0.138, 2.316},
-		Rule{[]Item{11, 218}, []Item{6}, 0.061, 0.983, 1.619},
-		Rule{[]Item{7, 11}, []Item{6}, 0.056, 0.978, 1.610},
-		Rule{[]Item{148}, []Item{11}, 0.056, 0.797, 2.168},
-		Rule{[]Item{11}, []Item{6, 148}, 0.056, 0.152, 2.319},
-		Rule{[]Item{218}, []Item{11}, 0.062, 0.696, 1.892},
-		Rule{[]Item{218}, []Item{11, 148}, 0.051, 0.565, 10.040},
-		Rule{[]Item{148}, []Item{6}, 0.065, 0.926, 1.524},
-		Rule{[]Item{6, 11}, []Item{148}, 0.056, 0.170, 2.413},
-		Rule{[]Item{11}, []Item{6, 7}, 0.056, 0.153, 2.063},
-		Rule{[]Item{11, 148}, []Item{218}, 0.051, 0.898, 10.040},
-		Rule{[]Item{148}, []Item{6, 11, 218}, 0.050, 0.713, 11.645},
-		Rule{[]Item{6}, []Item{11, 148, 218}, 0.050, 0.083, 1.639},
-		Rule{[]Item{7}, []Item{6, 11}, 0.056, 0.643, 1.963},
-		Rule{[]Item{6, 11, 148}, []Item{218}, 0.050, 0.903, 10.089},
-		Rule{[]Item{148}, []Item{6, 218}, 0.057, 0.813, 10.360},
-		Rule{[]Item{148}, []Item{6, 11}, 0.056, 0.790, 2.413},
-		Rule{[]Item{6, 148}, []Item{218}, 0.057, 0.878, 9.809},
-		Rule{[]Item{11}, []Item{148}, 0.056, 0.153, 2.168},
-		Rule{[]Item{11, 148}, []Item{6}, 0.056, 0.991, 1.631},
-		Rule{[]Item{6, 148, 218}, []Item{11}, 0.050, 0.877, 2.386},
-		Rule{[]Item{6}, []Item{148, 218}, 0.057, 0.095, 1.591},
-		Rule{[]Item{11}, []Item{6, 218}, 0.061, 0.167, 2.123},
-		Rule{[]Item{218}, []Item{6, 148}, 0.057, 0.642, 9.809},
-		Rule{[]Item{6, 148}, []Item{11}, 0.056, 0.853, 2.319},
-		Rule{[]Item{6, 11}, []Item{7}, 0.056, 0.172, 1.963},
-		Rule{[]Item{218}, []Item{6, 11, 148}, 0.050, 0.563, 10.089},
-		Rule{[]Item{148, 218}, []Item{11}, 0.051, 0.852, 2.316},
-		Rule{[]Item{6, 148}, []Item{11, 218}, 0.050, 0.770, 12.366},
-		Rule{[]Item{148}, []Item{11, 218}, 0.051, 0.716, 11.504},
-		Rule{[]Item{218}, []Item{6, 11}, 0.061, 0.684, 2.091},
-		Rule{[]Item{11, 148, 218}, []Item{6}, 0.050, 0.995, 1.639},
-		Rule{[]Item{11}, []Item{218}, 0.062, 0.169, 1.892},
-		Rule{[]Item{1, 11}, []Item{6}, 0.087, 0.937, 1.542},
-		Rule{[]Item{6, 11}, []Item{218}, 0.061, 0.187, 2.091},
-		Rule{[]Item{6}, []Item{148}, 0.065, 0.108, 1.524},
-		Rule{[]Item{6}, []Item{11, 148}, 0.056, 0.092, 1.631},
-		Rule{[]Item{148, 218}, []Item{6, 11}, 0.050, 0.848, 2.590},
-		Rule{[]Item{6, 218}, []Item{11}, 0.061, 0.781, 2.123},
-		Rule{[]Item{6, 7}, []Item{11}, 0.056, 0.759, 2.063},
-		Rule{[]Item{6}, []Item{11, 218}, 0.061, 0.101, 1.619},
-		Rule{[]Item{11, 218}, []Item{148}, 0.051, 0.813, 11.504},
-		Rule{[]Item{6, 11}, []Item{148, 218}, 0.050, 0.154, 2.590},
-		Rule{[]Item{148}, []Item{218}, 0.059, 0.841, 9.400},
-		Rule{[]Item{7}, []Item{11}, 0.058, 0.657, 1.786},
-		Rule{[]Item{6, 218}, []Item{11, 148}, 0.050, 0.642, 11.398},
-		Rule{[]Item{6, 11, 218}, []Item{148}, 0.050, 0.822, 11.645},
-		Rule{[]Item{6, 218}, []Item{148}, 0.057, 0.732, 10.360},
-		Rule{[]Item{6}, []Item{7, 11}, 0.056, 0.093, 1.610},
-		Rule{[]Item{11, 148}, []Item{6, 218}, 0.050, 0.894, 11.398},
-	}
-
-	rules := generateRules(itemsets, 990002, 0.05, 1.5, log.Default())
+		NewRule([]Item{6}, []Item{1, 11}, 0.0870, 0.143, 1.542),
+		NewRule([]Item{11}, []Item{1, 6}, 0.0870, 0.236, 1.772),
+		NewRule([]Item{218}, []Item{148}, 0.059, 0.664, 9.400),
+		NewRule([]Item{148, 218}, []Item{6}, 0.057, 0.966, 1.591),
+		NewRule([]Item{1, 6}, []Item{11}, 0.087, 0.652, 1.772),
+		NewRule([]Item{11, 218}, []Item{6, 148}, 0.050, 0.809, 12.366),
+		NewRule([]Item{11}, []Item{7}, 0.058, 0.157, 1.786),
+		NewRule([]Item{11}, []Item{6, 148, 218}, 0.050, 0.137, 2.386),
+		NewRule([]Item{11}, []Item{148, 218}, 0.051, 0.138, 2.316),
+		NewRule([]Item{11, 218}, []Item{6}, 0.061, 0.983, 1.619),
+		NewRule([]Item{7, 11}, []Item{6}, 0.056, 0.978, 1.610),
+		NewRule([]Item{148}, []Item{11}, 0.056, 0.797, 2.168),
+		NewRule([]Item{11}, []Item{6, 148}, 0.056, 0.152, 2.319),
+		NewRule([]Item{218}, []Item{11}, 0.062, 0.696, 1.892),
+		NewRule([]Item{218}, []Item{11, 148}, 0.051, 0.565, 10.040),
+		NewRule([]Item{148}, []Item{6}, 0.065, 0.926, 1.524),
+		NewRule([]Item{6, 11}, []Item{148}, 0.056, 0.170, 2.413),
+		NewRule([]Item{11}, []Item{6, 7}, 0.056, 0.153, 2.063),
+		NewRule([]Item{11, 148}, []Item{218}, 0.051, 0.898, 10.040),
+		NewRule([]Item{148}, []Item{6, 11, 218}, 0.050, 0.713, 11.645),
+		NewRule([]Item{6}, []Item{11, 148, 218}, 0.050, 0.083, 1.639),
+		NewRule([]Item{7}, []Item{6, 11}, 0.056, 0.643, 1.963),
+		NewRule([]Item{6, 11, 148}, []Item{218}, 0.050, 0.903, 10.089),
+		NewRule([]Item{148}, []Item{6, 218}, 0.057, 0.813, 10.360),
+		NewRule([]Item{148}, []Item{6, 11}, 0.056, 0.790, 2.413),
+		NewRule([]Item{6, 148}, []Item{218}, 0.057, 0.878, 9.809),
+		NewRule([]Item{11}, []Item{148}, 0.056, 0.153, 2.168),
+		NewRule([]Item{11, 148}, []Item{6}, 0.056, 0.991, 1.631),
+		NewRule([]Item{6, 148, 218}, []Item{11}, 0.050, 0.877, 2.386),
+		NewRule([]Item{6}, []Item{148, 218}, 0.057, 0.095, 1.591),
+		NewRule([]Item{11}, []Item{6, 218}, 0.061, 0.167, 2.123),
+		NewRule([]Item{218}, []Item{6, 148}, 0.057, 0.642, 9.809),
+		NewRule([]Item{6, 148}, []Item{11}, 0.056, 0.853, 2.319),
+		NewRule([]Item{6, 11}, []Item{7}, 0.056, 0.172, 1.963),
+		NewRule([]Item{218}, []Item{6, 11, 148}, 0.050, 0.563, 10.089),
+		NewRule([]Item{148, 218}, []Item{11}, 0.051, 0.852, 2.316),
+		NewRule([]Item{6, 148}, []Item{11, 218}, 0.050, 0.770, 12.366),
+		NewRule([]Item{148}, []Item{11, 218}, 0.051, 0.716, 11.504),
+		NewRule([]Item{218}, []Item{6, 11}, 0.061, 0.684, 2.091),
+		NewRule([]Item{11, 148, 218}, []Item{6}, 0.050, 0.995, 1.639),
+		NewRule([]Item{11}, []Item{218}, 0.062, 0.169, 1.892),
+		NewRule([]Item{1, 11}, []Item{6}, 0.087, 0.937, 1.542),
+		NewRule([]Item{6, 11}, []Item{218}, 0.061, 0.187, 2.091),
+		NewRule([]Item{6}, []Item{148}, 0.065, 0.108, 1.524),
+		NewRule([]Item{6}, []Item{11, 148}, 0.056, 0.092, 1.631),
+		NewRule([]Item{148, 218}, []Item{6, 11}, 0.050, 0.848, 2.590),
+		NewRule([]Item{6, 218}, []Item{11}, 0.061, 0.781, 2.123),
+		NewRule([]Item{6, 7}, []Item{11}, 0.056, 0.759, 2.063),
+		NewRule([]Item{6}, []Item{11, 218}, 0.061, 0.101, 1.619),
+		NewRule([]Item{11, 218}, []Item{148}, 0.051, 0.813, 11.504),
+		NewRule([]Item{6, 11}, []Item{148, 218}, 0.050, 0.154, 2.590),
+		NewRule([]Item{148}, []Item{218}, 0.059, 0.841, 9.400),
+		NewRule([]Item{7}, []Item{11}, 0.058, 0.657, 1.786),
+		NewRule([]Item{6, 218}, []Item{11, 148}, 0.050, 0.642, 11.398),
+		NewRule([]Item{6, 11, 218}, []Item{148}, 0.050, 0.822, 11.645),
+		NewRule([]Item{6, 218}, []Item{148}, 0.057, 0.732, 10.360),
+		NewRule([]Item{6}, []Item{7, 11}, 0.056, 0.093, 1.610),
+		NewRule([]Item{11, 148}, []Item{6, 218}, 0.050, 0.894, 11.398),
+	}
+
+	rules, err := generateRules(context.Background(), itemsets, 990002, RuleOptions{MinConfidence: 0.05, MinLift: 1.5, Log: log.Default()})
+	if err != nil {
+		t.Fatal(err)
+	}
 	log.Printf("Generated %d rules", len(rules))
 	for _, rule := range rules {
 		log.Print(rule)
@@ -169,3 +179,443 @@ func TestGenerateRules(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateRulesConfidenceBoundary(t *testing.T) {
+	// The confidence of {1} => {2} is exactly 3/4, which computing from
+	// supports, (3/5)/(4/5), would round to just below 0.75.
+	itemsets := []itemsetWithCount{
+		{[]Item{1}, 4},
+		{[]Item{2}, 3},
+		{[]Item{1, 2}, 3},
+	}
+	rules, err := generateRules(context.Background(), itemsets, 5, RuleOptions{MinConfidence: 0.75, Log: log.Default()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, found := find(rules, &Rule{Antecedent: []Item{1}, Consequent: []Item{2}})
+	if !found {
+		t.Fatal("expected rule with confidence at the threshold to be generated")
+	}
+	if r.Confidence != 0.75 {
+		t.Errorf("expected confidence 0.75, got %v", r.Confidence)
+	}
+}
+
+func TestGenerateRulesCancelled(t *testing.T) {
+	a, b, c, d := Item(1), Item(2), Item(3), Item(4)
+	itemsets := []itemsetWithCount{
+		{[]Item{a}, 4}, {[]Item{b}, 4}, {[]Item{c}, 5}, {[]Item{d}, 5},
+		{[]Item{a, b}, 4},
+		// Yields no rule, which cancels the rest.
+		{[]Item{c, d}, 1},
+		{[]Item{b, c}, 4},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := RuleOptions{
+		MinConfidence: 0.5,
+		Log:           log.New(io.Discard, "", 0),
+		NoRules:       func(Itemset) { cancel() },
+	}
+	rules, err := generateRules(ctx, itemsets, 10, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	var partial []Rule
+	for _, chunk := range rules {
+		partial = append(partial, chunk...)
+	}
+	if len(partial) != 2 {
+		t.Fatalf("expected the 2 rules of {a, b} generated before cancelling, got %v", partial)
+	}
+	for _, rule := range partial {
+		if !ruleEquals(&rule, &Rule{Antecedent: []Item{a}, Consequent: []Item{b}}) &&
+			!ruleEquals(&rule, &Rule{Antecedent: []Item{b}, Consequent: []Item{a}}) {
+			t.Errorf("expected only rules of {a, b}, got %v", rule)
+		}
+	}
+}
+
+func TestGenerateRulesPublic(t *testing.T) {
+	// Items need not be sorted within an itemset.
+	itemsets := []Itemset{
+		{[]Item{2, 1}, 3},
+		{[]Item{1}, 4},
+		{[]Item{2}, 3},
+	}
+	rules := GenerateRules(itemsets, 5, RuleOptions{MinConfidence: 0.8})
+	if len(rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(rules))
+	}
+	expected := Rule{Antecedent: []Item{2}, Consequent: []Item{1}}
+	if !ruleEquals(&rules[0], &expected) || rules[0].Confidence != 1 {
+		t.Errorf("expected %v with confidence 1, got %v", expected, rules[0])
+	}
+}
+
+func TestGenerateRulesLaplaceSmoothing(t *testing.T) {
+	// {1} => {2} always holds, but has been seen only once.
+	itemsets := []itemsetWithCount{
+		{[]Item{1}, 1},
+		{[]Item{2}, 1},
+		{[]Item{1, 2}, 1},
+	}
+	opts := RuleOptions{MinConfidence: 0.8, LaplaceSmoothing: 1, Log: log.Default()}
+	rules, err := generateRules(context.Background(), itemsets, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countRules(rules); n != 0 {
+		t.Errorf("expected corrected confidence 2/3 to filter out the rules, got %d", n)
+	}
+
+	opts.MinConfidence = 0.5
+	rules, err = generateRules(context.Background(), itemsets, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, found := find(rules, &Rule{Antecedent: []Item{1}, Consequent: []Item{2}})
+	if !found {
+		t.Fatal("expected rule to be generated")
+	}
+	if r.Confidence != 1 || math.Abs(r.CorrectedConfidence-2.0/3) > 1e-9 {
+		t.Errorf("expected confidence 1 and corrected 2/3, got %v and %v", r.Confidence, r.CorrectedConfidence)
+	}
+}
+
+func TestGenerateRulesMetricOverflow(t *testing.T) {
+	// {2} => {1} always holds, so its conviction is infinite.
+	itemsets := []Itemset{
+		{[]Item{1, 2}, 3},
+		{[]Item{1}, 4},
+		{[]Item{2}, 3},
+	}
+	for _, tt := range []struct {
+		policy     string
+		numRules   int
+		conviction float64
+	}{
+		{MetricOverflowEmit, 2, math.Inf(1)},
+		{MetricOverflowSkip, 1, 0},
+		{MetricOverflowClamp, 2, maxMetric},
+	} {
+		rules := GenerateRules(itemsets, 5, RuleOptions{MetricOverflow: tt.policy})
+		if len(rules) != tt.numRules {
+			t.Errorf("%s: expected %d rules, got %d", tt.policy, tt.numRules, len(rules))
+			continue
+		}
+		for _, rule := range rules {
+			if rule.Antecedent[0] == 2 && rule.Conviction != tt.conviction {
+				t.Errorf("%s: expected conviction %f, got %f", tt.policy, tt.conviction, rule.Conviction)
+			}
+			if tt.policy == MetricOverflowSkip && rule.Antecedent[0] == 2 {
+				t.Errorf("%s: expected the rule which always holds to be dropped", tt.policy)
+			}
+		}
+	}
+}
+
+func TestMineRuleIDs(t *testing.T) {
+	ids := func(input string) map[string]uint64 {
+		result, err := Mine(ArgumentsV2{
+			ItemsReader:   readerOf(input),
+			MinSupport:    0.2,
+			MinConfidence: 0.2,
+		}, log.New(io.Discard, "", 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		byName := make(map[string]uint64)
+		for _, rule := range result.Rules {
+			antecedent := result.Itemizer.strings(rule.Antecedent)
+			sort.Strings(antecedent)
+			consequent := result.Itemizer.strings(rule.Consequent)
+			sort.Strings(consequent)
+			byName[strings.Join(antecedent, " ")+" => "+strings.Join(consequent, " ")] = rule.ID
+		}
+		return byName
+	}
+	// The same transactions, with items first seen in another order.
+	expected := ids("milk,bread\nmilk,bread,eggs\nbread,eggs\nmilk,eggs\nmilk,bread\n")
+	actual := ids("eggs,bread\nmilk,eggs,bread\nbread,milk\nmilk,eggs\nmilk,bread\n")
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected the same IDs in both runs, got %v and %v", expected, actual)
+	}
+	if expected["bread => milk"] == expected["milk => bread"] {
+		t.Error("expected a rule and its reverse to have different IDs")
+	}
+}
+
+func TestGenerateRulesMinConfidenceOne(t *testing.T) {
+	// Every transaction with a holds b, but not the other way around.
+	result, err := Mine(ArgumentsV2{
+		ItemsReader:      readerOf("a,b\na,b\na,b,c\nb,c\nc\nb\n"),
+		MinSupport:       0.1,
+		MinConfidence:    1,
+		LaplaceSmoothing: 1,
+	}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range result.Rules {
+		if rule.Confidence != 1 {
+			t.Errorf("expected only rules which always hold, got %v", rule)
+		}
+	}
+	a, _ := result.Itemizer.lookup("a")
+	b, _ := result.Itemizer.lookup("b")
+	found := false
+	for _, rule := range result.Rules {
+		found = found || ruleEquals(&rule, &Rule{Antecedent: []Item{a}, Consequent: []Item{b}})
+	}
+	if !found {
+		t.Errorf("expected a => b, got %v", result.Rules)
+	}
+}
+
+func TestGenerateRulesMinRuleSupportCount(t *testing.T) {
+	itemsets := []Itemset{
+		{[]Item{1}, 4},
+		{[]Item{2}, 3},
+		{[]Item{3}, 2},
+		{[]Item{1, 2}, 3},
+		{[]Item{1, 3}, 2},
+	}
+	rules := GenerateRules(itemsets, 5, RuleOptions{MinRuleSupportCount: 3})
+	for _, rule := range rules {
+		if rule.Support < 0.6 {
+			t.Errorf("expected rules holding in at least 3 transactions, got %v", rule)
+		}
+	}
+	if len(rules) != 2 {
+		t.Errorf("expected the 2 rules of {1, 2}, got %d", len(rules))
+	}
+}
+
+func TestGenerateRulesMinConfidenceLift(t *testing.T) {
+	itemsets := []Itemset{
+		{[]Item{1}, 4},
+		{[]Item{2}, 3},
+		{[]Item{1, 2}, 3},
+	}
+	// 1 => 2 has confidence 0.75 against a base rate of 0.6, and 2 => 1 has
+	// confidence 1 against 0.8.
+	rules := GenerateRules(itemsets, 5, RuleOptions{MinConfidenceLift: 0.18})
+	if len(rules) != 1 || !ruleEquals(&rules[0], &Rule{Antecedent: []Item{2}, Consequent: []Item{1}}) {
+		t.Fatalf("expected only 2 => 1, got %v", rules)
+	}
+	if math.Abs(rules[0].ConfidenceLift-0.2) > 1e-9 {
+		t.Errorf("expected ConfidenceLift 0.2, got %f", rules[0].ConfidenceLift)
+	}
+	if rules := GenerateRules(itemsets, 5, RuleOptions{}); len(rules) != 2 {
+		t.Errorf("expected both rules without MinConfidenceLift, got %v", rules)
+	}
+}
+
+func TestGenerateRulesMinExcessCount(t *testing.T) {
+	itemsets := []Itemset{
+		{[]Item{1}, 4},
+		{[]Item{2}, 5},
+		{[]Item{3}, 5},
+		{[]Item{1, 2}, 4},
+		{[]Item{1, 3}, 3},
+	}
+	// Of 10 transactions, {1, 2} is expected in 2 but appears in 4, while
+	// {1, 3} is expected in 2 and appears in 3.
+	rules := GenerateRules(itemsets, 10, RuleOptions{MinExcessCount: 2})
+	if len(rules) != 2 {
+		t.Fatalf("expected the 2 rules of {1, 2}, got %v", rules)
+	}
+	for _, rule := range rules {
+		if rule.ExcessCount != 2 {
+			t.Errorf("expected ExcessCount 2, got %v", rule)
+		}
+	}
+	if rules := GenerateRules(itemsets, 10, RuleOptions{}); len(rules) != 4 {
+		t.Errorf("expected 4 rules without MinExcessCount, got %v", rules)
+	}
+}
+
+func TestGenerateRulesMinJaccard(t *testing.T) {
+	itemsets := []Itemset{
+		{[]Item{1}, 4},
+		{[]Item{2}, 4},
+		{[]Item{3}, 6},
+		{[]Item{1, 2}, 3},
+		{[]Item{1, 3}, 2},
+	}
+	// {1, 2} occurs in 3 of the 5 transactions holding 1 or 2, while
+	// {1, 3} occurs in 2 of the 8 holding 1 or 3.
+	rules := GenerateRules(itemsets, 10, RuleOptions{MinJaccard: 0.5})
+	if len(rules) != 2 {
+		t.Fatalf("expected the 2 rules of {1, 2}, got %v", rules)
+	}
+	for _, rule := range rules {
+		if rule.Jaccard != 0.6 {
+			t.Errorf("expected Jaccard 0.6, got %v", rule)
+		}
+	}
+	if rules := GenerateRules(itemsets, 10, RuleOptions{}); len(rules) != 4 {
+		t.Errorf("expected 4 rules without MinJaccard, got %v", rules)
+	}
+}
+
+func TestGenerateRulesLiftExclusive(t *testing.T) {
+	itemsets := []Itemset{
+		{[]Item{1}, 2},
+		{[]Item{2}, 5},
+		{[]Item{3}, 5},
+		{[]Item{1, 2}, 1},
+		{[]Item{1, 3}, 2},
+	}
+	// Of 10 transactions, {1, 2} has lift exactly 1 and {1, 3} has lift 2.
+	rules := GenerateRules(itemsets, 10, RuleOptions{MinLift: 1})
+	if len(rules) != 4 {
+		t.Fatalf("expected the 4 rules at or above lift 1 by default, got %v", rules)
+	}
+	for _, rule := range rules {
+		if rule.Lift != 1 && rule.Lift != 2 {
+			t.Errorf("expected lift 1 or 2, got %v", rule)
+		}
+	}
+	rules = GenerateRules(itemsets, 10, RuleOptions{MinLift: 1, LiftExclusive: true})
+	if len(rules) != 2 {
+		t.Fatalf("expected the 2 rules of {1, 3} with LiftExclusive, got %v", rules)
+	}
+	for _, rule := range rules {
+		if rule.Lift != 2 {
+			t.Errorf("expected lift 2, got %v", rule)
+		}
+	}
+}
+
+func TestGenerateRulesIncludeBaselineRules(t *testing.T) {
+	itemsets := []Itemset{
+		{[]Item{1}, 4},
+		{[]Item{2}, 3},
+		{[]Item{1, 2}, 3},
+	}
+	rules := GenerateRules(itemsets, 5, RuleOptions{MinConfidence: 0.9, IncludeBaselineRules: true})
+	baselines := 0
+	for _, rule := range rules {
+		if len(rule.Antecedent) > 0 {
+			continue
+		}
+		baselines++
+		if rule.Confidence != rule.Support || rule.Lift != 1 || rule.AntecedentSupport != 1 {
+			t.Errorf("expected confidence equal to support and lift 1, got %v", rule)
+		}
+	}
+	if baselines != 2 || len(rules) != 3 {
+		t.Errorf("expected 2 baseline rules and 2 => 1, got %v", rules)
+	}
+}
+
+func TestGenerateRulesBothDirections(t *testing.T) {
+	itemsets := []Itemset{
+		{[]Item{1}, 4},
+		{[]Item{2}, 2},
+		{[]Item{3}, 3},
+		{[]Item{1, 2}, 2},
+		{[]Item{2, 3}, 1},
+	}
+	// 2 => 1 has confidence 1, but 1 => 2 only 0.5. Neither rule of {2, 3}
+	// reaches 0.9.
+	if rules := GenerateRules(itemsets, 5, RuleOptions{MinConfidence: 0.9}); len(rules) != 1 {
+		t.Fatalf("expected only 2 => 1, got %v", rules)
+	}
+	rules := GenerateRules(itemsets, 5, RuleOptions{MinConfidence: 0.9, BothDirections: true})
+	if len(rules) != 2 {
+		t.Fatalf("expected both rules of {1, 2}, got %v", rules)
+	}
+	for _, rule := range rules {
+		if rule.Antecedent[0] == 1 && rule.Confidence != 0.5 {
+			t.Errorf("expected 1 => 2 with its own confidence 0.5, got %v", rule)
+		}
+	}
+}
+
+func TestMineFocusItem(t *testing.T) {
+	lines := make([]string, 0)
+	for _, transaction := range GenerateSyntheticDataset(300, 12, 0.4, 7) {
+		lines = append(lines, strings.Join(transaction, ","))
+	}
+	args := ArgumentsV2{
+		ItemsReader:   readerOf(strings.Join(lines, "\n")),
+		MinSupport:    0.05,
+		MinConfidence: 0.1,
+	}
+	measures := func(args ArgumentsV2) map[string]Rule {
+		result, err := Mine(args, log.New(io.Discard, "", 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules := make(map[string]Rule)
+		for _, rule := range result.Rules {
+			items := append(result.Itemizer.strings(rule.Antecedent), result.Itemizer.strings(rule.Consequent)...)
+			if strings.Contains(" "+strings.Join(items, " ")+" ", " item3 ") {
+				key := result.Itemizer.join(rule.Antecedent, " ") + " => " + result.Itemizer.join(rule.Consequent, " ")
+				rules[key] = Rule{Support: rule.Support, Confidence: rule.Confidence, Lift: rule.Lift}
+			}
+		}
+		return rules
+	}
+	expected := measures(args)
+	args.FocusItem = "item3"
+	focused := measures(args)
+	if len(expected) == 0 || !reflect.DeepEqual(focused, expected) {
+		t.Errorf("expected the %d rules involving item3, got %d", len(expected), len(focused))
+	}
+
+	args.FocusItem = "unknown"
+	result, err := Mine(args, log.New(io.Discard, "", 0))
+	if err != nil || len(result.Rules) != 0 {
+		t.Errorf("expected no rules for an unknown item, got %d, %v", len(result.Rules), err)
+	}
+}
+
+func TestRuleMarshalJSON(t *testing.T) {
+	rule := NewRule([]Item{1}, []Item{2}, 0.5, 1, 2)
+	rule.Conviction = math.Inf(1)
+	rule.Leverage = math.NaN()
+	data, err := json.Marshal([]Rule{rule})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("expected loadable JSON, got %s: %v", data, err)
+	}
+	if decoded[0]["Conviction"] != "Infinity" || decoded[0]["Leverage"] != "NaN" || decoded[0]["Lift"] != 2.0 {
+		t.Errorf("unexpected encoding %s", data)
+	}
+}
+
+func TestMineAttributeSeparator(t *testing.T) {
+	args := ArgumentsV2{
+		ItemsReader:   readerOf("tag=a,tag=b,size=L\ntag=a,tag=b,size=L\ntag=a,size=S\n"),
+		MinSupport:    0.5,
+		MinConfidence: 0.5,
+	}
+	sharedTag := func(args ArgumentsV2) (shared int, total int) {
+		result, err := Mine(args, log.New(io.Discard, "", 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, rule := range result.Rules {
+			a := result.Itemizer.join(rule.Antecedent, " ")
+			c := result.Itemizer.join(rule.Consequent, " ")
+			if strings.Contains(a, "tag=") && strings.Contains(c, "tag=") {
+				shared++
+			}
+		}
+		return shared, len(result.Rules)
+	}
+	if shared, _ := sharedTag(args); shared == 0 {
+		t.Fatal("expected rules with tag on both sides without AttributeSeparator")
+	}
+	args.AttributeSeparator = "="
+	if shared, total := sharedTag(args); shared != 0 || total == 0 {
+		t.Errorf("expected only rules between different attributes, got %d of %d with tag on both sides", shared, total)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"hash/fnv"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// writeRuleShards writes each rule to the shard of its antecedent, with
+// RuleShardWriter, in the format writeRules writes a single output in.
+// Every shard is written, even if no rule falls into it, so that the set
+// of files is the same whatever the rules.
+func writeRuleShards(rules [][]Rule, args ArgumentsV2, itemizer *Itemizer, numTransactions int) error {
+	shards := make([][]Rule, args.OutputShards)
+	for _, chunk := range rules {
+		for _, rule := range chunk {
+			i := antecedentShard(&rule, itemizer, args.OutputShards)
+			shards[i] = append(shards[i], rule)
+		}
+	}
+	shardArgs := args
+	shardArgs.OutputShards = 0
+	for i, shard := range shards {
+		i := i
+		shardArgs.RulesWriter = func() (io.WriteCloser, error) {
+			return args.RuleShardWriter(i)
+		}
+		if err := writeRules([][]Rule{shard}, shardArgs, itemizer, numTransactions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// antecedentShard returns which of shards the rule goes to, from a 64-bit
+// FNV-1a hash of the sorted strings of its antecedent, so that it is the
+// same in every run, and all rules with the same antecedent go to the same
+// shard.
+func antecedentShard(rule *Rule, itemizer *Itemizer, shards int) int {
+	h := fnv.New64a()
+	strs := itemizer.strings(rule.Antecedent)
+	sort.Strings(strs)
+	for _, s := range strs {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return int(h.Sum64() % uint64(shards))
+}
+
+// shardPath returns the path of the shard of path, with the number of the
+// shard inserted before the extensions of its file name, so that
+// "rules.csv.gz" becomes "rules.3.csv.gz", which keeps the compression
+// that the extension asks for.
+func shardPath(path string, shard int) string {
+	dir, base := filepath.Split(path)
+	n := strconv.Itoa(shard)
+	if i := strings.IndexByte(base, '.'); i > 0 {
+		return dir + base[:i] + "." + n + base[i:]
+	}
+	return dir + base + "." + n
+}
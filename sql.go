@@ -0,0 +1,49 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeRulesSQL writes one INSERT statement per rule. The antecedent and
+// consequent are stored as space separated item lists, as in the CSV
+// output. String literals follow the SQL standard, where a quote is
+// escaped by doubling it; MySQL additionally needs NO_BACKSLASH_ESCAPES
+// if items contain backslashes.
+func writeRulesSQL(w io.Writer, rules [][]Rule, itemizer *Itemizer, table string) error {
+	if table == "" {
+		table = defaultSQLTable
+	}
+	for _, chunk := range rules {
+		for _, rule := range chunk {
+			if _, err := fmt.Fprintf(w,
+				"INSERT INTO %s (antecedent, consequent, confidence, lift, support) VALUES (%s, %s, %f, %f, %f);\n",
+				table,
+				sqlString(itemizer.join(rule.Antecedent, " ")),
+				sqlString(itemizer.join(rule.Consequent, " ")),
+				rule.Confidence, rule.Lift, rule.Support); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,36 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteRulesSQL(t *testing.T) {
+	itemizer := newItemizer()
+	items := itemizer.Itemize([]string{"o'brien", "milk", "bread"})
+	rules := [][]Rule{{NewRule(items[:2], items[2:], 0.25, 0.5, 2)}}
+
+	var b strings.Builder
+	if err := writeRulesSQL(&b, rules, &itemizer, "shop.rules"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "INSERT INTO shop.rules (antecedent, consequent, confidence, lift, support) " +
+		"VALUES ('o''brien milk', 'bread', 0.500000, 2.000000, 0.250000);\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
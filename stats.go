@@ -0,0 +1,156 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"strconv"
+	"time"
+)
+
+// Stats describes a mining run.
+type Stats struct {
+	NumTransactions int
+	NumItemsets     int
+	NumRules        int
+	// Number of distinct items read.
+	NumDistinctItems int
+	// Number of items which reach MinSupport on their own. Only these can
+	// be in frequent itemsets.
+	NumFrequentItems int
+	// Highest support of any single item. No itemsets are frequent when
+	// MinSupport is above it.
+	MaxSingleItemSupport float64
+	// Number of transactions with more than MaxItemsPerTransaction items,
+	// which were truncated or skipped.
+	NumLongTransactions int
+	// Whether frequent itemsets longer than MaxRecursionDepth were left
+	// out.
+	ReachedMaxRecursionDepth bool
+	// Number of frequent items of the FP-tree header whose itemsets were
+	// restored from CheckpointPath rather than grown.
+	NumRestoredHeaderItems int
+	// Number of malformed lines skipped with SkipMalformed.
+	NumMalformedLines int
+	// Number of frequent itemsets of two or more items whose candidate
+	// rules were all rejected, each by one of the thresholds counted in
+	// the RejectedBy fields, as RuleOptions.NoRules reports them. With
+	// BothDirections, a pair is counted when neither of its rules
+	// qualifies. Itemsets skipped whole, counted in the ItemsetsRejectedBy
+	// fields, are not counted here. Many such itemsets suggest the
+	// thresholds are too strict.
+	NumItemsetsWithoutRules int
+	// The itemsets counted by NumItemsetsWithoutRules, as item strings, if
+	// RecordItemsetsWithoutRules is set.
+	ItemsetsWithoutRules [][]string
+	// Number of candidate rules rejected by each threshold, which tells
+	// which of them to relax for more rules. A rule is counted once, by
+	// the first threshold it fails, in the order of these fields.
+	// Candidates with a consequent extending one rejected by
+	// MinConfidence are never made, and are not counted.
+	RejectedByConfidence     int
+	RejectedByConfidenceLift int
+	RejectedByExcessCount    int
+	RejectedByJaccard        int
+	RejectedByLift           int
+	// Rules with the same attribute on both sides, with
+	// AttributeSeparator.
+	RejectedByAttributes int
+	// Rules which are not class rules, with ClassRulesOnly, from itemsets
+	// holding a label. Itemsets holding none are counted by
+	// ItemsetsRejectedByLabels instead.
+	RejectedByLabels int
+	// Rules with an infinite or NaN measure, with MetricOverflowSkip.
+	RejectedByMetricOverflow int
+	// Number of frequent itemsets of two or more items skipped whole,
+	// before any of their candidate rules is made, because they are below
+	// MinRuleSupportCount, lack the FocusItem, or with ClassRulesOnly hold
+	// no label. An itemset is counted once, by the first of these it
+	// fails, and not by NumItemsetsWithoutRules.
+	ItemsetsRejectedByRuleSupportCount int
+	ItemsetsRejectedByFocus            int
+	ItemsetsRejectedByLabels           int
+	// Whether rules were left out of the output by MaxOutputBytes.
+	TruncatedOutput bool
+	// With DryRun, the projected cost of mining, which is also logged.
+	// nil otherwise.
+	Estimate *CostEstimate
+	// Time spent counting the items, growing the frequent itemsets and
+	// generating the rules.
+	CountTime    time.Duration
+	FPGrowthTime time.Duration
+	RulesTime    time.Duration
+}
+
+// logSummary logs the time a run took in total, and how many
+// transactions, itemsets and rules each phase went through per second.
+// Phases which did not run are left out.
+func logSummary(stats *Stats, total time.Duration, log Logger) {
+	log.Println("Summary:")
+	log.Printf("  Total time    %s", total)
+	log.Printf("  Transactions  %d in %s (%s/s)", stats.NumTransactions, stats.CountTime, rate(stats.NumTransactions, stats.CountTime))
+	if stats.FPGrowthTime > 0 {
+		log.Printf("  Itemsets      %d in %s (%s/s)", stats.NumItemsets, stats.FPGrowthTime, rate(stats.NumItemsets, stats.FPGrowthTime))
+	}
+	if stats.RulesTime > 0 {
+		log.Printf("  Rules         %d in %s (%s/s)", stats.NumRules, stats.RulesTime, rate(stats.NumRules, stats.RulesTime))
+	}
+}
+
+// countRejection counts a candidate rule, or a whole itemset, rejected by
+// r.
+func (stats *Stats) countRejection(r rejection) {
+	switch r {
+	case rejectedByConfidence:
+		stats.RejectedByConfidence++
+	case rejectedByConfidenceLift:
+		stats.RejectedByConfidenceLift++
+	case rejectedByExcessCount:
+		stats.RejectedByExcessCount++
+	case rejectedByJaccard:
+		stats.RejectedByJaccard++
+	case rejectedByLift:
+		stats.RejectedByLift++
+	case rejectedByAttributes:
+		stats.RejectedByAttributes++
+	case rejectedByLabels:
+		stats.RejectedByLabels++
+	case rejectedByMetricOverflow:
+		stats.RejectedByMetricOverflow++
+	case itemsetRejectedByRuleSupportCount:
+		stats.ItemsetsRejectedByRuleSupportCount++
+	case itemsetRejectedByFocus:
+		stats.ItemsetsRejectedByFocus++
+	case itemsetRejectedByLabels:
+		stats.ItemsetsRejectedByLabels++
+	}
+}
+
+// rate formats n per elapsed as a whole number per second.
+func rate(n int, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(float64(n)/elapsed.Seconds(), 'f', 0, 64)
+}
+
+// statsFor returns the Stats to fill in for a run, which are the caller's
+// if they asked for them.
+func statsFor(stats *Stats) *Stats {
+	if stats == nil {
+		return &Stats{}
+	}
+	*stats = Stats{}
+	return stats
+}
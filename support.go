@@ -0,0 +1,162 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrSupportMismatch is returned by VerifySupports for an itemset whose
+// count differs from the number of transactions which contain it.
+var ErrSupportMismatch = errors.New("itemset support does not match the dataset")
+
+// SupportOf returns the number of transactions which contain all of items,
+// whether the itemset is frequent or not. It reads the dataset again,
+// parsing it as it was parsed for mining. Items unknown to the Itemizer
+// appear in no transaction.
+func (r *Result) SupportOf(items ...string) (int, error) {
+	itemset := make([]Item, 0, len(items))
+	for _, str := range items {
+		item, found := r.Itemizer.lookup(strings.TrimSpace(str))
+		if !found {
+			return 0, nil
+		}
+		itemset = append(itemset, item)
+	}
+	count := 0
+	err := forEachTransaction(r.args, r.Itemizer, func(fields []string, transaction []Item) bool {
+		if containsItems(transaction, itemset) {
+			count++
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ExplainRule returns up to limit transactions of the dataset at path
+// which support rule, that is which contain both its antecedent and its
+// consequent, as their item strings. A limit of 0 returns all of them. The
+// dataset is parsed as it was parsed for mining.
+func (r *Result) ExplainRule(rule Rule, path string, limit int) ([][]string, error) {
+	itemset := append(append([]Item(nil), rule.Antecedent...), rule.Consequent...)
+	args := r.args
+	args.ItemsReader = func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+	examples := make([][]string, 0)
+	err := forEachTransaction(args, r.Itemizer, func(fields []string, transaction []Item) bool {
+		if !containsItems(transaction, itemset) {
+			return true
+		}
+		example := make([]string, 0, len(fields))
+		for _, field := range fields {
+			if field = strings.TrimSpace(field); len(field) > 0 {
+				example = append(example, field)
+			}
+		}
+		examples = append(examples, example)
+		return limit <= 0 || len(examples) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return examples, nil
+}
+
+// verifySupports counts the transactions which contain each itemset of
+// result by brute force, reading the dataset again, and returns an error
+// wrapping ErrSupportMismatch for the first itemset whose count differs.
+// Transactions are truncated by MaxItemsPerTransaction as for mining.
+func verifySupports(args ArgumentsV2, result *Result) error {
+	minCount := minCountFor(args.MinSupport, result.NumTransactions)
+	counts := make([]int, len(result.itemsets))
+	err := forEachTransaction(args, result.Itemizer, func(fields []string, transaction []Item) bool {
+		transaction, ok := limitTransaction(args, transaction, result.Itemizer, result.frequency, minCount)
+		if !ok {
+			return true
+		}
+		for i, iwc := range result.itemsets {
+			if containsItems(transaction, iwc.itemset) {
+				counts[i]++
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for i, iwc := range result.itemsets {
+		if counts[i] != iwc.count {
+			return fmt.Errorf("%w: {%s} has count %d, but is in %d transactions",
+				ErrSupportMismatch, result.Itemizer.join(iwc.itemset, " "), iwc.count, counts[i])
+		}
+	}
+	return nil
+}
+
+// forEachTransaction reads the dataset of args and calls fn with the item
+// strings of each transaction, and the items they convert to. Strings
+// unknown to itemizer are left out of the items, and never added to it.
+// Transactions skipped by SkipLongTransactions are left out too. Reading
+// stops early if fn returns false.
+func forEachTransaction(args ArgumentsV2, itemizer *Itemizer, fn func([]string, []Item) bool) error {
+	if args.ItemsReader == nil {
+		return ErrItemsReaderIsNil
+	}
+	_, err := scanTransactions(args, nil, func(fields []string) bool {
+		transaction := make([]Item, 0, len(fields))
+		numItems := 0
+		for _, field := range fields {
+			field = strings.TrimSpace(field)
+			if len(field) == 0 {
+				continue
+			}
+			numItems++
+			if item, found := itemizer.lookup(field); found {
+				transaction = append(transaction, item)
+			}
+		}
+		if args.SkipLongTransactions && longTransaction(args, numItems) {
+			return true
+		}
+		return fn(fields, transaction)
+	})
+	return err
+}
+
+// containsItems reports whether transaction contains every item of
+// itemset.
+func containsItems(transaction []Item, itemset []Item) bool {
+	for _, want := range itemset {
+		found := false
+		for _, item := range transaction {
+			if item == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
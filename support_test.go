@@ -0,0 +1,40 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"errors"
+	"io"
+	"log"
+	"testing"
+)
+
+func TestVerifySupports(t *testing.T) {
+	args := ArgumentsV2{
+		ItemsReader:            readerOf("a,b,c\na,b\na,b,c,d\nb,c\na,c,d\n"),
+		MinSupport:             0.2,
+		MinConfidence:          0.2,
+		MaxItemsPerTransaction: 3,
+		VerifySupports:         true,
+	}
+	result, err := Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.itemsets[0].count++
+	if err := verifySupports(result.args, result); !errors.Is(err, ErrSupportMismatch) {
+		t.Errorf("expected ErrSupportMismatch, got %v", err)
+	}
+}
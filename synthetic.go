@@ -0,0 +1,45 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+// GenerateSyntheticDataset returns numTx transactions over the items
+// "item1" to "itemN", where N is numItems. Each item appears in each
+// transaction independently with probability density, so every itemset of
+// k items has an expected support of density^k, and rules between items
+// have an expected lift of 1. The same seed always generates the same
+// dataset.
+func GenerateSyntheticDataset(numTx, numItems int, density float64, seed int64) [][]string {
+	r := rand.New(rand.NewSource(seed))
+	names := make([]string, numItems)
+	for i := range names {
+		names[i] = "item" + strconv.Itoa(i+1)
+	}
+	transactions := make([][]string, numTx)
+	for i := range transactions {
+		transaction := make([]string, 0)
+		for _, name := range names {
+			if r.Float64() < density {
+				transaction = append(transaction, name)
+			}
+		}
+		transactions[i] = transaction
+	}
+	return transactions
+}
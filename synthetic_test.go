@@ -0,0 +1,80 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"io"
+	"log"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// syntheticReader returns an ItemsReader of transactions in CSV format.
+func syntheticReader(transactions [][]string) ItemsReader {
+	var b strings.Builder
+	for _, transaction := range transactions {
+		b.WriteString(strings.Join(transaction, ","))
+		b.WriteString("\n")
+	}
+	return readerOf(b.String())
+}
+
+func TestGenerateSyntheticDataset(t *testing.T) {
+	dataset := GenerateSyntheticDataset(2000, 10, 0.3, 1)
+	if !reflect.DeepEqual(dataset, GenerateSyntheticDataset(2000, 10, 0.3, 1)) {
+		t.Fatal("expected the same dataset for the same seed")
+	}
+	result, err := Mine(ArgumentsV2{
+		ItemsReader: syntheticReader(dataset),
+		MinSupport:  0.05,
+	}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Items are independent, so singles have support about 0.3, pairs about
+	// 0.09, and rules a lift about 1.
+	for _, iwc := range result.itemsets {
+		expected := math.Pow(0.3, float64(len(iwc.itemset)))
+		if support := float64(iwc.count) / 2000; math.Abs(support-expected) > 0.05 {
+			t.Errorf("expected support about %f for %v, got %f", expected, iwc.itemset, support)
+		}
+	}
+	for _, rule := range result.Rules {
+		if math.Abs(rule.Lift-1) > 0.3 {
+			t.Errorf("expected lift about 1, got %f", rule.Lift)
+		}
+	}
+}
+
+func benchmarkMineSynthetic(b *testing.B, density float64) {
+	args := ArgumentsV2{
+		ItemsReader:   syntheticReader(GenerateSyntheticDataset(5000, 50, density, 1)),
+		MinSupport:    0.05,
+		MinConfidence: 0.5,
+	}
+	logger := log.New(io.Discard, "", 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Mine(args, logger); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMineSyntheticSparse(b *testing.B) { benchmarkMineSynthetic(b, 0.05) }
+func BenchmarkMineSyntheticMedium(b *testing.B) { benchmarkMineSynthetic(b, 0.15) }
+func BenchmarkMineSyntheticDense(b *testing.B)  { benchmarkMineSynthetic(b, 0.3) }
@@ -0,0 +1,68 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// treeNode is an itemset in the tree-json output format. Its itemset is
+// the items of the nodes on the path to it.
+type treeNode struct {
+	Item     string      `json:"item"`
+	Support  float64     `json:"support"`
+	Children []*treeNode `json:"children,omitempty"`
+}
+
+// writeItemsetsTreeJSON writes itemsets as a JSON array of trees, where
+// each itemset is a child of the itemset without its last item, in lexical
+// order. As every subset of a frequent itemset is frequent, the parent of
+// each itemset is among itemsets too.
+func writeItemsetsTreeJSON(w io.Writer, itemsets []itemsetWithCount, itemizer *Itemizer, numTransactions int) error {
+	sorted := make([][]Item, len(itemsets))
+	supports := make(map[string]float64, len(itemsets))
+	for i, iwc := range itemsets {
+		items := append([]Item(nil), iwc.itemset...)
+		sort.Slice(items, func(i, j int) bool {
+			return itemizer.toStr(items[i]) < itemizer.toStr(items[j])
+		})
+		sorted[i] = items
+		supports[itemsKey(items)] = float64(iwc.count) / float64(numTransactions)
+	}
+	// Parents come before their children, and siblings in lexical order.
+	sort.Slice(sorted, func(i, j int) bool {
+		return compareItemNames(sorted[i], sorted[j], itemizer) < 0
+	})
+
+	roots := make([]*treeNode, 0)
+	nodes := make(map[string]*treeNode, len(sorted))
+	for _, items := range sorted {
+		key := itemsKey(items)
+		node := &treeNode{
+			Item:    itemizer.toStr(items[len(items)-1]),
+			Support: supports[key],
+		}
+		nodes[key] = node
+		parent, found := nodes[itemsKey(items[:len(items)-1])]
+		if len(items) == 1 || !found {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	return json.NewEncoder(w).Encode(roots)
+}
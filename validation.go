@@ -0,0 +1,131 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import "sort"
+
+// validateRules sets the validation measures of rules from the dataset of
+// args.ValidationReader, which is parsed as the mined one, and drops the
+// rules whose validation confidence is below MinValidationConfidence. It
+// returns the remaining rules and the number of validation transactions.
+func validateRules(args ArgumentsV2, rules [][]Rule, itemizer *Itemizer) ([][]Rule, int, error) {
+	// Count the antecedent, consequent and union of every rule once, however
+	// many rules share them.
+	index := make(map[string]int)
+	itemsets := make([][]Item, 0)
+	indexOf := func(items []Item) int {
+		key := itemsKey(items)
+		i, found := index[key]
+		if !found {
+			i = len(itemsets)
+			index[key] = i
+			itemsets = append(itemsets, items)
+		}
+		return i
+	}
+	type ruleSets struct{ antecedent, consequent, union int }
+	sets := make([][]ruleSets, len(rules))
+	for i, chunk := range rules {
+		sets[i] = make([]ruleSets, len(chunk))
+		for j, rule := range chunk {
+			union := append(append([]Item(nil), rule.Antecedent...), rule.Consequent...)
+			sort.Slice(union, func(a, b int) bool {
+				return union[a] < union[b]
+			})
+			sets[i][j] = ruleSets{indexOf(rule.Antecedent), indexOf(rule.Consequent), indexOf(union)}
+		}
+	}
+
+	// Index the itemsets by their first item, so that each transaction is
+	// only checked against the itemsets it may contain, and mark the items
+	// of the transaction, so that checking an itemset takes one lookup per
+	// item. The empty antecedent of baseline rules is in every transaction.
+	byFirstItem := make(map[Item][]int)
+	empty := make([]int, 0)
+	for i, itemset := range itemsets {
+		if len(itemset) == 0 {
+			empty = append(empty, i)
+			continue
+		}
+		byFirstItem[itemset[0]] = append(byFirstItem[itemset[0]], i)
+	}
+	counts := make([]int, len(itemsets))
+	numTransactions := 0
+	marks := make([]int, 0)
+	distinct := make([]Item, 0)
+	validation := args
+	validation.ItemsReader = args.ValidationReader
+	err := forEachTransaction(validation, itemizer, func(fields []string, transaction []Item) bool {
+		numTransactions++
+		distinct = distinct[:0]
+		for _, item := range transaction {
+			marks = ensureInBounds(marks, int(item))
+			if marks[item] != numTransactions {
+				marks[item] = numTransactions
+				distinct = append(distinct, item)
+			}
+		}
+		for _, i := range empty {
+			counts[i]++
+		}
+		for _, item := range distinct {
+		candidates:
+			for _, i := range byFirstItem[item] {
+				for _, want := range itemsets[i][1:] {
+					if int(want) >= len(marks) || marks[want] != numTransactions {
+						continue candidates
+					}
+				}
+				counts[i]++
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	validated := make([][]Rule, 0, len(rules))
+	for i, chunk := range rules {
+		kept := make([]Rule, 0, len(chunk))
+		for j, rule := range chunk {
+			s := sets[i][j]
+			setValidationMeasures(&rule, counts[s.antecedent], counts[s.consequent], counts[s.union], numTransactions)
+			if rule.ValidationConfidence >= args.MinValidationConfidence {
+				kept = append(kept, rule)
+			}
+		}
+		validated = append(validated, kept)
+	}
+	return validated, numTransactions, nil
+}
+
+// setValidationMeasures sets the validation measures of rule from the
+// counts of its antecedent, consequent and both in numTransactions
+// validation transactions. Measures which would divide by zero are 0.
+func setValidationMeasures(rule *Rule, aCount, cCount, acCount, numTransactions int) {
+	rule.ValidationSupport, rule.ValidationConfidence, rule.ValidationLift = 0, 0, 0
+	if numTransactions == 0 {
+		return
+	}
+	n := float64(numTransactions)
+	rule.ValidationSupport = float64(acCount) / n
+	if aCount > 0 {
+		rule.ValidationConfidence = float64(acCount) / float64(aCount)
+	}
+	if aCount > 0 && cCount > 0 {
+		rule.ValidationLift = float64(acCount) * n / (float64(aCount) * float64(cCount))
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestValidateRulesBruteForce(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	dataset := func(n int) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			items := make([]string, 1+random.Intn(6))
+			for j := range items {
+				// Items may repeat within a transaction, and "z" is never mined.
+				items[j] = string(rune('a' + random.Intn(7)))
+				if random.Intn(20) == 0 {
+					items[j] = "z"
+				}
+			}
+			fmt.Fprintln(&b, strings.Join(items, ","))
+		}
+		return b.String()
+	}
+	args := ArgumentsV2{
+		ItemsReader:          readerOf(dataset(200)),
+		MinSupport:           0.02,
+		IncludeBaselineRules: true,
+	}
+	result, err := Mine(args, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rules) == 0 {
+		t.Fatal("expected rules")
+	}
+
+	validation := dataset(100)
+	args.ValidationReader = readerOf(validation)
+	validated, numTransactions, err := validateRules(args, [][]Rule{result.Rules}, result.Itemizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numTransactions != 100 || len(validated[0]) != len(result.Rules) {
+		t.Fatalf("expected %d rules validated on 100 transactions, got %d on %d", len(result.Rules), len(validated[0]), numTransactions)
+	}
+
+	transactions := make([][]Item, 0)
+	for _, line := range strings.Split(strings.TrimSpace(validation), "\n") {
+		transaction := make([]Item, 0)
+		for _, field := range strings.Split(line, ",") {
+			if item, found := result.Itemizer.lookup(field); found {
+				transaction = append(transaction, item)
+			}
+		}
+		transactions = append(transactions, transaction)
+	}
+	count := func(itemset []Item) int {
+		n := 0
+		for _, transaction := range transactions {
+			if containsItems(transaction, itemset) {
+				n++
+			}
+		}
+		return n
+	}
+	for _, rule := range validated[0] {
+		expected := rule
+		union := append(append([]Item(nil), rule.Antecedent...), rule.Consequent...)
+		setValidationMeasures(&expected, count(rule.Antecedent), count(rule.Consequent), count(union), numTransactions)
+		if rule.ValidationSupport != expected.ValidationSupport || rule.ValidationConfidence != expected.ValidationConfidence ||
+			rule.ValidationLift != expected.ValidationLift {
+			t.Errorf("%v => %v: expected validation measures %v %v %v, got %v %v %v", rule.Antecedent, rule.Consequent,
+				expected.ValidationSupport, expected.ValidationConfidence, expected.ValidationLift,
+				rule.ValidationSupport, rule.ValidationConfidence, rule.ValidationLift)
+		}
+	}
+}
@@ -0,0 +1,484 @@
+// Copyright 2022 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testRules(itemizer *Itemizer) [][]Rule {
+	items := itemizer.Itemize([]string{"milk", "eggs", "bread"})
+	return [][]Rule{{NewRule(items[:2], items[2:], 0.25, 0.5, 2)}}
+}
+
+func TestWriteRulesCSV(t *testing.T) {
+	itemizer := newItemizer()
+	rules := testRules(&itemizer)
+	tests := []struct {
+		name     string
+		header   bool
+		metrics  bool
+		expected string
+	}{
+		{"default", true, true, "Antecedent => Consequent,Confidence,Lift,Support\nmilk eggs => bread,0.500000,2.000000,0.250000\n"},
+		{"no-header", false, true, "milk eggs => bread,0.500000,2.000000,0.250000\n"},
+		{"rules-only", false, false, "milk eggs => bread\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := writeRulesCSV(&b, rules, &itemizer, csvFormat{header: tt.header, metrics: tt.metrics}); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, b.String())
+		}
+	}
+}
+
+func TestWriteRulesCSVGroupByAntecedent(t *testing.T) {
+	itemizer := newItemizer()
+	items := itemizer.Itemize([]string{"milk", "eggs", "bread"})
+	rules := [][]Rule{{
+		NewRule(items[:1], items[1:2], 0.25, 0.5, 2),
+		NewRule(items[1:2], items[:1], 0.25, 0.5, 2),
+		NewRule(items[:1], items[2:], 0.25, 0.5, 2),
+	}}
+	var b strings.Builder
+	err := writeRulesCSV(&b, groupByAntecedent(rules, &itemizer), &itemizer, csvFormat{group: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "eggs => milk\n\nmilk => eggs\nmilk => bread\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestWriteRulesCSVSeparators(t *testing.T) {
+	itemizer := newItemizer()
+	items := itemizer.Itemize([]string{"a => b", "c d", "e"})
+	rules := [][]Rule{{NewRule(items[:2], items[2:], 0.25, 0.5, 2)}}
+	var b strings.Builder
+	err := writeRulesCSV(&b, rules, &itemizer, csvFormat{header: true, metrics: true, arrow: "\t", itemSep: "|"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Antecedent\tConsequent,Confidence,Lift,Support\na => b|c d\te,0.500000,2.000000,0.250000\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestWriteRulesCSVSeparateColumns(t *testing.T) {
+	itemizer := newItemizer()
+	var b strings.Builder
+	err := writeRulesCSV(&b, testRules(&itemizer), &itemizer, csvFormat{header: true, metrics: true, arrow: "|", separate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Antecedent,Consequent,Confidence,Lift,Support\nmilk eggs,bread,0.500000,2.000000,0.250000\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestWriteRulesCSVSupportAsPercent(t *testing.T) {
+	itemizer := newItemizer()
+	var b strings.Builder
+	err := writeRulesCSV(&b, testRules(&itemizer), &itemizer, csvFormat{metrics: true, support: supportFormat{percent: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "milk eggs => bread,0.500000,2.000000,25.0000%\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestWriteRulesCSVEmitSizes(t *testing.T) {
+	itemizer := newItemizer()
+	var b strings.Builder
+	columns := ruleColumns(ArgumentsV2{EmitSizes: true})
+	err := writeRulesCSV(&b, testRules(&itemizer), &itemizer, csvFormat{header: true, metrics: true, columns: columns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Antecedent => Consequent,Confidence,Lift,Support,AntecedentSize,ConsequentSize\n" +
+		"milk eggs => bread,0.500000,2.000000,0.250000,2,1\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestSupportBins(t *testing.T) {
+	bins := []float64{0.01, 0.05, 0.1}
+	tests := []struct {
+		support  float64
+		expected string
+	}{
+		{0, "0-1%"},
+		{0.005, "0-1%"},
+		{0.01, "1-5%"},
+		{0.07, "5-10%"},
+		{0.1, "10-100%"},
+		{1, "10-100%"},
+	}
+	for _, tt := range tests {
+		if got := (supportFormat{bins: bins}).format(tt.support); got != tt.expected {
+			t.Errorf("support %v: expected %q, got %q", tt.support, tt.expected, got)
+		}
+	}
+	itemizer := newItemizer()
+	var b strings.Builder
+	err := writeRulesCSV(&b, testRules(&itemizer), &itemizer, csvFormat{metrics: true, support: supportFormat{percent: true, bins: bins}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "milk eggs => bread,0.500000,2.000000,10-100%\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestWriteRulesAdjacency(t *testing.T) {
+	itemizer := newItemizer()
+	items := itemizer.Itemize([]string{"milk", "eggs", "bread", "jam"})
+	milk, eggs, bread, jam := items[0:1], items[1:2], items[2:3], items[3:4]
+	rules := [][]Rule{{
+		NewRule(milk, eggs, 0.25, 0.5, 1),
+		NewRule(milk, bread, 0.5, 0.75, 1.5),
+		NewRule(milk, jam, 0.25, 0.5, 2),
+		NewRule(bread, milk, 0.5, 0.6, 1.5),
+		NewRule(items[:2], bread, 0.25, 1, 2),
+	}}
+	tests := []struct {
+		name     string
+		top      int
+		expected string
+	}{
+		{"all", 0, "bread -> [(milk, 0.600000)]\nmilk -> [(bread, 0.750000), (eggs, 0.500000), (jam, 0.500000)]\n"},
+		{"top-2", 2, "bread -> [(milk, 0.600000)]\nmilk -> [(bread, 0.750000), (eggs, 0.500000)]\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := writeRulesAdjacency(&b, rules, &itemizer, tt.top); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, b.String())
+		}
+	}
+}
+
+func TestWriteRulesParquet(t *testing.T) {
+	itemizer := newItemizer()
+	var b bytes.Buffer
+	if err := writeRulesParquet(&b, testRules(&itemizer), &itemizer); err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+	if !bytes.HasPrefix(data, parquetMagic) || !bytes.HasSuffix(data, parquetMagic) {
+		t.Fatal("expected Parquet magic at start and end")
+	}
+	footer := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footer <= 0 || footer > len(data)-12 {
+		t.Fatalf("unexpected footer length %d", footer)
+	}
+	meta := data[len(data)-8-footer : len(data)-8]
+	for _, column := range []string{"antecedent", "consequent", "confidence", "lift", "support"} {
+		if !bytes.Contains(meta, []byte(column)) {
+			t.Errorf("expected column %s in the footer", column)
+		}
+	}
+	// The antecedent column holds one PLAIN encoded string per rule.
+	if !bytes.Contains(data, []byte("\x09\x00\x00\x00milk eggs")) {
+		t.Error("expected the antecedent to be written")
+	}
+}
+
+// thriftReader decodes the Thrift compact protocol, as far as
+// parquetWriter uses it: structs decode to maps from field ids to values,
+// lists to slices, integers to int64 and binaries to strings.
+type thriftReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (tr *thriftReader) byte() byte {
+	if tr.pos >= len(tr.data) {
+		tr.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	tr.pos++
+	return tr.data[tr.pos-1]
+}
+
+func (tr *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(tr.data[tr.pos:])
+	if n <= 0 {
+		tr.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	tr.pos += n
+	return v
+}
+
+func (tr *thriftReader) zigzag() int64 {
+	v := tr.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (tr *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return tr.zigzag()
+	case thriftBinary:
+		n := int(tr.varint())
+		if tr.err != nil || tr.pos+n > len(tr.data) {
+			tr.err = io.ErrUnexpectedEOF
+			return ""
+		}
+		tr.pos += n
+		return string(tr.data[tr.pos-n : tr.pos])
+	case thriftList:
+		header := tr.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(tr.varint())
+		}
+		list := make([]interface{}, 0)
+		for i := 0; i < size && tr.err == nil; i++ {
+			list = append(list, tr.value(header&0x0f))
+		}
+		return list
+	case thriftStruct:
+		fields := make(map[int64]interface{})
+		var id int64
+		for tr.err == nil {
+			header := tr.byte()
+			if header == 0 {
+				break
+			}
+			if delta := int64(header >> 4); delta != 0 {
+				id += delta
+			} else {
+				id = tr.zigzag()
+			}
+			fields[id] = tr.value(header & 0x0f)
+		}
+		return fields
+	}
+	tr.err = fmt.Errorf("unexpected Thrift type %d", typ)
+	return nil
+}
+
+func (tr *thriftReader) readStruct() map[int64]interface{} {
+	return tr.value(thriftStruct).(map[int64]interface{})
+}
+
+// readParquet decodes a file written by parquetWriter into its values,
+// column by column, checking the metadata against the data on the way.
+func readParquet(t *testing.T, data []byte) ([]string, [][]interface{}) {
+	t.Helper()
+	if !bytes.HasPrefix(data, parquetMagic) || !bytes.HasSuffix(data, parquetMagic) {
+		t.Fatal("expected Parquet magic at start and end")
+	}
+	footer := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footer <= 0 || footer > len(data)-12 {
+		t.Fatalf("unexpected footer length %d", footer)
+	}
+	tr := thriftReader{data: data[len(data)-8-footer : len(data)-8]}
+	meta := tr.readStruct()
+	if tr.err != nil || tr.pos != footer {
+		t.Fatalf("decoding the footer: %v, read %d of %d bytes", tr.err, tr.pos, footer)
+	}
+
+	schema := meta[2].([]interface{})
+	if numColumns := schema[0].(map[int64]interface{})[5].(int64); numColumns != int64(len(schema)-1) {
+		t.Fatalf("expected %d columns in the schema root, got %d", len(schema)-1, numColumns)
+	}
+	var names []string
+	var types []int64
+	for _, element := range schema[1:] {
+		element := element.(map[int64]interface{})
+		names = append(names, element[4].(string))
+		types = append(types, element[1].(int64))
+	}
+
+	columns := make([][]interface{}, len(names))
+	var numRows int64
+	for _, rowGroup := range meta[4].([]interface{}) {
+		rowGroup := rowGroup.(map[int64]interface{})
+		numRows += rowGroup[3].(int64)
+		for i, chunk := range rowGroup[1].([]interface{}) {
+			chunkMeta := chunk.(map[int64]interface{})[3].(map[int64]interface{})
+			if path := chunkMeta[3].([]interface{}); len(path) != 1 || path[0] != names[i] {
+				t.Fatalf("expected the path of column %d to be %s, got %v", i, names[i], path)
+			}
+			page := thriftReader{data: data, pos: int(chunkMeta[9].(int64))}
+			header := page.readStruct()
+			if page.err != nil {
+				t.Fatal(page.err)
+			}
+			values := data[page.pos : page.pos+int(header[2].(int64))]
+			numValues := header[5].(map[int64]interface{})[1].(int64)
+			if numValues != rowGroup[3].(int64) || numValues != chunkMeta[5].(int64) {
+				t.Fatalf("expected %d values in column %s, got %d", rowGroup[3], names[i], numValues)
+			}
+			for j := int64(0); j < numValues; j++ {
+				switch types[i] {
+				case parquetByteArray:
+					n := binary.LittleEndian.Uint32(values)
+					columns[i] = append(columns[i], string(values[4:4+n]))
+					values = values[4+n:]
+				case parquetDouble:
+					columns[i] = append(columns[i], math.Float64frombits(binary.LittleEndian.Uint64(values)))
+					values = values[8:]
+				default:
+					t.Fatalf("unexpected type %d of column %s", types[i], names[i])
+				}
+			}
+			if len(values) != 0 {
+				t.Fatalf("%d bytes left over in column %s", len(values), names[i])
+			}
+		}
+	}
+	for i, column := range columns {
+		if int64(len(column)) != numRows {
+			t.Fatalf("expected %d rows in column %s, got %d", numRows, names[i], len(column))
+		}
+	}
+	return names, columns
+}
+
+func TestWriteParquetRoundTrip(t *testing.T) {
+	itemizer := newItemizer()
+	items := itemizer.Itemize([]string{"milk", "eggs", "bread"})
+	rules := [][]Rule{
+		{NewRule(items[:2], items[2:], 0.25, 0.5, 2)},
+		{NewRule(items[2:], items[:1], 0.4, 0.8, 1.25), NewRule(items[:1], items[2:], 0.4, 0.6, 1.25)},
+	}
+	var b bytes.Buffer
+	if err := writeRulesParquet(&b, rules, &itemizer); err != nil {
+		t.Fatal(err)
+	}
+	names, columns := readParquet(t, b.Bytes())
+	expectedNames := []string{"antecedent", "consequent", "confidence", "lift", "support"}
+	expected := [][]interface{}{
+		{"milk eggs", "bread", "milk"},
+		{"bread", "milk", "bread"},
+		{0.5, 0.8, 0.6},
+		{2.0, 1.25, 1.25},
+		{0.25, 0.4, 0.4},
+	}
+	if !reflect.DeepEqual(names, expectedNames) || !reflect.DeepEqual(columns, expected) {
+		t.Errorf("expected rules %v %v, got %v %v", expectedNames, expected, names, columns)
+	}
+
+	b.Reset()
+	itemsets := []itemsetWithCount{{items[:1], 4}, {items[:2], 2}}
+	if err := writeItemsetsParquet(&b, itemsets, &itemizer, 5); err != nil {
+		t.Fatal(err)
+	}
+	names, columns = readParquet(t, b.Bytes())
+	expectedNames = []string{"itemset", "support"}
+	expected = [][]interface{}{{"milk", "milk eggs"}, {0.8, 0.4}}
+	if !reflect.DeepEqual(names, expectedNames) || !reflect.DeepEqual(columns, expected) {
+		t.Errorf("expected itemsets %v %v, got %v %v", expectedNames, expected, names, columns)
+	}
+
+	b.Reset()
+	if err := writeRulesParquet(&b, nil, &itemizer); err != nil {
+		t.Fatal(err)
+	}
+	if names, columns = readParquet(t, b.Bytes()); len(names) != 5 || len(columns[0]) != 0 {
+		t.Errorf("expected no rows, got %v %v", names, columns)
+	}
+}
+
+// The files in testdata were read back with github.com/xitongsys/parquet-go
+// v1.6.2, which found the same schema and rows as readParquet. Comparing
+// against them catches changes to the encoding that readParquet, written
+// alongside writeRulesParquet, might accept too.
+func TestWriteParquetGolden(t *testing.T) {
+	itemizer := newItemizer()
+	items := itemizer.Itemize([]string{"milk", "eggs", "bread"})
+	rules := [][]Rule{
+		{NewRule(items[:2], items[2:], 0.25, 0.5, 2)},
+		{NewRule(items[2:], items[:1], 0.4, 0.8, 1.25), NewRule(items[:1], items[2:], 0.4, 0.6, 1.25)},
+	}
+	tests := []struct {
+		golden string
+		write  func(w io.Writer) error
+	}{
+		{"rules.parquet", func(w io.Writer) error {
+			return writeRulesParquet(w, rules, &itemizer)
+		}},
+		{"itemsets.parquet", func(w io.Writer) error {
+			return writeItemsetsParquet(w, []itemsetWithCount{{items[:1], 4}, {items[:2], 2}}, &itemizer, 5)
+		}},
+		{"empty_rules.parquet", func(w io.Writer) error {
+			return writeRulesParquet(w, nil, &itemizer)
+		}},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := tt.write(&b); err != nil {
+			t.Fatal(err)
+		}
+		expected, err := os.ReadFile(filepath.Join("testdata", tt.golden))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b.Bytes(), expected) {
+			t.Errorf("%s: expected the bytes of the golden file, got %x", tt.golden, b.Bytes())
+		}
+	}
+}
+
+func TestWriteItemsetsTreeJSON(t *testing.T) {
+	itemizer := newItemizer()
+	items := itemizer.Itemize([]string{"milk", "eggs", "bread"})
+	milk, eggs, bread := items[0], items[1], items[2]
+	itemsets := []itemsetWithCount{
+		{[]Item{milk}, 4},
+		{[]Item{eggs}, 3},
+		{[]Item{bread}, 4},
+		{[]Item{milk, eggs}, 2},
+		{[]Item{milk, bread}, 3},
+		{[]Item{eggs, bread}, 2},
+		{[]Item{milk, eggs, bread}, 1},
+	}
+	var b strings.Builder
+	if err := writeItemsetsTreeJSON(&b, itemsets, &itemizer, 5); err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"item":"bread","support":0.8,"children":[{"item":"eggs","support":0.4,"children":[{"item":"milk","support":0.2}]},` +
+		`{"item":"milk","support":0.6}]},{"item":"eggs","support":0.6,"children":[{"item":"milk","support":0.4}]},` +
+		`{"item":"milk","support":0.8}]` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %s, got %s", expected, b.String())
+	}
+}